@@ -20,12 +20,25 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// Reload is called by the management API to request that the config be
+	// reloaded.  Reloading is not available when nil.
+	Reload func()
+
+	// configMutex guards the plugin lists of Config, they are replaced when
+	// a new config is applied to the running agent.
+	configMutex    sync.RWMutex
+	reloadRequests chan *reloadRequest
+
+	flushMutex    sync.Mutex
+	flushRequests map[*models.RunningOutput]chan struct{}
 }
 
 // NewAgent returns an Agent for the given Config.
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:         config,
+		reloadRequests: make(chan *reloadRequest),
 	}
 	return a, nil
 }
@@ -44,6 +57,17 @@ func NewAgent(config *config.Config) (*Agent, error) {
 type inputUnit struct {
 	dst    chan<- telegraf.Metric
 	inputs []*models.RunningInput
+
+	// updates changes the running inputs while the unit runs.
+	updates chan *inputUpdate
+}
+
+// inputUpdate adds and removes inputs of a running inputUnit.  Added service
+// inputs must already be started, removed ones are stopped.
+type inputUpdate struct {
+	add    []*models.RunningInput
+	remove []*models.RunningInput
+	done   chan struct{}
 }
 
 //  ______     ┌───────────┐     ______
@@ -77,6 +101,41 @@ type aggregatorUnit struct {
 	aggregators []*models.RunningAggregator
 }
 
+// chainUnit is the processors and aggregators between the inputs and the
+// outputs.  Metrics written to src pass through the chain to the channel the
+// chain was started with.
+//
+//  ______     ┌────────────┐     ┌─────────────┐     ┌────────────┐     ______
+// ()_____)──▶ │ Processors │──▶ │ Aggregators │──▶ │ Processors │──▶ ()_____)
+//             └────────────┘     └─────────────┘     └────────────┘
+type chainUnit struct {
+	src           chan<- telegraf.Metric
+	startTime     time.Time
+	processors    []*processorUnit
+	aggProcessors []*processorUnit
+	aggregator    *aggregatorUnit
+}
+
+// relayUnit forwards the metrics of the inputs through the processor and
+// aggregator chain to the outputs.  The chain can be replaced while running
+// without stopping the inputs or outputs.
+//
+//  ______     ┌───────┐     ┌───────┐     ┌───────┐     ______
+// ()_____)──▶ │ Relay │──▶ │ Chain │──▶ │ Relay │──▶ ()_____)
+//             └───────┘     └───────┘     └───────┘
+type relayUnit struct {
+	src     <-chan telegraf.Metric
+	dst     chan<- telegraf.Metric
+	chain   *relayedChain
+	replace chan *relayedChain
+}
+
+// relayedChain is a chain and the channel it writes to.
+type relayedChain struct {
+	unit *chainUnit
+	out  <-chan telegraf.Metric
+}
+
 // outputUnit is a group of Outputs and their source channel.  Metrics on the
 // channel are written to all outputs.
 //
@@ -92,6 +151,21 @@ type aggregatorUnit struct {
 type outputUnit struct {
 	src     <-chan telegraf.Metric
 	outputs []*models.RunningOutput
+
+	// updates changes the running outputs while the unit runs.
+	updates chan *outputUpdate
+}
+
+// outputUpdate adds and removes outputs of a running outputUnit.  Added
+// outputs must already be connected, removed ones are flushed and closed.
+type outputUpdate struct {
+	add    []*models.RunningOutput
+	remove []*models.RunningOutput
+	done   chan struct{}
+
+	// bufferFrom holds the running outputs whose buffer is taken over by
+	// an added output, they are removed before the added output starts.
+	bufferFrom map[*models.RunningOutput]*models.RunningOutput
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -110,40 +184,39 @@ func (a *Agent) Run(ctx context.Context) error {
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	outputC, ou, err := a.startOutputs(ctx, a.Config.Outputs)
 	if err != nil {
 		return err
 	}
+	ou.updates = make(chan *outputUpdate)
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		aggC := next
-		if len(a.Config.AggProcessors) != 0 {
-			aggC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au, err = a.startAggregators(aggC, next, a.Config.Aggregators)
-		if err != nil {
-			return err
-		}
+	chain, err := a.startRelayedChain(startTime,
+		a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators)
+	if err != nil {
+		return err
 	}
 
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	inputC := make(chan telegraf.Metric, 100)
+	ru := &relayUnit{
+		src:     inputC,
+		dst:     outputC,
+		chain:   chain,
+		replace: make(chan *relayedChain),
 	}
 
-	iu, err := a.startInputs(next, a.Config.Inputs)
+	iu, err := a.startInputs(inputC, a.Config.Inputs)
 	if err != nil {
 		return err
 	}
+	iu.updates = make(chan *inputUpdate)
+
+	if a.Config.Agent.APIAddress != "" {
+		api, err := startAPI(a, a.Config.Agent.APIAddress)
+		if err != nil {
+			return err
+		}
+		defer api.stop()
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -155,46 +228,26 @@ func (a *Agent) Run(ctx context.Context) error {
 		}
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(apu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runAggregators(startTime, au)
-			if err != nil {
-				log.Printf("E! [agent] Error running aggregators: %v", err)
-			}
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(pu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runRelay(ru)
+	}()
 
+	// The inputs are stopped once no new config is being applied.
+	inputCtx, stopInputs := context.WithCancel(context.Background())
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := a.runInputs(ctx, startTime, iu)
+		err := a.runInputs(inputCtx, startTime, iu)
 		if err != nil {
 			log.Printf("E! [agent] Error running inputs: %v", err)
 		}
 	}()
 
+	a.serveReloads(ctx, &pipeline{inputs: iu, relay: ru, outputs: ou})
+	stopInputs()
+
 	wg.Wait()
 
 	log.Printf("D! [agent] Stopped Successfully")
@@ -203,35 +256,64 @@ func (a *Agent) Run(ctx context.Context) error {
 
 // initPlugins runs the Init function on plugins.
 func (a *Agent) initPlugins() error {
-	for _, input := range a.Config.Inputs {
+	err := initInputs(a.Config.Inputs)
+	if err != nil {
+		return err
+	}
+	err = initProcessors(a.Config.Processors)
+	if err != nil {
+		return err
+	}
+	err = initAggregators(a.Config.Aggregators)
+	if err != nil {
+		return err
+	}
+	err = initProcessors(a.Config.AggProcessors)
+	if err != nil {
+		return err
+	}
+	err = initOutputs(a.Config.Outputs)
+	if err != nil {
+		return err
+	}
+	return a.linkDeadLetterOutputs()
+}
+
+func initInputs(inputs []*models.RunningInput) error {
+	for _, input := range inputs {
 		err := input.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize input %s: %v",
 				input.LogName(), err)
 		}
 	}
-	for _, processor := range a.Config.Processors {
+	return nil
+}
+
+func initProcessors(processors models.RunningProcessors) error {
+	for _, processor := range processors {
 		err := processor.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize processor %s: %v",
 				processor.Config.Name, err)
 		}
 	}
-	for _, aggregator := range a.Config.Aggregators {
+	return nil
+}
+
+func initAggregators(aggregators []*models.RunningAggregator) error {
+	for _, aggregator := range aggregators {
 		err := aggregator.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize aggregator %s: %v",
 				aggregator.Config.Name, err)
 		}
 	}
-	for _, processor := range a.Config.AggProcessors {
-		err := processor.Init()
-		if err != nil {
-			return fmt.Errorf("could not initialize processor %s: %v",
-				processor.Config.Name, err)
-		}
-	}
-	for _, output := range a.Config.Outputs {
+	return nil
+}
+
+func initOutputs(outputs []*models.RunningOutput) error {
+	for _, output := range outputs {
 		err := output.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize output %s: %v",
@@ -241,6 +323,84 @@ func (a *Agent) initPlugins() error {
 	return nil
 }
 
+// linkDeadLetterOutputs connects outputs to the outputs receiving the batches
+// they give up on.  Outputs used as a dead letter sink receive no other
+// metrics.
+func (a *Agent) linkDeadLetterOutputs() error {
+	targets, err := deadLetterTargets(a.Config.Outputs)
+	if err != nil {
+		return err
+	}
+
+	for i, output := range a.Config.Outputs {
+		if targets[i] >= 0 {
+			linkDeadLetterOutput(output, a.Config.Outputs[targets[i]])
+		}
+	}
+	return nil
+}
+
+// deadLetterTargets returns for each output the index of its dead letter
+// output, or -1 if it has none.
+func deadLetterTargets(outputs []*models.RunningOutput) ([]int, error) {
+	targets := make([]int, len(outputs))
+	for i, output := range outputs {
+		targets[i] = -1
+
+		ref := output.Config.DeadLetterOutput
+		if ref == "" {
+			continue
+		}
+
+		var matches []int
+		for j, other := range outputs {
+			if other.Config.Alias == ref {
+				matches = append(matches, j)
+			}
+		}
+		if len(matches) == 0 {
+			for j, other := range outputs {
+				if other.Config.Alias == "" && other.Config.Name == ref {
+					matches = append(matches, j)
+				}
+			}
+		}
+
+		switch {
+		case len(matches) == 0:
+			return nil, fmt.Errorf("dead letter output %q of %s not found", ref, output.LogName())
+		case len(matches) > 1:
+			return nil, fmt.Errorf("dead letter output %q of %s is ambiguous; set an alias", ref, output.LogName())
+		case matches[0] == i:
+			return nil, fmt.Errorf("output %s cannot be its own dead letter output", output.LogName())
+		}
+		targets[i] = matches[0]
+	}
+
+	// Dead letters are not passed on, an output receiving the dead letters
+	// of another output cannot have a dead letter output itself.
+	for i, j := range targets {
+		switch {
+		case j < 0 || targets[j] < 0:
+			continue
+		case targets[j] == i:
+			return nil, fmt.Errorf("outputs %s and %s are each other's dead letter output",
+				outputs[i].LogName(), outputs[j].LogName())
+		default:
+			return nil, fmt.Errorf("dead letter output %s of %s cannot have a dead letter output",
+				outputs[j].LogName(), outputs[i].LogName())
+		}
+	}
+	return targets, nil
+}
+
+func linkDeadLetterOutput(output, target *models.RunningOutput) {
+	output.DeadLetter = target
+	target.DeadLetterOnly = true
+	log.Printf("D! [agent] Output %s receives the dead letters of %s",
+		target.LogName(), output.LogName())
+}
+
 func (a *Agent) startInputs(
 	dst chan<- telegraf.Metric,
 	inputs []*models.RunningInput,
@@ -290,7 +450,10 @@ func (a *Agent) runInputs(
 	unit *inputUnit,
 ) error {
 	var wg sync.WaitGroup
-	for _, input := range unit.inputs {
+
+	// stops holds the function stopping the gather of each running input.
+	stops := make(map[*models.RunningInput]func())
+	start := func(input *models.RunningInput, startTime time.Time) {
 		// Overwrite agent interval if this plugin has its own.
 		interval := a.Config.Agent.Interval.Duration
 		if input.Config.Interval != 0 {
@@ -315,27 +478,68 @@ func (a *Agent) runInputs(
 		} else {
 			ticker = NewUnalignedTicker(interval, jitter)
 		}
-		defer ticker.Stop()
 
 		acc := NewAccumulator(input, unit.dst)
 		acc.SetPrecision(getPrecision(precision, interval))
 
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
 		wg.Add(1)
-		go func(input *models.RunningInput) {
+		go func() {
 			defer wg.Done()
+			defer close(done)
+			defer ticker.Stop()
 			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
+		}()
+
+		stops[input] = func() {
+			cancel()
+			<-done
+		}
 	}
 
-	wg.Wait()
+	inputs := append([]*models.RunningInput(nil), unit.inputs...)
+	for _, input := range inputs {
+		start(input, startTime)
+	}
 
-	log.Printf("D! [agent] Stopping service inputs")
-	stopServiceInputs(unit.inputs)
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
 
-	close(unit.dst)
-	log.Printf("D! [agent] Input channel closed")
+			log.Printf("D! [agent] Stopping service inputs")
+			stopServiceInputs(inputs)
 
-	return nil
+			close(unit.dst)
+			log.Printf("D! [agent] Input channel closed")
+
+			return nil
+		case update := <-unit.updates:
+			for _, input := range update.remove {
+				log.Printf("D! [agent] Stopping input %s", input.LogName())
+				stops[input]()
+				delete(stops, input)
+				stopServiceInputs([]*models.RunningInput{input})
+				inputs = removeInput(inputs, input)
+			}
+			for _, input := range update.add {
+				log.Printf("D! [agent] Starting input %s", input.LogName())
+				start(input, time.Now())
+				inputs = append(inputs, input)
+			}
+			close(update.done)
+		}
+	}
+}
+
+func removeInput(inputs []*models.RunningInput, input *models.RunningInput) []*models.RunningInput {
+	for i, other := range inputs {
+		if other == input {
+			return append(inputs[:i], inputs[i+1:]...)
+		}
+	}
+	return inputs
 }
 
 // testStartInputs is a variation of startInputs for use in --test and --once
@@ -597,7 +801,7 @@ func (a *Agent) runAggregators(
 
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
 	}
@@ -608,7 +812,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -623,7 +827,7 @@ func (a *Agent) runAggregators(
 		cancel()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		wg.Add(1)
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
@@ -648,6 +852,145 @@ func (a *Agent) runAggregators(
 	return nil
 }
 
+// startChain sets up the processors and aggregators writing to dst and
+// calls Start on all processors.
+func (a *Agent) startChain(
+	startTime time.Time,
+	dst chan<- telegraf.Metric,
+	processors models.RunningProcessors,
+	aggProcessors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
+) (*chainUnit, error) {
+	unit := &chainUnit{startTime: startTime}
+
+	next := dst
+	var err error
+	if len(aggregators) != 0 {
+		aggC := next
+		if len(aggProcessors) != 0 {
+			aggC, unit.aggProcessors, err = a.startProcessors(next, aggProcessors)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		next, unit.aggregator, err = a.startAggregators(aggC, next, aggregators)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(processors) != 0 {
+		next, unit.processors, err = a.startProcessors(next, processors)
+		if err != nil {
+			for _, u := range unit.aggProcessors {
+				u.processor.Stop()
+			}
+			return nil, err
+		}
+	}
+
+	unit.src = next
+	return unit, nil
+}
+
+// runChain runs the processors and aggregators until the source channel is
+// closed and all metrics have been written.
+func (a *Agent) runChain(unit *chainUnit) {
+	var wg sync.WaitGroup
+	if unit.aggregator != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := a.runProcessors(unit.aggProcessors)
+			if err != nil {
+				log.Printf("E! [agent] Error running processors: %v", err)
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := a.runAggregators(unit.startTime, unit.aggregator)
+			if err != nil {
+				log.Printf("E! [agent] Error running aggregators: %v", err)
+			}
+		}()
+	}
+
+	if unit.processors != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := a.runProcessors(unit.processors)
+			if err != nil {
+				log.Printf("E! [agent] Error running processors: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// startRelayedChain starts a chain writing to a channel of its own, to be run
+// by a relayUnit.
+func (a *Agent) startRelayedChain(
+	startTime time.Time,
+	processors models.RunningProcessors,
+	aggProcessors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
+) (*relayedChain, error) {
+	out := make(chan telegraf.Metric, 100)
+	unit, err := a.startChain(startTime, out, processors, aggProcessors, aggregators)
+	if err != nil {
+		return nil, err
+	}
+	return &relayedChain{unit: unit, out: out}, nil
+}
+
+// runRelay runs the chain and forwards metrics through it until the source
+// channel is closed and all metrics have been written.  A replaced chain is
+// closed and finishes processing the metrics it holds in the background.
+func (a *Agent) runRelay(unit *relayUnit) {
+	var wg sync.WaitGroup
+	run := func(chain *relayedChain) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runChain(chain.unit)
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for metric := range chain.out {
+				unit.dst <- metric
+			}
+		}()
+	}
+
+	chain := unit.chain
+	run(chain)
+	for {
+		select {
+		case metric, ok := <-unit.src:
+			if !ok {
+				close(chain.unit.src)
+				wg.Wait()
+
+				close(unit.dst)
+				log.Printf("D! [agent] Relay channel closed")
+				return
+			}
+			chain.unit.src <- metric
+		case next := <-unit.replace:
+			run(next)
+			close(chain.unit.src)
+			chain = next
+		}
+	}
+}
+
 func updateWindow(start time.Time, roundInterval bool, period time.Duration) (time.Time, time.Time) {
 	var until time.Time
 	if roundInterval {
@@ -739,15 +1082,14 @@ func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput)
 func (a *Agent) runOutputs(
 	unit *outputUnit,
 ) error {
-	var wg sync.WaitGroup
-
 	// Start flush loop
 	interval := a.Config.Agent.FlushInterval.Duration
 	jitter := a.Config.Agent.FlushJitter.Duration
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	for _, output := range unit.outputs {
+	// stops holds the function stopping the flush loop of each running
+	// output, the loop flushes the output one last time before returning.
+	stops := make(map[*models.RunningOutput]func())
+	start := func(output *models.RunningOutput) {
 		interval := interval
 		// Overwrite agent flush_interval if this plugin has its own.
 		if output.Config.FlushInterval != 0 {
@@ -760,32 +1102,127 @@ func (a *Agent) runOutputs(
 			jitter = output.Config.FlushJitter
 		}
 
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
 
 			ticker := NewRollingTicker(interval, jitter)
 			defer ticker.Stop()
 
 			a.flushLoop(ctx, output, ticker)
-		}(output)
+		}()
+
+		stops[output] = func() {
+			cancel()
+			<-done
+		}
 	}
 
-	for metric := range unit.src {
-		for i, output := range unit.outputs {
-			if i == len(a.Config.Outputs)-1 {
-				output.AddMetric(metric)
-			} else {
-				output.AddMetric(metric.Copy())
+	// stop flushes and closes the outputs, handing their buffer over to
+	// the output taking it over if any.  Dead letter outputs are flushed
+	// last so they can receive the batches given up on during the final
+	// flush of the other outputs.
+	stop := func(outputs []*models.RunningOutput, takeovers map[*models.RunningOutput]*models.RunningOutput) {
+		for _, deadLetterOnly := range []bool{false, true} {
+			var wg sync.WaitGroup
+			for _, output := range outputs {
+				if output.DeadLetterOnly != deadLetterOnly {
+					continue
+				}
+				wg.Add(1)
+				go func(stop func()) {
+					defer wg.Done()
+					stop()
+				}(stops[output])
+			}
+			wg.Wait()
+		}
+
+		for _, output := range outputs {
+			delete(stops, output)
+			if next, ok := takeovers[output]; ok {
+				next.TakeBuffer(output)
 			}
+			output.Close()
 		}
 	}
 
-	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
-	cancel()
-	wg.Wait()
+	outputs := append([]*models.RunningOutput(nil), unit.outputs...)
+	for _, output := range outputs {
+		start(output)
+	}
+	fanout := fanoutOutputs(outputs)
 
-	return nil
+	for {
+		select {
+		case metric, ok := <-unit.src:
+			if !ok {
+				log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
+				stop(outputs, nil)
+				return nil
+			}
+
+			if len(fanout) == 0 {
+				metric.Drop()
+				continue
+			}
+			for i, output := range fanout {
+				if i == len(fanout)-1 {
+					output.AddMetric(metric)
+				} else {
+					output.AddMetric(metric.Copy())
+				}
+			}
+		case update := <-unit.updates:
+			// The outputs whose buffer is taken over are stopped first,
+			// the buffer is never written by two outputs.
+			var replaced []*models.RunningOutput
+			takeovers := make(map[*models.RunningOutput]*models.RunningOutput)
+			for _, output := range update.add {
+				if from, ok := update.bufferFrom[output]; ok {
+					log.Printf("D! [agent] Stopping output %s", from.LogName())
+					outputs = removeOutput(outputs, from)
+					replaced = append(replaced, from)
+					takeovers[from] = output
+				}
+			}
+			stop(replaced, takeovers)
+
+			for _, output := range update.add {
+				log.Printf("D! [agent] Starting output %s", output.LogName())
+				start(output)
+				outputs = append(outputs, output)
+			}
+			for _, output := range update.remove {
+				log.Printf("D! [agent] Stopping output %s", output.LogName())
+				outputs = removeOutput(outputs, output)
+			}
+			fanout = fanoutOutputs(outputs)
+			stop(update.remove, nil)
+			close(update.done)
+		}
+	}
+}
+
+// fanoutOutputs returns the outputs receiving the metrics of the inputs.
+func fanoutOutputs(outputs []*models.RunningOutput) []*models.RunningOutput {
+	var fanout []*models.RunningOutput
+	for _, output := range outputs {
+		if !output.DeadLetterOnly {
+			fanout = append(fanout, output)
+		}
+	}
+	return fanout
+}
+
+func removeOutput(outputs []*models.RunningOutput, output *models.RunningOutput) []*models.RunningOutput {
+	for i, other := range outputs {
+		if other == output {
+			return append(outputs[:i], outputs[i+1:]...)
+		}
+	}
+	return outputs
 }
 
 // flushLoop runs an output's flush function periodically until the context is
@@ -806,23 +1243,29 @@ func (a *Agent) flushLoop(
 	watchForFlushSignal(flushRequested)
 	defer stopListeningForFlushSignal(flushRequested)
 
+	// watch for flush requests from the management API
+	apiFlushRequested := a.watchForFlushRequest(output)
+	defer a.stopListeningForFlushRequest(output)
+
 	for {
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.Flush))
 			return
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.Flush))
 			return
 		case <-ticker.Elapsed():
 			logError(a.flushOnce(output, ticker, output.Write))
 		case <-flushRequested:
-			logError(a.flushOnce(output, ticker, output.Write))
+			logError(a.flushOnce(output, ticker, output.Flush))
+		case <-apiFlushRequested:
+			logError(a.flushOnce(output, ticker, output.Flush))
 		case <-output.BatchReady:
 			// Favor the ticker over batch ready
 			select {
@@ -835,6 +1278,41 @@ func (a *Agent) flushLoop(
 	}
 }
 
+// watchForFlushRequest returns the channel notified when a flush of the
+// output is requested with requestFlush.
+func (a *Agent) watchForFlushRequest(output *models.RunningOutput) <-chan struct{} {
+	a.flushMutex.Lock()
+	defer a.flushMutex.Unlock()
+
+	if a.flushRequests == nil {
+		a.flushRequests = make(map[*models.RunningOutput]chan struct{})
+	}
+	c := make(chan struct{}, 1)
+	a.flushRequests[output] = c
+	return c
+}
+
+func (a *Agent) stopListeningForFlushRequest(output *models.RunningOutput) {
+	a.flushMutex.Lock()
+	defer a.flushMutex.Unlock()
+
+	delete(a.flushRequests, output)
+}
+
+// requestFlush asks every running output to flush, as on SIGUSR1.  It does
+// not wait for the flushes to complete.
+func (a *Agent) requestFlush() {
+	a.flushMutex.Lock()
+	defer a.flushMutex.Unlock()
+
+	for _, c := range a.flushRequests {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // flushOnce runs the output's Write function once, logging a warning each
 // interval it fails to complete before.
 func (a *Agent) flushOnce(
@@ -906,70 +1384,24 @@ func (a *Agent) test(ctx context.Context, wait time.Duration, outputC chan<- tel
 
 	startTime := time.Now()
 
-	next := outputC
-
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au, err = a.startAggregators(procC, next, a.Config.Aggregators)
-		if err != nil {
-			return err
-		}
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	chain, err := a.startChain(startTime, outputC,
+		a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators)
+	if err != nil {
+		return err
 	}
 
-	iu, err := a.testStartInputs(next, a.Config.Inputs)
+	iu, err := a.testStartInputs(chain.src, a.Config.Inputs)
 	if err != nil {
 		return err
 	}
 
 	var wg sync.WaitGroup
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(apu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runAggregators(startTime, au)
-			if err != nil {
-				log.Printf("E! [agent] Error running aggregators: %v", err)
-			}
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(pu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runChain(chain)
+	}()
 
 	wg.Add(1)
 	go func() {
@@ -1021,37 +1453,18 @@ func (a *Agent) once(ctx context.Context, wait time.Duration) error {
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	outputC, ou, err := a.startOutputs(ctx, a.Config.Outputs)
 	if err != nil {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au, err = a.startAggregators(procC, next, a.Config.Aggregators)
-		if err != nil {
-			return err
-		}
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	chain, err := a.startChain(startTime, outputC,
+		a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators)
+	if err != nil {
+		return err
 	}
 
-	iu, err := a.testStartInputs(next, a.Config.Inputs)
+	iu, err := a.testStartInputs(chain.src, a.Config.Inputs)
 	if err != nil {
 		return err
 	}
@@ -1066,36 +1479,11 @@ func (a *Agent) once(ctx context.Context, wait time.Duration) error {
 		}
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(apu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runAggregators(startTime, au)
-			if err != nil {
-				log.Printf("E! [agent] Error running aggregators: %v", err)
-			}
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			err := a.runProcessors(pu)
-			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
-			}
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runChain(chain)
+	}()
 
 	wg.Add(1)
 	go func() {
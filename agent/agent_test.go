@@ -166,3 +166,83 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestAgent_LinkDeadLetterOutputs(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  dead_letter_output = "dead"
+
+[[outputs.file]]
+  alias = "dead"
+`))
+	require.NoError(t, err)
+	a, _ := NewAgent(c)
+	require.NoError(t, a.linkDeadLetterOutputs())
+	// The order of the outputs is not defined by the config.
+	output, dead := c.Outputs[0], c.Outputs[1]
+	if output.Config.Alias == "dead" {
+		output, dead = dead, output
+	}
+	require.Equal(t, dead, output.DeadLetter)
+	require.True(t, dead.DeadLetterOnly)
+	require.False(t, output.DeadLetterOnly)
+
+	c = config.NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  dead_letter_output = "missing"
+`))
+	require.NoError(t, err)
+	a, _ = NewAgent(c)
+	require.Error(t, a.linkDeadLetterOutputs())
+
+	c = config.NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  alias = "self"
+  dead_letter_output = "self"
+`))
+	require.NoError(t, err)
+	a, _ = NewAgent(c)
+	require.Error(t, a.linkDeadLetterOutputs())
+}
+
+func TestAgent_LinkDeadLetterOutputsCycle(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  alias = "a"
+  dead_letter_output = "b"
+
+[[outputs.file]]
+  alias = "b"
+  dead_letter_output = "a"
+`))
+	require.NoError(t, err)
+	a, _ := NewAgent(c)
+	err = a.linkDeadLetterOutputs()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "each other's dead letter output")
+}
+
+func TestAgent_LinkDeadLetterOutputsChain(t *testing.T) {
+	c := config.NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  alias = "a"
+  dead_letter_output = "b"
+
+[[outputs.file]]
+  alias = "b"
+  dead_letter_output = "c"
+
+[[outputs.file]]
+  alias = "c"
+`))
+	require.NoError(t, err)
+	a, _ := NewAgent(c)
+	err = a.linkDeadLetterOutputs()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot have a dead letter output")
+}
@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const apiShutdownTimeout = 5 * time.Second
+
+// apiServer is the local HTTP management API of a running agent.
+//
+//	GET  /api/v1/plugins  list the running plugins, their stats and status
+//	POST /api/v1/reload   reload the config, same as SIGHUP
+//	POST /api/v1/flush    flush all outputs, same as SIGUSR1
+type apiServer struct {
+	agent  *Agent
+	server *http.Server
+}
+
+// pluginInfo is the description of a running plugin returned by the API.
+// Only the settings common to all plugins of a type are included as the
+// plugin specific settings can contain credentials.
+type pluginInfo struct {
+	Name   string                      `json:"name"`
+	Alias  string                      `json:"alias,omitempty"`
+	Config interface{}                 `json:"config"`
+	Stats  map[string]map[string]int64 `json:"stats"`
+	Status *statusInfo                 `json:"status,omitempty"`
+	Buffer *bufferInfo                 `json:"buffer,omitempty"`
+}
+
+type statusInfo struct {
+	LastRun       *time.Time `json:"last_run,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+type bufferInfo struct {
+	Strategy string `json:"strategy"`
+	Length   int    `json:"length"`
+	// Limit is the maximum number of metrics of the memory buffer, the disk
+	// buffer is bounded by SizeLimit bytes instead.
+	Limit     int   `json:"limit,omitempty"`
+	Size      int64 `json:"size_bytes,omitempty"`
+	SizeLimit int64 `json:"size_limit_bytes,omitempty"`
+}
+
+func newBufferInfo(output *models.RunningOutput) *bufferInfo {
+	info := &bufferInfo{
+		Strategy: models.BufferStrategyMemory,
+		Length:   output.BufferLength(),
+	}
+	if size, limit, ok := output.BufferSize(); ok {
+		info.Strategy = models.BufferStrategyDisk
+		info.Size = size
+		info.SizeLimit = limit
+		return info
+	}
+	info.Limit = output.MetricBufferLimit
+	return info
+}
+
+type pluginsResponse struct {
+	Inputs      []pluginInfo `json:"inputs"`
+	Processors  []pluginInfo `json:"processors"`
+	Aggregators []pluginInfo `json:"aggregators"`
+	Outputs     []pluginInfo `json:"outputs"`
+}
+
+// startAPI starts serving the management API on address until stop is
+// called.
+func startAPI(a *Agent, address string) (*apiServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	api := &apiServer{agent: a}
+	api.server = &http.Server{Handler: api.handler()}
+
+	log.Printf("I! [agent] Starting management API at: http://%s", listener.Addr())
+	go func() {
+		err := api.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("E! [agent] Error serving management API: %v", err)
+		}
+	}()
+	return api, nil
+}
+
+// stop shuts down the server, waiting for active requests to complete.
+func (api *apiServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+	defer cancel()
+
+	err := api.server.Shutdown(ctx)
+	if err != nil {
+		log.Printf("E! [agent] Error stopping management API: %v", err)
+	}
+}
+
+func (api *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/plugins", api.plugins)
+	mux.HandleFunc("/api/v1/reload", api.reload)
+	mux.HandleFunc("/api/v1/flush", api.flush)
+	return mux
+}
+
+func (api *apiServer) plugins(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	api.agent.configMutex.RLock()
+	defer api.agent.configMutex.RUnlock()
+
+	cfg := api.agent.Config
+	resp := pluginsResponse{
+		Inputs:      make([]pluginInfo, 0, len(cfg.Inputs)),
+		Processors:  make([]pluginInfo, 0, len(cfg.Processors)),
+		Aggregators: make([]pluginInfo, 0, len(cfg.Aggregators)),
+		Outputs:     make([]pluginInfo, 0, len(cfg.Outputs)),
+	}
+
+	for _, input := range cfg.Inputs {
+		status := input.Status()
+		resp.Inputs = append(resp.Inputs, pluginInfo{
+			Name:   input.Config.Name,
+			Alias:  input.Config.Alias,
+			Config: input.Config,
+			Stats:  pluginStats("input", input.Config.Name, input.Config.Alias, "gather"),
+			Status: newStatusInfo(status),
+		})
+	}
+
+	// Processors run both before and after the aggregators, the two instances
+	// share their stats.
+	for _, processor := range cfg.Processors {
+		resp.Processors = append(resp.Processors, pluginInfo{
+			Name:   processor.Config.Name,
+			Alias:  processor.Config.Alias,
+			Config: processor.Config,
+			Stats:  pluginStats("processor", processor.Config.Name, processor.Config.Alias, "process"),
+		})
+	}
+
+	for _, aggregator := range cfg.Aggregators {
+		resp.Aggregators = append(resp.Aggregators, pluginInfo{
+			Name:   aggregator.Config.Name,
+			Alias:  aggregator.Config.Alias,
+			Config: aggregator.Config,
+			Stats:  pluginStats("aggregator", aggregator.Config.Name, aggregator.Config.Alias, "aggregate"),
+		})
+	}
+
+	for _, output := range cfg.Outputs {
+		status := output.Status()
+		resp.Outputs = append(resp.Outputs, pluginInfo{
+			Name:   output.Config.Name,
+			Alias:  output.Config.Alias,
+			Config: output.Config,
+			Stats:  pluginStats("output", output.Config.Name, output.Config.Alias, "write"),
+			Status: newStatusInfo(status),
+			Buffer: newBufferInfo(output),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (api *apiServer) reload(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	if api.agent.Reload == nil {
+		http.Error(w, "reload is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	log.Printf("I! [agent] Reload requested by management API")
+	api.agent.Reload()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (api *apiServer) flush(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	log.Printf("I! [agent] Flush requested by management API")
+	api.agent.requestFlush()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// pluginStats returns the selfstat values of a plugin keyed by measurement.
+func pluginStats(tag, name, alias string, measurement string) map[string]map[string]int64 {
+	tags := map[string]string{tag: name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	return map[string]map[string]int64{
+		"internal_" + measurement: selfstat.Lookup(measurement, tags),
+	}
+}
+
+func newStatusInfo(status models.PluginStatus) *statusInfo {
+	info := &statusInfo{LastError: status.LastError}
+	if !status.LastRun.IsZero() {
+		info.LastRun = &status.LastRun
+	}
+	if !status.LastErrorTime.IsZero() {
+		info.LastErrorTime = &status.LastErrorTime
+	}
+	return info
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
+		http.StatusMethodNotAllowed)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("E! [agent] Error writing management API response: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/config"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/minmax"
+	_ "github.com/influxdata/telegraf/plugins/processors/rename"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPI(t *testing.T) (*Agent, *httptest.Server) {
+	return newTestAPIWithConfig(t, `
+[[inputs.cpu]]
+  alias = "all_cpus"
+
+[[processors.rename]]
+
+[[aggregators.minmax]]
+
+[[outputs.file]]
+  metric_buffer_limit = 100
+`)
+}
+
+func newTestAPIWithConfig(t *testing.T, data string) (*Agent, *httptest.Server) {
+	c := config.NewConfig()
+	err := c.LoadConfigData([]byte(data))
+	require.NoError(t, err)
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	api := &apiServer{agent: a}
+	ts := httptest.NewServer(api.handler())
+	t.Cleanup(ts.Close)
+	return a, ts
+}
+
+func TestAPI_Plugins(t *testing.T) {
+	a, ts := newTestAPI(t)
+	a.Config.Outputs[0].AddMetric(testutil.TestMetric(42))
+	a.Config.Inputs[0].Log().Errorf("gather failed")
+
+	resp, err := http.Get(ts.URL + "/api/v1/plugins")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var plugins pluginsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&plugins))
+
+	require.Len(t, plugins.Inputs, 1)
+	input := plugins.Inputs[0]
+	require.Equal(t, "cpu", input.Name)
+	require.Equal(t, "all_cpus", input.Alias)
+	require.Equal(t, "all_cpus", input.Config.(map[string]interface{})["Alias"])
+	require.Contains(t, input.Stats["internal_gather"], "metrics_gathered")
+	require.Equal(t, "gather failed", input.Status.LastError)
+	require.NotNil(t, input.Status.LastErrorTime)
+	require.Nil(t, input.Status.LastRun)
+
+	require.Len(t, plugins.Processors, 1)
+	require.Equal(t, "rename", plugins.Processors[0].Name)
+	require.Len(t, plugins.Aggregators, 1)
+	require.Equal(t, "minmax", plugins.Aggregators[0].Name)
+
+	require.Len(t, plugins.Outputs, 1)
+	output := plugins.Outputs[0]
+	require.Equal(t, "file", output.Name)
+	require.Equal(t, &bufferInfo{Strategy: "memory", Length: 1, Limit: 100}, output.Buffer)
+	require.Contains(t, output.Stats["internal_write"], "buffer_size")
+}
+
+func TestAPI_PluginsDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-api")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a, ts := newTestAPIWithConfig(t, fmt.Sprintf(`
+[[outputs.file]]
+  metric_buffer_limit = 100
+  buffer_strategy = "disk"
+  buffer_directory = %q
+  buffer_size_limit = "1MiB"
+`, dir))
+	output := a.Config.Outputs[0]
+	require.NoError(t, output.Init())
+	defer output.Close()
+	output.AddMetric(testutil.TestMetric(42))
+	size, limit, ok := output.BufferSize()
+	require.True(t, ok)
+	require.NotZero(t, size)
+
+	resp, err := http.Get(ts.URL + "/api/v1/plugins")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var plugins pluginsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&plugins))
+	require.Len(t, plugins.Outputs, 1)
+	require.Equal(t, &bufferInfo{
+		Strategy:  "disk",
+		Length:    1,
+		Size:      size,
+		SizeLimit: 1024 * 1024,
+	}, plugins.Outputs[0].Buffer)
+	require.Equal(t, int64(1024*1024), limit)
+}
+
+func TestAPI_Reload(t *testing.T) {
+	a, ts := newTestAPI(t)
+
+	resp, err := http.Post(ts.URL+"/api/v1/reload", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+
+	var reloads int
+	a.Reload = func() { reloads++ }
+	resp, err = http.Post(ts.URL+"/api/v1/reload", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Equal(t, 1, reloads)
+
+	resp, err = http.Get(ts.URL + "/api/v1/reload")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, 1, reloads)
+}
+
+func TestAPI_Flush(t *testing.T) {
+	a, ts := newTestAPI(t)
+	requested := a.watchForFlushRequest(a.Config.Outputs[0])
+	defer a.stopListeningForFlushRequest(a.Config.Outputs[0])
+
+	resp, err := http.Post(ts.URL+"/api/v1/flush", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case <-requested:
+	default:
+		t.Fatal("flush was not requested")
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+// CheckPlugins initializes the plugins of the config without starting them
+// and returns all errors found.  Besides initialization errors it reports
+// plugins of the same type sharing an alias and dead letter outputs that
+// cannot be resolved.  The buffers of the outputs are not opened, a disk
+// buffer in use by a running agent is left untouched.
+func CheckPlugins(c *config.Config) []error {
+	var errs []error
+	fail := func(plugin interface{}, err error) {
+		if source := c.Source(plugin); source != "" {
+			err = fmt.Errorf("%s: %w", source, err)
+		}
+		errs = append(errs, err)
+	}
+
+	aliases := make(map[string]interface{})
+	checkAlias := func(plugin interface{}, name, alias string) {
+		if alias == "" {
+			return
+		}
+		key := name + "::" + alias
+		if first, ok := aliases[key]; ok {
+			msg := fmt.Sprintf("duplicate alias %q of %s", alias, name)
+			if source := c.Source(first); source != "" {
+				msg += ", first used at " + source
+			}
+			fail(plugin, errors.New(msg))
+			return
+		}
+		aliases[key] = plugin
+	}
+
+	for _, input := range c.Inputs {
+		checkAlias(input, "inputs."+input.Config.Name, input.Config.Alias)
+		if err := input.Init(); err != nil {
+			fail(input, fmt.Errorf("could not initialize input %s: %v", input.LogName(), err))
+		}
+	}
+	for _, processor := range c.Processors {
+		checkAlias(processor, "processors."+processor.Config.Name, processor.Config.Alias)
+		if err := processor.Init(); err != nil {
+			fail(processor, fmt.Errorf("could not initialize processor %s: %v", processor.LogName(), err))
+		}
+	}
+	for _, aggregator := range c.Aggregators {
+		checkAlias(aggregator, "aggregators."+aggregator.Config.Name, aggregator.Config.Alias)
+		if err := aggregator.Init(); err != nil {
+			fail(aggregator, fmt.Errorf("could not initialize aggregator %s: %v", aggregator.LogName(), err))
+		}
+	}
+	for _, output := range c.Outputs {
+		checkAlias(output, "outputs."+output.Config.Name, output.Config.Alias)
+		if err := output.InitPlugin(); err != nil {
+			fail(output, fmt.Errorf("could not initialize output %s: %v", output.LogName(), err))
+		}
+	}
+
+	if _, err := deadLetterTargets(c.Outputs); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/stretchr/testify/require"
+)
+
+type checkInput struct {
+	initErr error
+}
+
+func (i *checkInput) SampleConfig() string                  { return "" }
+func (i *checkInput) Description() string                   { return "" }
+func (i *checkInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *checkInput) Init() error                           { return i.initErr }
+
+func TestCheckPlugins(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&checkInput{}, &models.InputConfig{Name: "check", Alias: "a"}),
+		models.NewRunningInput(&checkInput{initErr: errors.New("missing server")},
+			&models.InputConfig{Name: "check", Alias: "b"}),
+		models.NewRunningInput(&checkInput{}, &models.InputConfig{Name: "check", Alias: "a"}),
+		models.NewRunningInput(&checkInput{}, &models.InputConfig{Name: "other", Alias: "a"}),
+	}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput("test", &reloadOutput{}, &models.OutputConfig{
+			Name:             "test",
+			DeadLetterOutput: "nowhere",
+		}, 0, 0),
+	}
+
+	var errs []string
+	for _, err := range CheckPlugins(c) {
+		errs = append(errs, err.Error())
+	}
+	require.Equal(t, []string{
+		`could not initialize input inputs.check::b: missing server`,
+		`duplicate alias "a" of inputs.check`,
+		`dead letter output "nowhere" of outputs.test not found`,
+	}, errs)
+}
+
+func TestCheckPluginsValid(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&checkInput{}, &models.InputConfig{Name: "check"}),
+		models.NewRunningInput(&checkInput{}, &models.InputConfig{Name: "check"}),
+	}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput("test", &reloadOutput{}, &models.OutputConfig{Name: "test"}, 0, 0),
+	}
+	require.Empty(t, CheckPlugins(c))
+}
+
+func TestCheckPluginsSkipsDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-check")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	bufferDir := filepath.Join(dir, "buffer")
+
+	c := config.NewConfig()
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput("test", &reloadOutput{}, &models.OutputConfig{
+			Name:            "test",
+			BufferStrategy:  models.BufferStrategyDisk,
+			BufferDirectory: bufferDir,
+		}, 0, 0),
+	}
+	require.Empty(t, CheckPlugins(c))
+
+	_, err = os.Stat(bufferDir)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,405 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+)
+
+// ErrRestartRequired is returned by ApplyConfig when the new config changes
+// settings shared by all plugins, the agent must be restarted to apply it.
+var ErrRestartRequired = errors.New("agent settings or global tags changed")
+
+// stateSaver is implemented by plugins keeping their state in a file across
+// restarts, the state can be saved while the plugin runs.
+type stateSaver interface {
+	SaveState() error
+}
+
+type reloadRequest struct {
+	config *config.Config
+	err    chan error
+}
+
+// pipeline is the running units of an agent started by Run.
+type pipeline struct {
+	inputs  *inputUnit
+	relay   *relayUnit
+	outputs *outputUnit
+}
+
+// ApplyConfig replaces the config of the agent while it runs.  Inputs and
+// outputs are only restarted if their settings changed, unchanged service
+// inputs keep listening and unchanged outputs keep their buffer.  The
+// processors and aggregators are connected in a chain, they are all
+// restarted if any of them changed.
+//
+// The running config is kept if the new plugins cannot be started.
+// ErrRestartRequired is returned if the agent settings or global tags
+// changed.
+func (a *Agent) ApplyConfig(ctx context.Context, c *config.Config) error {
+	req := &reloadRequest{
+		config: c,
+		err:    make(chan error, 1),
+	}
+
+	select {
+	case a.reloadRequests <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-req.err
+}
+
+// serveReloads applies the configs passed to ApplyConfig until the context
+// is done.
+func (a *Agent) serveReloads(ctx context.Context, p *pipeline) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-a.reloadRequests:
+			req.err <- a.applyConfig(ctx, req.config, p)
+		}
+	}
+}
+
+func (a *Agent) applyConfig(ctx context.Context, c *config.Config, p *pipeline) error {
+	if !reflect.DeepEqual(a.Config.Agent, c.Agent) || !reflect.DeepEqual(a.Config.Tags, c.Tags) {
+		return ErrRestartRequired
+	}
+
+	targets, err := deadLetterTargets(c.Outputs)
+	if err != nil {
+		return err
+	}
+
+	// Plugins are kept if a plugin with the same settings is in the new
+	// config, the instance of the new config is then discarded.
+	inputMatches, removedInputs := matchIDs(inputIDs(a.Config.Inputs), inputIDs(c.Inputs))
+	inputs := make([]*models.RunningInput, len(c.Inputs))
+	var addInputs, removeInputs []*models.RunningInput
+	for i, j := range inputMatches {
+		if j < 0 {
+			inputs[i] = c.Inputs[i]
+			addInputs = append(addInputs, c.Inputs[i])
+			continue
+		}
+		inputs[i] = a.Config.Inputs[j]
+	}
+	for _, j := range removedInputs {
+		removeInputs = append(removeInputs, a.Config.Inputs[j])
+	}
+
+	outputMatches, removedOutputs := matchIDs(
+		runningOutputIDs(a.Config.Outputs), outputIDs(c.Outputs, targets))
+	outputs := make([]*models.RunningOutput, len(c.Outputs))
+	var addOutputs, removeOutputs []*models.RunningOutput
+	for i, j := range outputMatches {
+		if j < 0 {
+			outputs[i] = c.Outputs[i]
+			addOutputs = append(addOutputs, c.Outputs[i])
+			continue
+		}
+		outputs[i] = a.Config.Outputs[j]
+	}
+	for _, j := range removedOutputs {
+		removeOutputs = append(removeOutputs, a.Config.Outputs[j])
+	}
+
+	// A disk buffer cannot be opened twice, a new output using the buffer
+	// directory of a removed output takes over the open buffer instead.
+	bufferFrom := make(map[*models.RunningOutput]*models.RunningOutput)
+	for _, output := range addOutputs {
+		for i, old := range removeOutputs {
+			if sameBufferDirectory(output, old) {
+				bufferFrom[output] = old
+				removeOutputs = append(removeOutputs[:i], removeOutputs[i+1:]...)
+				break
+			}
+		}
+	}
+
+	chainChanged := !sameIDs(processorIDs(a.Config.Processors), processorIDs(c.Processors)) ||
+		!sameIDs(aggregatorIDs(a.Config.Aggregators), aggregatorIDs(c.Aggregators))
+
+	if len(addInputs) == 0 && len(removeInputs) == 0 &&
+		len(addOutputs) == 0 && len(removeOutputs) == 0 && !chainChanged {
+		log.Printf("I! [agent] Config unchanged")
+		return nil
+	}
+
+	// The new outputs opening their own buffer must close it if the config
+	// is not applied, the connected ones are closed as a whole.  Outputs
+	// taking over the buffer of a removed output have no buffer yet.
+	var connected int
+	closeOutputs := func() {
+		for i, output := range addOutputs {
+			if i < connected {
+				output.Close()
+				continue
+			}
+			if _, ok := bufferFrom[output]; !ok {
+				output.CloseBuffer()
+			}
+		}
+	}
+
+	err = initInputs(addInputs)
+	if err != nil {
+		return err
+	}
+	for _, output := range addOutputs {
+		if _, ok := bufferFrom[output]; ok {
+			err = output.InitPlugin()
+		} else {
+			err = output.Init()
+		}
+		if err != nil {
+			closeOutputs()
+			return fmt.Errorf("could not initialize output %s: %v",
+				output.Config.Name, err)
+		}
+	}
+	if chainChanged {
+		// The running chain is only stopped once the new chain is started,
+		// its state is saved first so the new plugins restore the latest
+		// state.
+		a.saveChainState()
+
+		err = initProcessors(c.Processors)
+		if err != nil {
+			closeOutputs()
+			return err
+		}
+		err = initAggregators(c.Aggregators)
+		if err != nil {
+			closeOutputs()
+			return err
+		}
+		err = initProcessors(c.AggProcessors)
+		if err != nil {
+			closeOutputs()
+			return err
+		}
+	}
+
+	// The dead letter links of kept outputs are unchanged, they are part of
+	// the output ID.
+	for i, output := range outputs {
+		if targets[i] < 0 || outputMatches[i] >= 0 {
+			continue
+		}
+		target := outputs[targets[i]]
+		if outputMatches[targets[i]] >= 0 {
+			output.DeadLetter = target
+			continue
+		}
+		linkDeadLetterOutput(output, target)
+	}
+
+	// Start the new plugins, nothing is changed in the running pipeline
+	// until all of them are started.
+	for _, output := range addOutputs {
+		err := a.connectOutput(ctx, output)
+		if err != nil {
+			closeOutputs()
+			return fmt.Errorf("connecting output %s: %w", output.LogName(), err)
+		}
+		connected++
+	}
+
+	_, err = a.startInputs(p.inputs.dst, addInputs)
+	if err != nil {
+		closeOutputs()
+		return err
+	}
+
+	var chain *relayedChain
+	if chainChanged {
+		chain, err = a.startRelayedChain(time.Now(),
+			c.Processors, c.AggProcessors, c.Aggregators)
+		if err != nil {
+			stopServiceInputs(addInputs)
+			closeOutputs()
+			return err
+		}
+	}
+
+	// Hand over the new plugins to the pipeline, new outputs are added first
+	// and old ones removed last so metrics in flight are not lost.
+	update := &outputUpdate{add: addOutputs, bufferFrom: bufferFrom, done: make(chan struct{})}
+	p.outputs.updates <- update
+	<-update.done
+
+	if chainChanged {
+		log.Printf("D! [agent] Restarting processors and aggregators")
+		p.relay.replace <- chain
+	}
+
+	inputUpdate := &inputUpdate{add: addInputs, remove: removeInputs, done: make(chan struct{})}
+	p.inputs.updates <- inputUpdate
+	<-inputUpdate.done
+
+	update = &outputUpdate{remove: removeOutputs, done: make(chan struct{})}
+	p.outputs.updates <- update
+	<-update.done
+
+	a.configMutex.Lock()
+	a.Config.Inputs = inputs
+	a.Config.Outputs = outputs
+	if chainChanged {
+		a.Config.Processors = c.Processors
+		a.Config.AggProcessors = c.AggProcessors
+		a.Config.Aggregators = c.Aggregators
+	}
+	a.configMutex.Unlock()
+
+	log.Printf("I! [agent] Applied config: started %d and stopped %d inputs, "+
+		"started %d and stopped %d outputs, restarted processors and aggregators: %t",
+		len(addInputs), len(removeInputs), len(addOutputs), len(removeOutputs)+len(bufferFrom), chainChanged)
+	return nil
+}
+
+// saveChainState saves the state of the running processors and aggregators
+// keeping state.
+func (a *Agent) saveChainState() {
+	save := func(plugin interface{}, name string) {
+		if p, ok := plugin.(stateSaver); ok {
+			if err := p.SaveState(); err != nil {
+				log.Printf("E! [agent] Saving state of %s: %v", name, err)
+			}
+		}
+	}
+
+	for _, processor := range a.Config.Processors {
+		save(processor.Processor, processor.LogName())
+	}
+	for _, processor := range a.Config.AggProcessors {
+		save(processor.Processor, processor.LogName())
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		save(aggregator.Aggregator, aggregator.LogName())
+	}
+}
+
+// sameBufferDirectory returns true if both outputs use a disk buffer stored in
+// the same directory.
+func sameBufferDirectory(a, b *models.RunningOutput) bool {
+	if a.Config.BufferStrategy != models.BufferStrategyDisk ||
+		b.Config.BufferStrategy != models.BufferStrategyDisk {
+		return false
+	}
+	return filepath.Clean(a.Config.BufferDirectory) == filepath.Clean(b.Config.BufferDirectory)
+}
+
+// matchIDs pairs the running plugins with the plugins of a new config having
+// the same ID.  It returns for each new plugin the index of its running
+// plugin, or -1 if there is none, and the indexes of the running plugins
+// without a new plugin.
+func matchIDs(running, next []string) ([]int, []int) {
+	unmatched := make(map[string][]int)
+	for i, id := range running {
+		unmatched[id] = append(unmatched[id], i)
+	}
+
+	matches := make([]int, len(next))
+	for i, id := range next {
+		matches[i] = -1
+		if indexes := unmatched[id]; len(indexes) > 0 {
+			matches[i] = indexes[0]
+			unmatched[id] = indexes[1:]
+		}
+	}
+
+	var removed []int
+	for _, indexes := range unmatched {
+		removed = append(removed, indexes...)
+	}
+	sort.Ints(removed)
+	return matches, removed
+}
+
+// sameIDs returns true if both lists hold the same IDs in any order.
+func sameIDs(a, b []string) bool {
+	matches, removed := matchIDs(a, b)
+	if len(removed) > 0 {
+		return false
+	}
+	for _, j := range matches {
+		if j < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func inputIDs(inputs []*models.RunningInput) []string {
+	ids := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		ids = append(ids, input.Config.ID)
+	}
+	return ids
+}
+
+func processorIDs(processors models.RunningProcessors) []string {
+	ids := make([]string, 0, len(processors))
+	for _, processor := range processors {
+		ids = append(ids, processor.Config.ID)
+	}
+	return ids
+}
+
+func aggregatorIDs(aggregators []*models.RunningAggregator) []string {
+	ids := make([]string, 0, len(aggregators))
+	for _, aggregator := range aggregators {
+		ids = append(ids, aggregator.Config.ID)
+	}
+	return ids
+}
+
+// outputIDs returns the IDs of the outputs of a new config, the dead letter
+// links of an output are part of its ID.
+func outputIDs(outputs []*models.RunningOutput, targets []int) []string {
+	deadLetterOnly := make([]bool, len(outputs))
+	for _, j := range targets {
+		if j >= 0 {
+			deadLetterOnly[j] = true
+		}
+	}
+
+	ids := make([]string, 0, len(outputs))
+	for i, output := range outputs {
+		var target string
+		if targets[i] >= 0 {
+			target = outputs[targets[i]].Config.ID
+		}
+		ids = append(ids, outputID(output.Config.ID, target, deadLetterOnly[i]))
+	}
+	return ids
+}
+
+// runningOutputIDs returns the IDs of linked outputs.
+func runningOutputIDs(outputs []*models.RunningOutput) []string {
+	ids := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		var target string
+		if ro, ok := output.DeadLetter.(*models.RunningOutput); ok {
+			target = ro.Config.ID
+		}
+		ids = append(ids, outputID(output.Config.ID, target, output.DeadLetterOnly))
+	}
+	return ids
+}
+
+func outputID(id, deadLetterTarget string, deadLetterOnly bool) string {
+	return fmt.Sprintf("%s/%s/%t", id, deadLetterTarget, deadLetterOnly)
+}
@@ -0,0 +1,441 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/processors/starlark"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type reloadInput struct {
+	mu      sync.Mutex
+	started int
+	stopped int
+}
+
+func (i *reloadInput) SampleConfig() string                  { return "" }
+func (i *reloadInput) Description() string                   { return "" }
+func (i *reloadInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func (i *reloadInput) Start(acc telegraf.Accumulator) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.started++
+	return nil
+}
+
+func (i *reloadInput) Stop() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stopped++
+}
+
+func (i *reloadInput) counts() (int, int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.started, i.stopped
+}
+
+type reloadOutput struct {
+	mu        sync.Mutex
+	connected int
+	closed    int
+}
+
+func (o *reloadOutput) SampleConfig() string                  { return "" }
+func (o *reloadOutput) Description() string                   { return "" }
+func (o *reloadOutput) Write(metrics []telegraf.Metric) error { return nil }
+
+func (o *reloadOutput) Connect() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.connected++
+	return nil
+}
+
+func (o *reloadOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed++
+	return nil
+}
+
+func (o *reloadOutput) counts() (int, int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.connected, o.closed
+}
+
+func newReloadInput(id string) (*reloadInput, *models.RunningInput) {
+	input := &reloadInput{}
+	return input, models.NewRunningInput(input, &models.InputConfig{Name: "reload", ID: id})
+}
+
+func newReloadOutput(id string) (*reloadOutput, *models.RunningOutput) {
+	output := &reloadOutput{}
+	return output, models.NewRunningOutput("reload", output,
+		&models.OutputConfig{Name: "reload", ID: id}, 0, 0)
+}
+
+func newReloadConfig() *config.Config {
+	c := config.NewConfig()
+	c.Agent.Interval.Duration = time.Hour
+	c.Agent.FlushInterval.Duration = time.Hour
+	return c
+}
+
+func TestMatchIDs(t *testing.T) {
+	matches, removed := matchIDs([]string{"a", "b", "a", "c"}, []string{"a", "d", "a", "a", "c"})
+	require.Equal(t, []int{0, -1, 2, -1, 3}, matches)
+	require.Equal(t, []int{1}, removed)
+
+	matches, removed = matchIDs(nil, []string{"a"})
+	require.Equal(t, []int{-1}, matches)
+	require.Empty(t, removed)
+}
+
+func TestSameIDs(t *testing.T) {
+	require.True(t, sameIDs([]string{"a", "b", "a"}, []string{"a", "a", "b"}))
+	require.True(t, sameIDs(nil, nil))
+	require.False(t, sameIDs([]string{"a", "b"}, []string{"a", "a"}))
+	require.False(t, sameIDs([]string{"a"}, []string{"a", "b"}))
+	require.False(t, sameIDs([]string{"a", "b"}, []string{"a"}))
+}
+
+func TestOutputIDs(t *testing.T) {
+	_, primary := newReloadOutput("primary")
+	_, fallback := newReloadOutput("fallback")
+	outputs := []*models.RunningOutput{primary, fallback}
+
+	ids := outputIDs(outputs, []int{1, -1})
+	require.Equal(t, []string{"primary/fallback/false", "fallback//true"}, ids)
+
+	linkDeadLetterOutput(primary, fallback)
+	require.Equal(t, ids, runningOutputIDs(outputs))
+}
+
+func TestApplyConfig(t *testing.T) {
+	c := newReloadConfig()
+	keep, keepInput := newReloadInput("keep")
+	remove, removeInput := newReloadInput("remove")
+	output, runningOutput := newReloadOutput("output")
+	c.Inputs = []*models.RunningInput{keepInput, removeInput}
+	c.Outputs = []*models.RunningOutput{runningOutput}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		started, _ := remove.counts()
+		return started == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	next := newReloadConfig()
+	next.Tags = c.Tags
+	keepNext, keepNextInput := newReloadInput("keep")
+	add, addInput := newReloadInput("add")
+	outputNext, runningOutputNext := newReloadOutput("output")
+	next.Inputs = []*models.RunningInput{keepNextInput, addInput}
+	next.Outputs = []*models.RunningOutput{runningOutputNext}
+
+	err = a.ApplyConfig(ctx, next)
+	require.NoError(t, err)
+
+	started, stopped := keep.counts()
+	require.Equal(t, 1, started)
+	require.Equal(t, 0, stopped)
+	started, stopped = remove.counts()
+	require.Equal(t, 1, started)
+	require.Equal(t, 1, stopped)
+	started, _ = add.counts()
+	require.Equal(t, 1, started)
+	started, _ = keepNext.counts()
+	require.Equal(t, 0, started)
+
+	connected, closed := output.counts()
+	require.Equal(t, 1, connected)
+	require.Equal(t, 0, closed)
+	connected, _ = outputNext.counts()
+	require.Equal(t, 0, connected)
+
+	require.Equal(t, []*models.RunningInput{keepInput, addInput}, a.Config.Inputs)
+	require.Equal(t, []*models.RunningOutput{runningOutput}, a.Config.Outputs)
+
+	restart := newReloadConfig()
+	restart.Tags = map[string]string{"dc": "us-east-1"}
+	err = a.ApplyConfig(ctx, restart)
+	require.Equal(t, ErrRestartRequired, err)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, stopped = keep.counts()
+	require.Equal(t, 1, stopped)
+	_, stopped = add.counts()
+	require.Equal(t, 1, stopped)
+	_, closed = output.counts()
+	require.Equal(t, 1, closed)
+}
+
+type bufferOutput struct {
+	reloadOutput
+	written []telegraf.Metric
+}
+
+func (o *bufferOutput) Write(metrics []telegraf.Metric) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.written = append(o.written, metrics...)
+	return nil
+}
+
+func (o *bufferOutput) metrics() []telegraf.Metric {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.written
+}
+
+func newBufferOutput(id, dir string) (*bufferOutput, *models.RunningOutput) {
+	output := &bufferOutput{}
+	return output, models.NewRunningOutput("buffer", output, &models.OutputConfig{
+		Name:            "buffer",
+		ID:              id,
+		BufferStrategy:  models.BufferStrategyDisk,
+		BufferDirectory: dir,
+	}, 0, 0)
+}
+
+func TestApplyConfigTakesOverDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+	}
+	buffer, err := models.NewDiskBuffer("buffer", "", dir, 1024*1024, testutil.Logger{})
+	require.NoError(t, err)
+	buffer.Add(expected...)
+	require.NoError(t, buffer.Close())
+
+	c := newReloadConfig()
+	output, runningOutput := newBufferOutput("v1", dir)
+	c.Outputs = []*models.RunningOutput{runningOutput}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		connected, _ := output.counts()
+		return connected == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	next := newReloadConfig()
+	next.Tags = c.Tags
+	outputNext, runningOutputNext := newBufferOutput("v2", dir)
+	next.Outputs = []*models.RunningOutput{runningOutputNext}
+
+	err = a.ApplyConfig(ctx, next)
+	require.NoError(t, err)
+
+	// The replaced output is flushed before its buffer is handed over, the
+	// new output sees the metrics it wrote as removed.
+	_, closed := output.counts()
+	require.Equal(t, 1, closed)
+	testutil.RequireMetricsEqual(t, expected, output.metrics())
+	require.Equal(t, 0, runningOutputNext.BufferLength())
+
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Empty(t, outputNext.metrics())
+	buffer, err = models.NewDiskBuffer("buffer", "", dir, 1024*1024, testutil.Logger{})
+	require.NoError(t, err)
+	require.Equal(t, 0, buffer.Len())
+	require.NoError(t, buffer.Close())
+}
+
+// stateInput adds the metrics passed to add once started.
+type stateInput struct {
+	mu  sync.Mutex
+	acc telegraf.Accumulator
+}
+
+func (i *stateInput) SampleConfig() string                  { return "" }
+func (i *stateInput) Description() string                   { return "" }
+func (i *stateInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *stateInput) Stop()                                 {}
+
+func (i *stateInput) Start(acc telegraf.Accumulator) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.acc = acc
+	return nil
+}
+
+func (i *stateInput) add() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.acc.AddFields("cpu", map[string]interface{}{"value": 1.0}, nil, time.Unix(0, 0))
+}
+
+func newStateProcessor(id, stateFile string) *models.RunningProcessor {
+	processor := &starlark.Starlark{}
+	processor.Source = `
+def apply(metric):
+    state["count"] = state.get("count", 0) + 1
+    metric.fields["count"] = state["count"]
+    return metric
+`
+	processor.StateFile = stateFile
+	return models.NewRunningProcessor(processor, &models.ProcessorConfig{Name: "starlark", ID: id})
+}
+
+func TestApplyConfigRestoresProcessorState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "starlark.state")
+
+	c := newReloadConfig()
+	input := &stateInput{}
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(input, &models.InputConfig{Name: "state", ID: "input"}),
+	}
+	c.Processors = models.RunningProcessors{newStateProcessor("v1", stateFile)}
+	output := &bufferOutput{}
+	runningOutput := models.NewRunningOutput("buffer", output,
+		&models.OutputConfig{Name: "buffer", ID: "output"}, 0, 0)
+	c.Outputs = []*models.RunningOutput{runningOutput}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		input.mu.Lock()
+		defer input.mu.Unlock()
+		return input.acc != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	input.add()
+	input.add()
+	require.Eventually(t, func() bool {
+		return runningOutput.BufferLength() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The new processor continues counting from the state of the running
+	// processor.
+	next := newReloadConfig()
+	next.Tags = c.Tags
+	next.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&stateInput{}, &models.InputConfig{Name: "state", ID: "input"}),
+	}
+	next.Processors = models.RunningProcessors{newStateProcessor("v2", stateFile)}
+	next.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput("buffer", &bufferOutput{},
+			&models.OutputConfig{Name: "buffer", ID: "output"}, 0, 0),
+	}
+	require.NoError(t, a.ApplyConfig(ctx, next))
+
+	input.add()
+	require.Eventually(t, func() bool {
+		return runningOutput.BufferLength() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	var counts []interface{}
+	for _, m := range output.metrics() {
+		count, _ := m.GetField("count")
+		counts = append(counts, count)
+	}
+	require.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, counts)
+}
+
+type failingProcessor struct{}
+
+func (p *failingProcessor) SampleConfig() string { return "" }
+func (p *failingProcessor) Description() string  { return "" }
+func (p *failingProcessor) Init() error          { return errors.New("init failed") }
+
+func (p *failingProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	return in
+}
+
+func TestApplyConfigClosesNewBuffersOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newReloadConfig()
+	output, runningOutput := newReloadOutput("output")
+	c.Outputs = []*models.RunningOutput{runningOutput}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		connected, _ := output.counts()
+		return connected == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	next := newReloadConfig()
+	next.Tags = c.Tags
+	outputNext, runningOutputNext := newBufferOutput("buffer", dir)
+	next.Outputs = []*models.RunningOutput{runningOutput, runningOutputNext}
+	next.Processors = models.RunningProcessors{
+		models.NewRunningProcessor(processors.NewStreamingProcessorFromProcessor(&failingProcessor{}),
+			&models.ProcessorConfig{Name: "failing"}),
+	}
+
+	err = a.ApplyConfig(ctx, next)
+	require.Error(t, err)
+
+	// The disk buffer of the new output is closed, which persists its head,
+	// but the output itself was never connected.
+	require.FileExists(t, filepath.Join(dir, "head"))
+	connected, closed := outputNext.counts()
+	require.Equal(t, 0, connected)
+	require.Equal(t, 0, closed)
+	require.Equal(t, []*models.RunningOutput{runningOutput}, a.Config.Outputs)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/config"
+)
+
+// runConfigCheck loads the config files and initializes all plugins without
+// starting them.  All errors and deprecation warnings found are printed, it
+// returns false if there were errors.
+func runConfigCheck(inputFilters []string, outputFilters []string) bool {
+	c := config.NewConfig()
+	c.CheckMode = true
+	c.InputFilters = inputFilters
+	c.OutputFilters = outputFilters
+
+	if err := c.LoadConfig(*fConfig); err != nil {
+		c.Errors = append(c.Errors, err)
+	}
+	if *fConfigDirectory != "" {
+		if err := c.LoadDirectory(*fConfigDirectory); err != nil {
+			c.Errors = append(c.Errors, err)
+		}
+	}
+
+	errs := c.Errors
+	if len(errs) == 0 {
+		if err := validateConfig(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, agent.CheckPlugins(c)...)
+
+	for _, warning := range c.Warnings {
+		fmt.Fprintf(os.Stderr, "W! %s\n", warning)
+	}
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "E! %s\n", err)
+	}
+
+	fmt.Printf("Checked %d inputs, %d processors, %d aggregators and %d outputs: %d errors, %d warnings\n",
+		len(c.Inputs), len(c.Processors), len(c.Aggregators), len(c.Outputs),
+		len(errs), len(c.Warnings))
+	return len(errs) == 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+)
+
+const secretsUsage = `usage:
+  telegraf secrets list <id>
+  telegraf secrets get <id> <key>
+  telegraf secrets set <id> <key> [<value>]
+
+The value of "set" is read from stdin if not given.`
+
+// runSecrets runs the secrets command managing the secrets of the secret
+// stores in the config.
+func runSecrets(args []string) error {
+	if len(args) < 2 {
+		return errors.New(secretsUsage)
+	}
+
+	c := config.NewConfig()
+	c.SecretStoresOnly = true
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return err
+	}
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return err
+		}
+	}
+
+	store, ok := c.SecretStores[args[1]]
+	if !ok {
+		ids := make([]string, 0, len(c.SecretStores))
+		for id := range c.SecretStores {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return fmt.Errorf("no secretstore with id %q, configured are: %s",
+			args[1], strings.Join(ids, " "))
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 2:
+		return listSecrets(store)
+	case args[0] == "get" && len(args) == 3:
+		secret, err := store.Get(args[2])
+		if err != nil {
+			return err
+		}
+		fmt.Println(secret)
+		return nil
+	case args[0] == "set" && (len(args) == 3 || len(args) == 4):
+		var value string
+		if len(args) == 4 {
+			value = args[3]
+		} else {
+			data, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+		return store.Set(args[2], value)
+	default:
+		return errors.New(secretsUsage)
+	}
+}
+
+func listSecrets(store telegraf.SecretStore) error {
+	keys, err := store.List()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 )
 
 // If you update these, update usage.go and usage_windows.go
@@ -93,48 +94,97 @@ func reloadLoop(
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
-		go func() {
+
+		// Reloads requested with the management API are handled like SIGHUP.
+		reloadRequested := make(chan struct{}, 1)
+		requestReload := func() {
 			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config")
-					<-reload
-					reload <- true
+			case reloadRequested <- struct{}{}:
+			default:
+			}
+		}
+
+		ag, err := loadAgent(inputFilters, outputFilters)
+		if err != nil {
+			log.Fatalf("E! [telegraf] Error running agent: %v", err)
+		}
+		ag.Reload = requestReload
+
+		// A reload only restarts the plugins with changed settings, the agent
+		// is restarted if that is not possible.
+		restart := func() {
+			<-reload
+			reload <- true
+			cancel()
+		}
+		applyConfig := func() {
+			log.Printf("I! Reloading Telegraf config")
+			if *fRunOnce || *fTest || *fTestWait != 0 {
+				restart()
+				return
+			}
+			err := reloadConfig(ctx, ag, inputFilters, outputFilters)
+			switch {
+			case err == nil:
+			case errors.Is(err, agent.ErrRestartRequired):
+				log.Printf("I! Restarting Telegraf: %v", err)
+				restart()
+			default:
+				log.Printf("E! Error reloading config, keeping the running config: %v", err)
+			}
+		}
+
+		go func() {
+			for {
+				select {
+				case sig := <-signals:
+					if sig != syscall.SIGHUP {
+						cancel()
+						return
+					}
+					applyConfig()
+				case <-reloadRequested:
+					applyConfig()
+				case <-stop:
+					cancel()
+					return
+				case <-ctx.Done():
+					return
 				}
-				cancel()
-			case <-stop:
-				cancel()
 			}
 		}()
 
-		err := runAgent(ctx, inputFilters, outputFilters)
+		err = runAgent(ctx, ag)
 		if err != nil && err != context.Canceled {
 			log.Fatalf("E! [telegraf] Error running agent: %v", err)
 		}
 	}
 }
 
-func runAgent(ctx context.Context,
-	inputFilters []string,
-	outputFilters []string,
-) error {
-	log.Printf("I! Starting Telegraf %s", version)
-
-	// If no other options are specified, load the config file and run.
+// loadConfig loads and validates the config files.
+func loadConfig(inputFilters []string, outputFilters []string) (*config.Config, error) {
 	c := config.NewConfig()
 	c.OutputFilters = outputFilters
 	c.InputFilters = inputFilters
 	err := c.LoadConfig(*fConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if *fConfigDirectory != "" {
 		err = c.LoadDirectory(*fConfigDirectory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	if err := validateConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validateConfig checks the loaded config is runnable.
+func validateConfig(c *config.Config) error {
 	if !*fTest && len(c.Outputs) == 0 {
 		return errors.New("Error: no outputs found, did you provide a valid config file?")
 	}
@@ -151,10 +201,21 @@ func runAgent(ctx context.Context,
 		return fmt.Errorf("Agent flush_interval must be positive; found %s",
 			c.Agent.Interval.Duration)
 	}
+	return nil
+}
+
+func loadAgent(inputFilters []string, outputFilters []string) (*agent.Agent, error) {
+	log.Printf("I! Starting Telegraf %s", version)
+
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return nil, err
+	}
 
 	ag, err := agent.NewAgent(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Setup logging as configured.
@@ -162,6 +223,7 @@ func runAgent(ctx context.Context,
 		Debug:               ag.Config.Agent.Debug || *fDebug,
 		Quiet:               ag.Config.Agent.Quiet || *fQuiet,
 		LogTarget:           ag.Config.Agent.LogTarget,
+		LogFormat:           ag.Config.Agent.LogFormat,
 		Logfile:             ag.Config.Agent.Logfile,
 		RotationInterval:    ag.Config.Agent.LogfileRotationInterval,
 		RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize,
@@ -169,6 +231,23 @@ func runAgent(ctx context.Context,
 	}
 
 	logger.SetupLogging(logConfig)
+	return ag, nil
+}
+
+// reloadConfig applies the config files to the running agent.
+func reloadConfig(ctx context.Context, ag *agent.Agent,
+	inputFilters []string,
+	outputFilters []string,
+) error {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
+	return ag.ApplyConfig(ctx, c)
+}
+
+func runAgent(ctx context.Context, ag *agent.Agent) error {
+	c := ag.Config
 
 	if *fRunOnce {
 		wait := time.Duration(*fTestWait) * time.Second
@@ -291,7 +370,18 @@ func main() {
 		case "version":
 			fmt.Println(formatFullVersion())
 			return
+		case "secrets":
+			if err := runSecrets(args[1:]); err != nil {
+				log.Fatalf("E! %s", err)
+			}
+			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				if !runConfigCheck(inputFilters, outputFilters) {
+					os.Exit(1)
+				}
+				return
+			}
 			config.PrintSampleConfig(
 				sectionFilters,
 				inputFilters,
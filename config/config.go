@@ -2,7 +2,10 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -24,10 +27,14 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/toml"
 	"github.com/influxdata/toml/ast"
+	"github.com/influxdata/wlog"
 )
 
 var (
@@ -45,6 +52,13 @@ var (
 	// envVarRe is a regex to find environment variables in the config file
 	envVarRe = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
 
+	// secretRefRe is a regex to find references to secrets of secret stores
+	// in the values of the config file
+	secretRefRe = regexp.MustCompile(`@\{(\w+):([^{}]+)\}`)
+
+	// secretStoreIDRe is a regex matching valid secret store ids
+	secretStoreIDRe = regexp.MustCompile(`^\w+$`)
+
 	envVarEscaper = strings.NewReplacer(
 		`"`, `\"`,
 		`\`, `\\`,
@@ -70,6 +84,24 @@ type Config struct {
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors    models.RunningProcessors
 	AggProcessors models.RunningProcessors
+
+	// SecretStores are the secret stores by id, secrets referenced in the
+	// config are resolved when the config is loaded.
+	SecretStores map[string]telegraf.SecretStore
+
+	// SecretStoresOnly only loads the secret stores of config files.
+	SecretStoresOnly bool
+
+	// CheckMode continues loading the config after errors in a plugin or
+	// file, the errors are collected in Errors.
+	CheckMode bool
+	// Errors are the errors found in check mode.
+	Errors []error
+	// Warnings are the deprecated options found in the config.
+	Warnings []string
+
+	file    string                 // config file being loaded
+	sources map[interface{}]string // running plugin to config file and line
 }
 
 // NewConfig creates a new struct to hold the Telegraf config.
@@ -78,6 +110,7 @@ type Config struct {
 func NewConfig() *Config {
 	c := &Config{
 		UnusedFields: map[string]bool{},
+		sources:      map[interface{}]string{},
 
 		// Agent defaults:
 		Agent: &AgentConfig{
@@ -93,6 +126,7 @@ func NewConfig() *Config {
 		Outputs:       make([]*models.RunningOutput, 0),
 		Processors:    make([]*models.RunningProcessor, 0),
 		AggProcessors: make([]*models.RunningProcessor, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
 	}
@@ -153,12 +187,12 @@ type AgentConfig struct {
 	// FlushBufferWhenFull tells Telegraf to flush the metric buffer whenever
 	// it fills up, regardless of FlushInterval. Setting this option to true
 	// does _not_ deactivate FlushInterval.
-	FlushBufferWhenFull bool // deprecated in 0.13; has no effect
+	FlushBufferWhenFull bool `deprecated:"0.13.0;option has no effect"`
 
 	// TODO(cam): Remove UTC and parameter, they are no longer
 	// valid for the agent config. Leaving them here for now for backwards-
 	// compatibility
-	UTC bool `toml:"utc"` // deprecated in 1.0.0; has no effect
+	UTC bool `toml:"utc" deprecated:"1.0.0;option has no effect"`
 
 	// Debug is the option for running in debug mode
 	Debug bool `toml:"debug"`
@@ -171,6 +205,10 @@ type AgentConfig struct {
 	// is determined by the "logfile" setting.
 	LogTarget string `toml:"logtarget"`
 
+	// Log format controls the format of the lines written to stderr or the
+	// log file and can be "text" or "json".
+	LogFormat string `toml:"logformat"`
+
 	// Name of the file to be logged to when using the "file" logtarget.  If set to
 	// the empty string then logs are written to stderr.
 	Logfile string `toml:"logfile"`
@@ -189,6 +227,10 @@ type AgentConfig struct {
 
 	Hostname     string
 	OmitHostname bool
+
+	// APIAddress is the address the management API listens on.  The API is
+	// disabled when empty.
+	APIAddress string `toml:"api_address"`
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -338,6 +380,11 @@ var agentConfig = `
   ## is determined by the "logfile" setting.
   # logtarget = "file"
 
+  ## Log format controls the format of the log lines written to stderr or the
+  ## log file and can be "text" or "json".  JSON lines hold the time, level,
+  ## plugin type, name and alias, message and error of a log message.
+  # logformat = "text"
+
   ## Name of the file to be logged to when using the "file" logtarget.  If set to
   ## the empty string then logs are written to stderr.
   # logfile = ""
@@ -360,6 +407,12 @@ var agentConfig = `
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the local HTTP management API, disabled if empty.  The API
+  ## lists the running plugins and allows reloading the config or flushing
+  ## the outputs, it has no authentication and should only be bound to a
+  ## local address.
+  # api_address = "localhost:8099"
+
 `
 
 var outputHeader = `
@@ -677,6 +730,10 @@ func (c *Config) LoadDirectory(path string) error {
 		}
 		err := c.LoadConfig(thispath)
 		if err != nil {
+			if c.CheckMode {
+				c.Errors = append(c.Errors, err)
+				return nil
+			}
 			return err
 		}
 		return nil
@@ -725,7 +782,15 @@ func (c *Config) LoadConfig(path string) error {
 		return fmt.Errorf("Error loading config file %s: %w", path, err)
 	}
 
-	if err = c.LoadConfigData(data); err != nil {
+	c.file = path
+	defer func() { c.file = "" }()
+
+	n := len(c.Errors)
+	err = c.LoadConfigData(data)
+	for i := n; i < len(c.Errors); i++ {
+		c.Errors[i] = fmt.Errorf("Error loading config file %s: %w", path, c.Errors[i])
+	}
+	if err != nil {
 		return fmt.Errorf("Error loading config file %s: %w", path, err)
 	}
 	return nil
@@ -738,7 +803,32 @@ func (c *Config) LoadConfigData(data []byte) error {
 		return fmt.Errorf("Error parsing data: %s", err)
 	}
 
-	// Parse tags tables first:
+	// Parse secret stores first, the other tables can reference their
+	// secrets:
+	if val, ok := tbl.Fields["secretstores"]; ok {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("invalid configuration, error parsing secretstores table")
+		}
+		if err = c.loadSecretStores(subTable); err != nil {
+			return err
+		}
+	}
+	if c.SecretStoresOnly {
+		return nil
+	}
+	for name, val := range tbl.Fields {
+		if name == "secretstores" {
+			continue
+		}
+		if subTable, ok := val.(*ast.Table); ok {
+			if err = c.resolveSecrets(subTable); err != nil {
+				return fmt.Errorf("error resolving secrets of %s: %w", name, err)
+			}
+		}
+	}
+
+	// Parse tags tables:
 	for _, tableName := range []string{"tags", "global_tags"} {
 		if val, ok := tbl.Fields[tableName]; ok {
 			subTable, ok := val.(*ast.Table)
@@ -760,6 +850,7 @@ func (c *Config) LoadConfigData(data []byte) error {
 		if err = c.toml.UnmarshalTable(subTable, c.Agent); err != nil {
 			return fmt.Errorf("error parsing [agent]: %w", err)
 		}
+		c.checkDeprecations("agent", subTable, c.Agent)
 	}
 
 	if !c.Agent.OmitHostname {
@@ -787,27 +878,34 @@ func (c *Config) LoadConfigData(data []byte) error {
 		}
 
 		switch name {
-		case "agent", "global_tags", "tags":
+		case "agent", "global_tags", "tags", "secretstores":
 		case "outputs":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
 				// legacy [outputs.influxdb] support
 				case *ast.Table:
 					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("error parsing %s, %w", pluginName, err)
+						if err = c.pluginError(pluginName, fmt.Errorf("error parsing %s, %w", pluginName, err)); err != nil {
+							return err
+						}
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addOutput(pluginName, t); err != nil {
-							return fmt.Errorf("error parsing %s array, %w", pluginName, err)
+							if err = c.pluginError(pluginName, fmt.Errorf("error parsing %s array, %w", pluginName, err)); err != nil {
+								return err
+							}
+						}
+						if err = c.checkUnusedFields(name, pluginName, t.Line); err != nil {
+							return err
 						}
 					}
 				default:
 					return fmt.Errorf("unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
+				if err = c.checkUnusedFields(name, pluginName, subTable.Line); err != nil {
+					return err
 				}
 			}
 		case "inputs", "plugins":
@@ -816,20 +914,27 @@ func (c *Config) LoadConfigData(data []byte) error {
 				// legacy [inputs.cpu] support
 				case *ast.Table:
 					if err = c.addInput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("error parsing %s, %w", pluginName, err)
+						if err = c.pluginError(pluginName, fmt.Errorf("error parsing %s, %w", pluginName, err)); err != nil {
+							return err
+						}
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addInput(pluginName, t); err != nil {
-							return fmt.Errorf("error parsing %s, %w", pluginName, err)
+							if err = c.pluginError(pluginName, fmt.Errorf("error parsing %s, %w", pluginName, err)); err != nil {
+								return err
+							}
+						}
+						if err = c.checkUnusedFields(name, pluginName, t.Line); err != nil {
+							return err
 						}
 					}
 				default:
 					return fmt.Errorf("Unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
+				if err = c.checkUnusedFields(name, pluginName, subTable.Line); err != nil {
+					return err
 				}
 			}
 		case "processors":
@@ -838,15 +943,20 @@ func (c *Config) LoadConfigData(data []byte) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addProcessor(pluginName, t); err != nil {
-							return fmt.Errorf("error parsing %s, %w", pluginName, err)
+							if err = c.pluginError(pluginName, fmt.Errorf("error parsing %s, %w", pluginName, err)); err != nil {
+								return err
+							}
+						}
+						if err = c.checkUnusedFields(name, pluginName, t.Line); err != nil {
+							return err
 						}
 					}
 				default:
 					return fmt.Errorf("Unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
+				if err = c.checkUnusedFields(name, pluginName, subTable.Line); err != nil {
+					return err
 				}
 			}
 		case "aggregators":
@@ -855,22 +965,29 @@ func (c *Config) LoadConfigData(data []byte) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addAggregator(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", pluginName, err)
+							if err = c.pluginError(pluginName, fmt.Errorf("Error parsing %s, %s", pluginName, err)); err != nil {
+								return err
+							}
+						}
+						if err = c.checkUnusedFields(name, pluginName, t.Line); err != nil {
+							return err
 						}
 					}
 				default:
 					return fmt.Errorf("Unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
+				if err = c.checkUnusedFields(name, pluginName, subTable.Line); err != nil {
+					return err
 				}
 			}
 		// Assume it's an input input for legacy config file support if no other
 		// identifiers are present
 		default:
 			if err = c.addInput(name, subTable); err != nil {
-				return fmt.Errorf("Error parsing %s, %s", name, err)
+				if err = c.pluginError(name, fmt.Errorf("Error parsing %s, %s", name, err)); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -965,6 +1082,122 @@ func parseConfig(contents []byte) (*ast.Table, error) {
 	return toml.Parse(contents)
 }
 
+func (c *Config) loadSecretStores(tbl *ast.Table) error {
+	for pluginName, pluginVal := range tbl.Fields {
+		switch pluginSubTable := pluginVal.(type) {
+		case []*ast.Table:
+			for _, t := range pluginSubTable {
+				if err := c.addSecretStore(pluginName, t); err != nil {
+					return fmt.Errorf("error parsing %s, %w", pluginName, err)
+				}
+			}
+		default:
+			return fmt.Errorf("Unsupported config format: %s",
+				pluginName)
+		}
+		if len(c.UnusedFields) > 0 {
+			return fmt.Errorf("plugin secretstores.%s: line %d: configuration specified the fields %q, but they weren't used", pluginName, tbl.Line, keys(c.UnusedFields))
+		}
+	}
+	return nil
+}
+
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secretstore: %s", name)
+	}
+	store := creator()
+
+	var id string
+	c.getFieldString(table, "id", &id)
+	if !secretStoreIDRe.MatchString(id) {
+		return fmt.Errorf("line %d: invalid id %q, only letters, digits and underscores are allowed", table.Line, id)
+	}
+	if _, ok := c.SecretStores[id]; ok {
+		return fmt.Errorf("line %d: duplicate secretstore id %q", table.Line, id)
+	}
+	// The id names the store in the config, it is not a setting of the
+	// plugin.
+	delete(table.Fields, "id")
+
+	if err := c.toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+	models.SetLoggerOnPlugin(store, models.NewLogger("secretstores", name, id))
+
+	// The secrets are needed while loading the config, the store is
+	// initialized right away.
+	if s, ok := store.(telegraf.Initializer); ok {
+		if err := s.Init(); err != nil {
+			return fmt.Errorf("could not initialize secretstore %s: %w", id, err)
+		}
+	}
+
+	c.SecretStores[id] = store
+	return nil
+}
+
+// resolveSecrets replaces the secret references in the string values of tbl
+// with the secrets of the secret stores.
+func (c *Config) resolveSecrets(tbl *ast.Table) error {
+	for _, val := range tbl.Fields {
+		switch node := val.(type) {
+		case *ast.KeyValue:
+			if err := c.resolveSecretsOfValue(node.Value); err != nil {
+				return fmt.Errorf("line %d: %w", node.Line, err)
+			}
+		case *ast.Table:
+			if err := c.resolveSecrets(node); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range node {
+				if err := c.resolveSecrets(t); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveSecretsOfValue(value ast.Value) error {
+	switch v := value.(type) {
+	case *ast.String:
+		var err error
+		v.Value = secretRefRe.ReplaceAllStringFunc(v.Value, func(ref string) string {
+			if err != nil {
+				return ref
+			}
+			var secret string
+			match := secretRefRe.FindStringSubmatch(ref)
+			secret, err = c.getSecret(match[1], match[2])
+			return secret
+		})
+		return err
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := c.resolveSecretsOfValue(elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) getSecret(id, key string) (string, error) {
+	store, ok := c.SecretStores[id]
+	if !ok {
+		return "", fmt.Errorf("undefined secretstore %q", id)
+	}
+	secret, err := store.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("getting secret %q of secretstore %q: %w", key, id, err)
+	}
+	return secret, nil
+}
+
 func (c *Config) addAggregator(name string, table *ast.Table) error {
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
@@ -980,8 +1213,11 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, aggregator); err != nil {
 		return err
 	}
+	c.checkDeprecations("aggregators."+name, table, aggregator)
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	ra := models.NewRunningAggregator(aggregator, conf)
+	c.setSource(ra, table)
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -1000,6 +1236,12 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	if p, ok := rf.Processor.(unwrappable); ok {
+		c.checkDeprecations("processors."+name, table, p.Unwrap())
+	} else {
+		c.checkDeprecations("processors."+name, table, rf.Processor)
+	}
+	c.setSource(rf, table)
 	c.Processors = append(c.Processors, rf)
 
 	// save a copy for the aggregator
@@ -1063,9 +1305,11 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, output); err != nil {
 		return err
 	}
+	c.checkDeprecations("outputs."+name, table, output)
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	c.setSource(ro, table)
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -1113,8 +1357,10 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, input); err != nil {
 		return err
 	}
+	c.checkDeprecations("inputs."+name, table, input)
 
 	rp := models.NewRunningInput(input, pluginConfig)
+	c.setSource(rp, table)
 	rp.SetDefaultTags(c.Tags)
 	c.Inputs = append(c.Inputs, rp)
 	return nil
@@ -1126,6 +1372,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, error) {
 	conf := &models.AggregatorConfig{
 		Name:   name,
+		ID:     pluginID("aggregators", name, tbl),
 		Delay:  time.Millisecond * 100,
 		Period: time.Second * 30,
 		Grace:  time.Second * 0,
@@ -1139,6 +1386,7 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 	c.getFieldString(tbl, "name_suffix", &conf.MeasurementSuffix)
 	c.getFieldString(tbl, "name_override", &conf.NameOverride)
 	c.getFieldString(tbl, "alias", &conf.Alias)
+	c.getFieldLogLevel(tbl, "log_level", &conf.LogLevel)
 
 	conf.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1165,10 +1413,14 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 // builds the filter and returns a
 // models.ProcessorConfig to be inserted into models.RunningProcessor
 func (c *Config) buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error) {
-	conf := &models.ProcessorConfig{Name: name}
+	conf := &models.ProcessorConfig{
+		Name: name,
+		ID:   pluginID("processors", name, tbl),
+	}
 
 	c.getFieldInt64(tbl, "order", &conf.Order)
 	c.getFieldString(tbl, "alias", &conf.Alias)
+	c.getFieldLogLevel(tbl, "log_level", &conf.LogLevel)
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
@@ -1183,7 +1435,7 @@ func (c *Config) buildProcessor(name string, tbl *ast.Table) (*models.ProcessorC
 }
 
 // buildFilter builds a Filter
-// (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop) to
+// (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop/metricpass) to
 // be inserted into the models.OutputConfig/models.InputConfig
 // to be used for glob filtering on tags and measurements
 func (c *Config) buildFilter(tbl *ast.Table) (models.Filter, error) {
@@ -1203,6 +1455,7 @@ func (c *Config) buildFilter(tbl *ast.Table) (models.Filter, error) {
 
 	c.getFieldStringSlice(tbl, "tagexclude", &f.TagExclude)
 	c.getFieldStringSlice(tbl, "taginclude", &f.TagInclude)
+	c.getFieldString(tbl, "metricpass", &f.MetricPass)
 
 	if c.hasErrs() {
 		return f, c.firstErr()
@@ -1219,7 +1472,10 @@ func (c *Config) buildFilter(tbl *ast.Table) (models.Filter, error) {
 // builds the filter and returns a
 // models.InputConfig to be inserted into models.RunningInput
 func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
-	cp := &models.InputConfig{Name: name}
+	cp := &models.InputConfig{
+		Name: name,
+		ID:   pluginID("inputs", name, tbl),
+	}
 	c.getFieldDuration(tbl, "interval", &cp.Interval)
 	c.getFieldDuration(tbl, "precision", &cp.Precision)
 	c.getFieldDuration(tbl, "collection_jitter", &cp.CollectionJitter)
@@ -1227,6 +1483,9 @@ func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, e
 	c.getFieldString(tbl, "name_suffix", &cp.MeasurementSuffix)
 	c.getFieldString(tbl, "name_override", &cp.NameOverride)
 	c.getFieldString(tbl, "alias", &cp.Alias)
+	c.getFieldLogLevel(tbl, "log_level", &cp.LogLevel)
+	c.getFieldLimit(tbl, &cp.Limit)
+	c.getFieldSeriesLimit(tbl, &cp.SeriesLimit)
 
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1285,6 +1544,11 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	c.getFieldString(tbl, "json_time_format", &pc.JSONTimeFormat)
 	c.getFieldString(tbl, "json_timezone", &pc.JSONTimezone)
 	c.getFieldBool(tbl, "json_strict", &pc.JSONStrict)
+	if node, ok := tbl.Fields["json_v2"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			pc.JSONV2Config = c.getJSONV2Configs(subtbls)
+		}
+	}
 	c.getFieldString(tbl, "data_type", &pc.DataType)
 	c.getFieldString(tbl, "collectd_auth_file", &pc.CollectdAuthFile)
 	c.getFieldString(tbl, "collectd_security_level", &pc.CollectdSecurityLevel)
@@ -1324,6 +1588,47 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 
 	c.getFieldStringSlice(tbl, "form_urlencoded_tag_keys", &pc.FormUrlencodedTagKeys)
 
+	//for protobuf parser
+	c.getFieldString(tbl, "protobuf_schema_file", &pc.ProtobufSchemaFile)
+	c.getFieldStringSlice(tbl, "protobuf_import_paths", &pc.ProtobufImportPaths)
+	c.getFieldString(tbl, "protobuf_message_type", &pc.ProtobufMessageType)
+	c.getFieldBool(tbl, "protobuf_confluent_wire_format", &pc.ProtobufConfluentWireFormat)
+	c.getFieldString(tbl, "protobuf_schema_directory", &pc.ProtobufSchemaDirectory)
+	c.getFieldString(tbl, "protobuf_name_key", &pc.ProtobufNameKey)
+	c.getFieldStringSlice(tbl, "protobuf_string_fields", &pc.ProtobufStringFields)
+	c.getFieldString(tbl, "protobuf_time_key", &pc.ProtobufTimeKey)
+	c.getFieldString(tbl, "protobuf_time_format", &pc.ProtobufTimeFormat)
+	c.getFieldString(tbl, "protobuf_timezone", &pc.ProtobufTimezone)
+
+	//for avro parser
+	c.getFieldString(tbl, "avro_schema_file", &pc.AvroSchemaFile)
+	c.getFieldBool(tbl, "avro_confluent_wire_format", &pc.AvroConfluentWireFormat)
+	c.getFieldString(tbl, "avro_schema_directory", &pc.AvroSchemaDirectory)
+	c.getFieldString(tbl, "avro_name_key", &pc.AvroNameKey)
+	c.getFieldStringSlice(tbl, "avro_string_fields", &pc.AvroStringFields)
+	c.getFieldString(tbl, "avro_time_key", &pc.AvroTimeKey)
+	c.getFieldString(tbl, "avro_time_format", &pc.AvroTimeFormat)
+	c.getFieldString(tbl, "avro_timezone", &pc.AvroTimezone)
+
+	c.getFieldString(tbl, "otlp_format", &pc.OTLPFormat)
+
+	//for xml parser
+	if node, ok := tbl.Fields["xml"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			pc.XMLConfig = make([]xml.Config, len(subtbls))
+			for i, subtbl := range subtbls {
+				xc := &pc.XMLConfig[i]
+				c.getFieldString(subtbl, "metric_selection", &xc.Selection)
+				c.getFieldString(subtbl, "metric_name", &xc.MetricQuery)
+				c.getFieldString(subtbl, "timestamp", &xc.Timestamp)
+				c.getFieldString(subtbl, "timestamp_format", &xc.TimestampFmt)
+				c.getFieldStringMap(subtbl, "tags", &xc.Tags)
+				c.getFieldStringMap(subtbl, "fields", &xc.Fields)
+				c.getFieldStringMap(subtbl, "fields_int", &xc.FieldsInt)
+			}
+		}
+	}
+
 	pc.MetricName = name
 
 	if c.hasErrs() {
@@ -1333,6 +1638,45 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	return pc, nil
 }
 
+// getJSONV2Configs returns the json_v2 object selections of the tables,
+// including their nested selections.
+func (c *Config) getJSONV2Configs(tbls []*ast.Table) []json_v2.Config {
+	configs := make([]json_v2.Config, len(tbls))
+	for i, tbl := range tbls {
+		jc := &configs[i]
+		c.getFieldString(tbl, "path", &jc.Path)
+		c.getFieldString(tbl, "measurement_name", &jc.MeasurementName)
+		c.getFieldString(tbl, "measurement_name_path", &jc.MeasurementNamePath)
+		c.getFieldString(tbl, "timestamp_path", &jc.TimestampPath)
+		c.getFieldString(tbl, "timestamp_format", &jc.TimestampFormat)
+		c.getFieldString(tbl, "timestamp_timezone", &jc.TimestampTimezone)
+		jc.Tags = c.getJSONV2DataSets(tbl, "tag")
+		jc.Fields = c.getJSONV2DataSets(tbl, "field")
+		if node, ok := tbl.Fields["object"]; ok {
+			if subtbls, ok := node.([]*ast.Table); ok {
+				jc.Objects = c.getJSONV2Configs(subtbls)
+			}
+		}
+	}
+	return configs
+}
+
+func (c *Config) getJSONV2DataSets(tbl *ast.Table, fieldName string) []json_v2.DataSet {
+	var dataSets []json_v2.DataSet
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			for _, subtbl := range subtbls {
+				var ds json_v2.DataSet
+				c.getFieldString(subtbl, "path", &ds.Path)
+				c.getFieldString(subtbl, "rename", &ds.Rename)
+				c.getFieldString(subtbl, "type", &ds.Type)
+				dataSets = append(dataSets, ds)
+			}
+		}
+	}
+	return dataSets
+}
+
 // buildSerializer grabs the necessary entries from the ast.Table for creating
 // a serializers.Serializer object, and creates it, which can then be added onto
 // an Output object.
@@ -1357,6 +1701,7 @@ func (c *Config) buildSerializer(name string, tbl *ast.Table) (serializers.Seria
 	c.getFieldString(tbl, "graphite_separator", &sc.GraphiteSeparator)
 
 	c.getFieldDuration(tbl, "json_timestamp_units", &sc.TimestampUnits)
+	c.getFieldString(tbl, "json_template", &sc.JSONTemplate)
 
 	c.getFieldBool(tbl, "splunkmetric_hec_routing", &sc.HecRouting)
 	c.getFieldBool(tbl, "splunkmetric_multimetric", &sc.SplunkmetricMultiMetric)
@@ -1368,6 +1713,9 @@ func (c *Config) buildSerializer(name string, tbl *ast.Table) (serializers.Seria
 	c.getFieldBool(tbl, "prometheus_sort_metrics", &sc.PrometheusSortMetrics)
 	c.getFieldBool(tbl, "prometheus_string_as_label", &sc.PrometheusStringAsLabel)
 
+	c.getFieldString(tbl, "otlp_format", &sc.OTLPFormat)
+	c.getFieldStringSlice(tbl, "otlp_resource_tags", &sc.OTLPResourceTags)
+
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
@@ -1386,6 +1734,7 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 	}
 	oc := &models.OutputConfig{
 		Name:   name,
+		ID:     pluginID("outputs", name, tbl),
 		Filter: filter,
 	}
 
@@ -1396,39 +1745,79 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 
 	c.getFieldInt(tbl, "metric_buffer_limit", &oc.MetricBufferLimit)
 	c.getFieldInt(tbl, "metric_batch_size", &oc.MetricBatchSize)
+	c.getFieldString(tbl, "buffer_strategy", &oc.BufferStrategy)
+	c.getFieldString(tbl, "buffer_directory", &oc.BufferDirectory)
+	c.getFieldSize(tbl, "buffer_size_limit", &oc.BufferSizeLimit)
+	c.getFieldDuration(tbl, "retry_initial_backoff", &oc.RetryInitialBackoff)
+	c.getFieldDuration(tbl, "retry_max_backoff", &oc.RetryMaxBackoff)
+	c.getFieldDuration(tbl, "retry_jitter", &oc.RetryJitter)
+	c.getFieldInt(tbl, "retry_max_attempts", &oc.RetryMaxAttempts)
+	c.getFieldString(tbl, "dead_letter_output", &oc.DeadLetterOutput)
+	c.getFieldString(tbl, "dead_letter_file", &oc.DeadLetterFile)
 	c.getFieldString(tbl, "alias", &oc.Alias)
+	c.getFieldLogLevel(tbl, "log_level", &oc.LogLevel)
 	c.getFieldString(tbl, "name_override", &oc.NameOverride)
 	c.getFieldString(tbl, "name_suffix", &oc.NameSuffix)
 	c.getFieldString(tbl, "name_prefix", &oc.NamePrefix)
+	c.getFieldLimit(tbl, &oc.Limit)
+	c.getFieldSeriesLimit(tbl, &oc.SeriesLimit)
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
 
+	switch oc.BufferStrategy {
+	case "", models.BufferStrategyMemory:
+	case models.BufferStrategyDisk:
+		if oc.BufferDirectory == "" {
+			return nil, fmt.Errorf("buffer_directory is required when buffer_strategy is %q", oc.BufferStrategy)
+		}
+	default:
+		return nil, fmt.Errorf("unknown buffer_strategy %q", oc.BufferStrategy)
+	}
+
+	if oc.DeadLetterOutput != "" && oc.DeadLetterFile != "" {
+		return nil, fmt.Errorf("only one of dead_letter_output and dead_letter_file can be set")
+	}
+
 	return oc, nil
 }
 
 func (c *Config) missingTomlField(typ reflect.Type, key string) error {
 	switch key {
 	case "alias", "carbon2_format", "collectd_auth_file", "collectd_parse_multivalue",
+		"avro_confluent_wire_format", "avro_name_key", "avro_schema_directory", "avro_schema_file",
+		"avro_string_fields", "avro_time_format", "avro_time_key", "avro_timezone",
+		"buffer_directory", "buffer_size_limit", "buffer_strategy",
 		"collectd_security_level", "collectd_typesdb", "collection_jitter", "csv_column_names",
 		"csv_column_types", "csv_comment", "csv_delimiter", "csv_header_row_count",
 		"csv_measurement_column", "csv_skip_columns", "csv_skip_rows", "csv_tag_columns",
 		"csv_timestamp_column", "csv_timestamp_format", "csv_timezone", "csv_trim_space", "csv_skip_values",
 		"data_format", "data_type", "delay", "drop", "drop_original", "dropwizard_metric_registry_path",
+		"dead_letter_file", "dead_letter_output",
 		"dropwizard_tag_paths", "dropwizard_tags_path", "dropwizard_time_format", "dropwizard_time_path",
 		"fielddrop", "fieldpass", "flush_interval", "flush_jitter", "form_urlencoded_tag_keys",
 		"grace", "graphite_separator", "graphite_tag_support", "grok_custom_pattern_files",
 		"grok_custom_patterns", "grok_named_patterns", "grok_patterns", "grok_timezone",
 		"grok_unique_timestamp", "influx_max_line_bytes", "influx_sort_fields", "influx_uint_support",
 		"interval", "json_name_key", "json_query", "json_strict", "json_string_fields",
+		"json_template",
 		"json_time_format", "json_time_key", "json_timestamp_units", "json_timezone",
+		"json_v2", "log_level", "max_metrics_per_second",
 		"metric_batch_size", "metric_buffer_limit", "name_override", "name_prefix",
+		"metric_burst", "metricpass",
 		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "precision",
+		"otlp_format", "otlp_resource_tags",
 		"prefix", "prometheus_export_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
+		"protobuf_confluent_wire_format", "protobuf_import_paths", "protobuf_message_type",
+		"protobuf_name_key", "protobuf_schema_directory", "protobuf_schema_file",
+		"protobuf_string_fields", "protobuf_time_format", "protobuf_time_key", "protobuf_timezone",
+		"retry_initial_backoff", "retry_jitter", "retry_max_attempts", "retry_max_backoff",
+		"sample_method", "sample_rate",
 		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
+		"series_limit", "series_policy", "series_strip_tags", "series_window",
 		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "templates",
-		"wavefront_source_override", "wavefront_use_strict":
+		"wavefront_source_override", "wavefront_use_strict", "xml":
 
 		// ignore fields that are common to all plugins.
 	default:
@@ -1447,6 +1836,21 @@ func (c *Config) getFieldString(tbl *ast.Table, fieldName string, target *string
 	}
 }
 
+func (c *Config) getFieldLogLevel(tbl *ast.Table, fieldName string, target *wlog.Level) {
+	var name string
+	c.getFieldString(tbl, fieldName, &name)
+	if name == "" {
+		return
+	}
+
+	level, ok := wlog.StringToLevel[strings.ToUpper(name)]
+	if !ok {
+		c.addError(tbl, fmt.Errorf("invalid %s %q, expected debug, info, warn, error or off", fieldName, name))
+		return
+	}
+	*target = level
+}
+
 func (c *Config) getFieldDuration(tbl *ast.Table, fieldName string, target interface{}) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -1518,6 +1922,68 @@ func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string, target *int64)
 	}
 }
 
+func (c *Config) getFieldFloat64(tbl *ast.Table, fieldName string, target *float64) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Float:
+				f, err := v.Float()
+				if err != nil {
+					c.addError(tbl, fmt.Errorf("unexpected float type %q, expecting float", v.Value))
+					return
+				}
+				*target = f
+			case *ast.Integer:
+				i, err := v.Int()
+				if err != nil {
+					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting int", v.Value))
+					return
+				}
+				*target = float64(i)
+			}
+		}
+	}
+}
+
+// getFieldLimit parses the sampling and rate limit settings of inputs and
+// outputs.
+func (c *Config) getFieldLimit(tbl *ast.Table, target *models.Limit) {
+	c.getFieldFloat64(tbl, "max_metrics_per_second", &target.MaxMetricsPerSecond)
+	c.getFieldInt(tbl, "metric_burst", &target.MetricBurst)
+	c.getFieldFloat64(tbl, "sample_rate", &target.SampleRate)
+	c.getFieldString(tbl, "sample_method", &target.SampleMethod)
+
+	if err := target.Validate(); err != nil {
+		c.addError(tbl, err)
+	}
+}
+
+// getFieldSeriesLimit parses the series cardinality settings of inputs and
+// outputs.
+func (c *Config) getFieldSeriesLimit(tbl *ast.Table, target *models.SeriesLimit) {
+	c.getFieldInt(tbl, "series_limit", &target.Limit)
+	c.getFieldDuration(tbl, "series_window", &target.Window)
+	c.getFieldString(tbl, "series_policy", &target.Policy)
+	c.getFieldStringSlice(tbl, "series_strip_tags", &target.StripTags)
+
+	if err := target.Validate(); err != nil {
+		c.addError(tbl, err)
+	}
+}
+
+func (c *Config) getFieldSize(tbl *ast.Table, fieldName string, target *int64) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				c.addError(tbl, fmt.Errorf("error parsing size: %w", err))
+				return
+			}
+			*target = int64(size)
+		}
+	}
+}
+
 func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string, target *[]string) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
@@ -1566,6 +2032,60 @@ func (c *Config) getFieldStringMap(tbl *ast.Table, fieldName string, target *map
 	}
 }
 
+// pluginID returns an identifier of the plugin settings in tbl.  Plugins
+// with the same identifier are configured identically, the order of the
+// settings and formatting of the table do not matter.
+func pluginID(pluginType, name string, tbl *ast.Table) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s.%s\n", pluginType, name)
+	writeTable(h, tbl)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeTable writes the fields of the table sorted by key.
+func writeTable(w io.Writer, tbl *ast.Table) {
+	names := make([]string, 0, len(tbl.Fields))
+	for name := range tbl.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		switch field := tbl.Fields[name].(type) {
+		case *ast.KeyValue:
+			fmt.Fprintf(w, "%q = ", name)
+			writeValue(w, field.Value)
+			fmt.Fprintln(w)
+		case *ast.Table:
+			fmt.Fprintf(w, "[%q]\n", name)
+			writeTable(w, field)
+			fmt.Fprintln(w, "[]")
+		case []*ast.Table:
+			for _, t := range field {
+				fmt.Fprintf(w, "[[%q]]\n", name)
+				writeTable(w, t)
+				fmt.Fprintln(w, "[[]]")
+			}
+		}
+	}
+}
+
+func writeValue(w io.Writer, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.Array:
+		fmt.Fprint(w, "[")
+		for _, elem := range v.Value {
+			writeValue(w, elem)
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprint(w, "]")
+	case *ast.String:
+		fmt.Fprintf(w, "%q", v.Value)
+	default:
+		fmt.Fprint(w, value.Source())
+	}
+}
+
 func keys(m map[string]bool) []string {
 	result := []string{}
 	for k := range m {
@@ -1586,7 +2106,52 @@ func (c *Config) firstErr() error {
 }
 
 func (c *Config) addError(tbl *ast.Table, err error) {
-	c.errs = append(c.errs, fmt.Errorf("line %d:%d: %w", tbl.Line, tbl.Position, err))
+	c.errs = append(c.errs, fmt.Errorf("line %d: %w", tbl.Line, err))
+}
+
+// pluginError returns the error of loading the plugin, in check mode the
+// error and any further errors found in the plugin table are collected and nil
+// is returned to continue with the next plugin.
+func (c *Config) pluginError(name string, err error) error {
+	if !c.CheckMode {
+		return err
+	}
+
+	c.Errors = append(c.Errors, err)
+	if len(c.errs) > 1 {
+		for _, e := range c.errs[1:] {
+			c.Errors = append(c.Errors, fmt.Errorf("error parsing %s, %w", name, e))
+		}
+	}
+	c.errs = nil
+	c.UnusedFields = map[string]bool{}
+	return nil
+}
+
+// checkUnusedFields returns an error if the plugin table has fields unknown to
+// the plugin.
+func (c *Config) checkUnusedFields(name, pluginName string, line int) error {
+	if len(c.UnusedFields) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used",
+		name, pluginName, line, keys(c.UnusedFields))
+	return c.pluginError(pluginName, err)
+}
+
+// setSource records the config file and line of a running plugin.
+func (c *Config) setSource(plugin interface{}, tbl *ast.Table) {
+	source := fmt.Sprintf("line %d", tbl.Line)
+	if c.file != "" {
+		source = c.file + ":" + strconv.Itoa(tbl.Line)
+	}
+	c.sources[plugin] = source
+}
+
+// Source returns the config file and line of a running plugin, such as a
+// *models.RunningInput.
+func (c *Config) Source(plugin interface{}) string {
+	return c.sources[plugin]
 }
 
 // unwrappable lets you retrieve the original telegraf.Processor from the
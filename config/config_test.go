@@ -1,7 +1,10 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -16,6 +19,11 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs/azure_monitor"
 	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/directory"
+	"github.com/influxdata/toml/ast"
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -59,6 +67,7 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.ID = c.Inputs[0].Config.ID
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 }
@@ -98,6 +107,7 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.ID = c.Inputs[0].Config.ID
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 }
@@ -144,6 +154,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.ID = c.Inputs[0].Config.ID
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 
@@ -168,12 +179,14 @@ func TestConfig_LoadDirectory(t *testing.T) {
 
 	assert.Equal(t, ex, c.Inputs[1].Input,
 		"Merged Testdata did not produce a correct exec struct.")
+	eConfig.ID = c.Inputs[1].Config.ID
 	assert.Equal(t, eConfig, c.Inputs[1].Config,
 		"Merged Testdata did not produce correct exec metadata.")
 
 	memcached.Servers = []string{"192.168.1.1"}
 	assert.Equal(t, memcached, c.Inputs[2].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.ID = c.Inputs[2].Config.ID
 	assert.Equal(t, mConfig, c.Inputs[2].Config,
 		"Testdata did not produce correct memcached metadata.")
 
@@ -185,6 +198,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 
 	assert.Equal(t, pstat, c.Inputs[3].Input,
 		"Merged Testdata did not produce a correct procstat struct.")
+	pConfig.ID = c.Inputs[3].Config.ID
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
@@ -278,3 +292,475 @@ func TestConfig_AzureMonitorNamespacePrefix(t *testing.T) {
 	assert.Equal(t, "", azureMonitor.NamespacePrefix)
 	assert.Equal(t, true, ok)
 }
+
+func TestConfig_OutputBufferStrategy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  buffer_strategy = "disk"
+  buffer_directory = '` + dir + `'
+  buffer_size_limit = "10MB"`))
+	require.NoError(t, err)
+	require.Equal(t, models.BufferStrategyDisk, c.Outputs[0].Config.BufferStrategy)
+	require.Equal(t, dir, c.Outputs[0].Config.BufferDirectory)
+	require.Equal(t, int64(10*1000*1000), c.Outputs[0].Config.BufferSizeLimit)
+	require.NoError(t, c.Outputs[0].Init())
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  buffer_strategy = "disk"`))
+	require.Error(t, err)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  buffer_strategy = "tape"`))
+	require.Error(t, err)
+}
+
+func TestConfig_PluginID(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  servers = ["localhost", 'remote']
+  interval = "5s"
+  [inputs.memcached.tags]
+    dc = "east"
+
+[[inputs.memcached]]
+  interval   =   "5s"   # same settings, different layout
+  servers = [
+    "localhost",
+    "remote",
+  ]
+  [inputs.memcached.tags]
+    dc = "east"
+
+[[inputs.memcached]]
+  servers = ["localhost", "remote"]
+  interval = "5s"
+  [inputs.memcached.tags]
+    dc = "west"
+
+[[inputs.memcached]]
+  servers = ["localhost", "remote"]
+  interval = "5s"
+`))
+	require.NoError(t, err)
+	require.Len(t, c.Inputs, 4)
+
+	ids := make([]string, 0, len(c.Inputs))
+	for _, input := range c.Inputs {
+		require.NotEmpty(t, input.Config.ID)
+		ids = append(ids, input.Config.ID)
+	}
+	require.Equal(t, ids[0], ids[1])
+	require.NotEqual(t, ids[0], ids[2])
+	require.NotEqual(t, ids[0], ids[3])
+	require.NotEqual(t, ids[2], ids[3])
+}
+
+func TestConfig_SecretStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "host"), []byte("secret-host\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "dc"), []byte("east"), 0600))
+
+	data := []byte(`
+[[inputs.memcached]]
+  servers = ["@{local:host}:11211", "localhost"]
+  [inputs.memcached.tags]
+    dc = "@{local:dc}"
+
+[[secretstores.directory]]
+  id = "local"
+  path = '` + dir + `'
+`)
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(data))
+	require.Contains(t, c.SecretStores, "local")
+	require.Len(t, c.Inputs, 1)
+	input := c.Inputs[0].Input.(*memcached.Memcached)
+	require.Equal(t, []string{"secret-host:11211", "localhost"}, input.Servers)
+	require.Equal(t, map[string]string{"dc": "east"}, c.Inputs[0].Config.Tags)
+	id := c.Inputs[0].Config.ID
+
+	// Secrets are resolved again when the config is reloaded, plugins with
+	// changed secrets get a new ID.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "dc"), []byte("west"), 0600))
+	c = NewConfig()
+	require.NoError(t, c.LoadConfigData(data))
+	require.Equal(t, map[string]string{"dc": "west"}, c.Inputs[0].Config.Tags)
+	require.NotEqual(t, id, c.Inputs[0].Config.ID)
+
+	// Only the secret stores are loaded, missing secrets are no error.
+	c = NewConfig()
+	c.SecretStoresOnly = true
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  servers = ["@{local:missing}"]
+
+[[secretstores.directory]]
+  id = "local"
+  path = '`+dir+`'
+`)))
+	require.Contains(t, c.SecretStores, "local")
+	require.Empty(t, c.Inputs)
+}
+
+func TestConfig_SecretStoreErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "missing secret",
+			data: `
+[[secretstores.directory]]
+  id = "local"
+  path = '` + dir + `'
+
+[[inputs.memcached]]
+  servers = ["@{local:missing}"]`,
+			err: "getting secret \"missing\" of secretstore \"local\"",
+		},
+		{
+			name: "undefined store",
+			data: `
+[[inputs.memcached]]
+  servers = ["@{other:host}"]`,
+			err: "undefined secretstore \"other\"",
+		},
+		{
+			name: "missing id",
+			data: `
+[[secretstores.directory]]
+  path = '` + dir + `'`,
+			err: "invalid id \"\"",
+		},
+		{
+			name: "duplicate id",
+			data: `
+[[secretstores.directory]]
+  id = "local"
+  path = '` + dir + `'
+
+[[secretstores.directory]]
+  id = "local"
+  path = '` + dir + `'`,
+			err: "duplicate secretstore id \"local\"",
+		},
+		{
+			name: "id outside of secretstores",
+			data: `
+[[inputs.memcached]]
+  id = "local"
+  servers = ["localhost"]`,
+			err: "configuration specified the fields [\"id\"], but they weren't used",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			err := c.LoadConfigData([]byte(tt.data))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestConfig_Limit(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  max_metrics_per_second = 100
+  metric_burst = 500
+  sample_rate = 0.5
+  sample_method = "hash"
+
+[[outputs.http]]
+  max_metrics_per_second = 2.5
+`))
+	require.NoError(t, err)
+	require.Equal(t, models.Limit{
+		MaxMetricsPerSecond: 100,
+		MetricBurst:         500,
+		SampleRate:          0.5,
+		SampleMethod:        models.SampleMethodHash,
+	}, c.Inputs[0].Config.Limit)
+	require.Equal(t, models.Limit{MaxMetricsPerSecond: 2.5}, c.Outputs[0].Config.Limit)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  sample_rate = 2.0
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sample_rate must be between 0 and 1")
+}
+
+func TestConfig_SeriesLimit(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  series_limit = 1000
+  series_window = "24h"
+  series_policy = "strip_tag"
+  series_strip_tags = ["request_id"]
+
+[[outputs.http]]
+  series_limit = 10
+`))
+	require.NoError(t, err)
+	require.Equal(t, models.SeriesLimit{
+		Limit:     1000,
+		Window:    24 * time.Hour,
+		Policy:    models.SeriesPolicyStripTag,
+		StripTags: []string{"request_id"},
+	}, c.Inputs[0].Config.SeriesLimit)
+	require.Equal(t, models.SeriesLimit{Limit: 10}, c.Outputs[0].Config.SeriesLimit)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  series_limit = 10
+  series_policy = "strip_tag"
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "series_strip_tags is required")
+}
+
+func TestConfig_MetricPass(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  metricpass = 'tags.state == "active" && fields.count > 0'
+`))
+	require.NoError(t, err)
+	require.Equal(t, `tags.state == "active" && fields.count > 0`, c.Inputs[0].Config.Filter.MetricPass)
+	require.True(t, c.Inputs[0].Config.Filter.IsActive())
+	require.Empty(t, c.UnusedFields)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.http]]
+  metricpass = 'tags.state = "active"'
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Error compiling 'metricpass', column 12")
+}
+
+func TestConfig_CheckMode(t *testing.T) {
+	c := NewConfig()
+	c.CheckMode = true
+	err := c.LoadConfigData([]byte(`
+[agent]
+  utc = true
+
+[[inputs.memcached]]
+  servers = "localhost"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+  unknown = 1
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[outputs.http]]
+  flush_interval = "5 minutes"
+  series_limit = -1
+`))
+	require.NoError(t, err)
+	require.Len(t, c.Inputs, 2)
+	require.Len(t, c.Outputs, 0)
+	require.Equal(t, "line 8", c.Source(c.Inputs[0]))
+	require.Equal(t, "line 12", c.Source(c.Inputs[1]))
+	require.Equal(t, []string{
+		`line 3: option "utc" of agent is deprecated since 1.0.0; option has no effect`,
+	}, c.Warnings)
+
+	var errs []string
+	for _, err := range c.Errors {
+		errs = append(errs, err.Error())
+	}
+	sort.Strings(errs)
+	require.Equal(t, []string{
+		`error parsing http array, line 15: error parsing duration: time: unknown unit " minutes" in duration "5 minutes"`,
+		`error parsing http, line 15: series_limit must not be negative`,
+		`error parsing memcached, line 6: (memcached.Memcached.Servers) cannot unmarshal TOML string into []string`,
+		`plugin inputs.memcached: line 8: configuration specified the fields ["unknown"], but they weren't used`,
+	}, errs)
+}
+
+func TestConfig_LogLevel(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[agent]
+  logformat = "json"
+
+[[inputs.memcached]]
+  log_level = "debug"
+
+[[outputs.http]]
+  log_level = "ERROR"
+`))
+	require.NoError(t, err)
+	require.Equal(t, "json", c.Agent.LogFormat)
+	require.Equal(t, wlog.DEBUG, c.Inputs[0].Config.LogLevel)
+	require.Equal(t, wlog.ERROR, c.Outputs[0].Config.LogLevel)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  log_level = "verbose"
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid log_level "verbose"`)
+}
+
+func TestConfig_XMLParser(t *testing.T) {
+	data := []byte(`
+[[inputs.exec]]
+  commands = ["cat example.xml"]
+  data_format = "xml"
+
+  [[inputs.exec.xml]]
+    metric_selection = "//Sensor"
+    metric_name = "'sensor'"
+    timestamp = "/Gateway/Timestamp"
+    timestamp_format = "unix_ms"
+    [inputs.exec.xml.tags]
+      name = "@name"
+    [inputs.exec.xml.fields]
+      temperature = "number(Variable/@temperature)"
+    [inputs.exec.xml.fields_int]
+      consumers = "Variable/@consumers"
+
+  [[inputs.exec.xml]]
+    metric_selection = "/Gateway"
+`)
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(data))
+	require.Len(t, c.Inputs, 1)
+
+	tbl, err := parseConfig(data)
+	require.NoError(t, err)
+	inputTbl := tbl.Fields["inputs"].(*ast.Table).Fields["exec"].([]*ast.Table)[0]
+	pc, err := NewConfig().getParserConfig("exec", inputTbl)
+	require.NoError(t, err)
+	require.Equal(t, []xml.Config{
+		{
+			Selection:    "//Sensor",
+			MetricQuery:  "'sensor'",
+			Timestamp:    "/Gateway/Timestamp",
+			TimestampFmt: "unix_ms",
+			Tags:         map[string]string{"name": "@name"},
+			Fields:       map[string]string{"temperature": "number(Variable/@temperature)"},
+			FieldsInt:    map[string]string{"consumers": "Variable/@consumers"},
+		},
+		{
+			Selection: "/Gateway",
+			Tags:      map[string]string{},
+			Fields:    map[string]string{},
+			FieldsInt: map[string]string{},
+		},
+	}, pc.XMLConfig)
+}
+
+func TestConfig_SchemaParsers(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.exec]]
+  commands = ["cat message.bin"]
+  data_format = "protobuf"
+  tag_keys = ["host"]
+  protobuf_schema_file = "../plugins/parsers/protobuf/testdata/measurement.proto"
+  protobuf_import_paths = []
+  protobuf_message_type = "example.Measurement"
+  protobuf_name_key = "name"
+  protobuf_string_fields = ["status"]
+  protobuf_time_key = "time"
+
+[[inputs.exec]]
+  commands = ["cat message.bin"]
+  data_format = "avro"
+  avro_confluent_wire_format = true
+  avro_schema_directory = "../plugins/parsers/avro/testdata/registry"
+  avro_time_key = "ts"
+  avro_time_format = "unix_ms"
+  avro_timezone = "UTC"
+`))
+	require.NoError(t, err)
+	require.Len(t, c.Inputs, 2)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[inputs.exec]]
+  commands = ["cat message.bin"]
+  data_format = "protobuf"
+  protobuf_schema_file = "../plugins/parsers/protobuf/testdata/measurement.proto"
+  protobuf_message_type = "example.Unknown"
+`))
+	require.Error(t, err)
+}
+
+func TestConfig_JSONV2Parser(t *testing.T) {
+	data := []byte(`
+[[inputs.exec]]
+  commands = ["cat weather.json"]
+  data_format = "json_v2"
+
+  [[inputs.exec.json_v2]]
+    measurement_name = "weather"
+    timestamp_path = "time"
+    timestamp_format = "unix"
+    timestamp_timezone = "UTC"
+    [[inputs.exec.json_v2.tag]]
+      path = "station.id"
+      rename = "station"
+    [[inputs.exec.json_v2.object]]
+      path = "samples"
+      measurement_name_path = "sensor"
+      [[inputs.exec.json_v2.object.field]]
+        path = "value"
+        type = "float"
+`)
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(data))
+	require.Len(t, c.Inputs, 1)
+
+	tbl, err := parseConfig(data)
+	require.NoError(t, err)
+	inputTbl := tbl.Fields["inputs"].(*ast.Table).Fields["exec"].([]*ast.Table)[0]
+	pc, err := NewConfig().getParserConfig("exec", inputTbl)
+	require.NoError(t, err)
+	require.Equal(t, []json_v2.Config{
+		{
+			MeasurementName:   "weather",
+			TimestampPath:     "time",
+			TimestampFormat:   "unix",
+			TimestampTimezone: "UTC",
+			Tags:              []json_v2.DataSet{{Path: "station.id", Rename: "station"}},
+			Objects: []json_v2.Config{
+				{
+					Path:                "samples",
+					MeasurementNamePath: "sensor",
+					Fields:              []json_v2.DataSet{{Path: "value", Type: "float"}},
+				},
+			},
+		},
+	}, pc.JSONV2Config)
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// checkDeprecations records a warning for each option set in tbl that is
+// marked as deprecated on the plugin struct.  Options are marked with a struct
+// tag holding the version they were deprecated in and a notice:
+//
+//	SSLCA string `toml:"ssl_ca" deprecated:"1.7.0;use 'tls_ca' instead"`
+func (c *Config) checkDeprecations(plugin string, tbl *ast.Table, v interface{}) {
+	deprecated := make(map[string]string)
+	deprecatedFields(reflect.TypeOf(v), deprecated)
+	if len(deprecated) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		tag, ok := deprecated[key]
+		if !ok {
+			tag, ok = deprecated[toml.DefaultConfig.NormFieldName(nil, key)]
+		}
+		if !ok {
+			continue
+		}
+
+		var line int
+		if kv, ok := tbl.Fields[key].(*ast.KeyValue); ok {
+			line = kv.Line
+		}
+		since, notice := tag, ""
+		if i := strings.Index(tag, ";"); i >= 0 {
+			since, notice = tag[:i], strings.TrimSpace(tag[i+1:])
+		}
+
+		msg := fmt.Sprintf("line %d: option %q of %s is deprecated since %s", line, key, plugin, since)
+		if notice != "" {
+			msg += "; " + notice
+		}
+		if c.file != "" {
+			msg = c.file + ": " + msg
+		}
+		if !c.CheckMode {
+			log.Printf("W! [config] %s", msg)
+		}
+		c.Warnings = append(c.Warnings, msg)
+	}
+}
+
+// deprecatedFields adds the deprecated options of the struct type t to m.
+// Options with a toml tag are keyed by the tag, others by their normalized
+// field name.
+func deprecatedFields(t reflect.Type, m map[string]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			deprecatedFields(field.Type, m)
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("deprecated")
+		if !ok {
+			continue
+		}
+		key := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if key == "" {
+			key = toml.DefaultConfig.NormFieldName(t, field.Name)
+		}
+		m[key] = tag
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+)
+
+type deprecatedPlugin struct {
+	URL     string   `toml:"url" deprecated:"1.7.0;use 'urls' instead"`
+	URLs    []string `toml:"urls"`
+	Timeout int      `deprecated:"1.9.0"`
+	tls.ClientConfig
+}
+
+func TestCheckDeprecations(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+urls = ["http://localhost"]
+url = "http://localhost"
+timeout = 5
+ssl_ca = "/etc/ca.pem"
+`))
+	require.NoError(t, err)
+
+	c := NewConfig()
+	c.CheckMode = true
+	c.file = "telegraf.conf"
+	c.checkDeprecations("inputs.test", tbl, &deprecatedPlugin{})
+	require.Equal(t, []string{
+		`telegraf.conf: line 5: option "ssl_ca" of inputs.test is deprecated since 1.7.0; use 'tls_ca' instead`,
+		`telegraf.conf: line 4: option "timeout" of inputs.test is deprecated since 1.9.0`,
+		`telegraf.conf: line 3: option "url" of inputs.test is deprecated since 1.7.0; use 'urls' instead`,
+	}, c.Warnings)
+}
@@ -0,0 +1,491 @@
+// Package expr implements boolean expressions over metrics, as used by the
+// metricpass filter.
+//
+// An expression compares the name, tags, fields and time of a metric:
+//
+//	name == "cpu" && (tags.host =~ "^web" || fields.usage_idle < 10.0)
+//	has(fields.value) and time > now() - 5m
+//
+// Comparisons with a missing tag or field are false.
+package expr
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Expression is a compiled expression.
+type Expression struct {
+	source string
+	root   node
+	now    func() time.Time
+}
+
+// Compile parses the expression and checks the types of its operands.
+func Compile(s string) (*Expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{source: s, root: root, now: time.Now}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval returns true if the metric matches the expression.
+func (e *Expression) Eval(metric telegraf.Metric) bool {
+	env := &env{metric: metric, now: e.now()}
+	v, ok := e.root.eval(env)
+	return ok && truth(v)
+}
+
+type env struct {
+	metric telegraf.Metric
+	now    time.Time
+}
+
+// kind is the static type of a node, kindAny is the type of tags and fields
+// which is only known when evaluated.
+type kind int
+
+const (
+	kindAny kind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindTime
+	kindDuration
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindTime:
+		return "time"
+	case kindDuration:
+		return "duration"
+	default:
+		return "any"
+	}
+}
+
+// node is an element of the expression tree.  The eval method returns false
+// if the value is undefined, e.g. a missing field.
+type node interface {
+	kind() kind
+	position() int
+	eval(env *env) (interface{}, bool)
+}
+
+type literalNode struct {
+	pos   int
+	value interface{}
+}
+
+func (n *literalNode) position() int { return n.pos }
+
+func (n *literalNode) kind() kind { return kindOf(n.value) }
+
+func (n *literalNode) eval(*env) (interface{}, bool) { return n.value, true }
+
+type nameNode struct{ pos int }
+
+func (n *nameNode) position() int { return n.pos }
+
+func (n *nameNode) kind() kind { return kindString }
+
+func (n *nameNode) eval(env *env) (interface{}, bool) { return env.metric.Name(), true }
+
+type timeNode struct{ pos int }
+
+func (n *timeNode) position() int { return n.pos }
+
+func (n *timeNode) kind() kind { return kindTime }
+
+func (n *timeNode) eval(env *env) (interface{}, bool) { return env.metric.Time(), true }
+
+type nowNode struct{ pos int }
+
+func (n *nowNode) position() int { return n.pos }
+
+func (n *nowNode) kind() kind { return kindTime }
+
+func (n *nowNode) eval(env *env) (interface{}, bool) { return env.now, true }
+
+// refNode is a tag or field of the metric.
+type refNode struct {
+	pos   int
+	field bool
+	key   string
+}
+
+func (n *refNode) position() int { return n.pos }
+
+func (n *refNode) kind() kind {
+	if n.field {
+		return kindAny
+	}
+	return kindString
+}
+
+func (n *refNode) eval(env *env) (interface{}, bool) {
+	if n.field {
+		return env.metric.GetField(n.key)
+	}
+	return env.metric.GetTag(n.key)
+}
+
+type hasNode struct {
+	pos int
+	ref *refNode
+}
+
+func (n *hasNode) position() int { return n.pos }
+
+func (n *hasNode) kind() kind { return kindBool }
+
+func (n *hasNode) eval(env *env) (interface{}, bool) {
+	_, ok := n.ref.eval(env)
+	return ok, true
+}
+
+type logicalNode struct {
+	pos         int
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) position() int { return n.pos }
+
+func (n *logicalNode) kind() kind { return kindBool }
+
+func (n *logicalNode) eval(env *env) (interface{}, bool) {
+	v, ok := n.left.eval(env)
+	left := ok && truth(v)
+	if left == n.or {
+		return left, true
+	}
+	v, ok = n.right.eval(env)
+	return ok && truth(v), true
+}
+
+type notNode struct {
+	pos     int
+	operand node
+}
+
+func (n *notNode) position() int { return n.pos }
+
+func (n *notNode) kind() kind { return kindBool }
+
+func (n *notNode) eval(env *env) (interface{}, bool) {
+	v, ok := n.operand.eval(env)
+	return !(ok && truth(v)), true
+}
+
+type matchNode struct {
+	pos     int
+	negate  bool
+	operand node
+	re      *regexp.Regexp
+}
+
+func (n *matchNode) position() int { return n.pos }
+
+func (n *matchNode) kind() kind { return kindBool }
+
+func (n *matchNode) eval(env *env) (interface{}, bool) {
+	v, ok := n.operand.eval(env)
+	if !ok {
+		return false, true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false, true
+	}
+	return n.re.MatchString(s) != n.negate, true
+}
+
+type compareNode struct {
+	pos         int
+	op          string
+	left, right node
+}
+
+func (n *compareNode) position() int { return n.pos }
+
+func (n *compareNode) kind() kind { return kindBool }
+
+func (n *compareNode) eval(env *env) (interface{}, bool) {
+	l, ok := n.left.eval(env)
+	if !ok {
+		return false, true
+	}
+	r, ok := n.right.eval(env)
+	if !ok {
+		return false, true
+	}
+
+	c, ok := compare(l, r)
+	if !ok {
+		// Values of different types are only ever unequal.
+		return n.op == "!=" && c != 0, true
+	}
+	switch n.op {
+	case "==":
+		return c == 0, true
+	case "!=":
+		return c != 0, true
+	case "<":
+		return c < 0, true
+	case "<=":
+		return c <= 0, true
+	case ">":
+		return c > 0, true
+	case ">=":
+		return c >= 0, true
+	}
+	return false, true
+}
+
+// compare returns the order of the values, it returns false if the values
+// cannot be ordered.  Booleans are only compared for equality and a non-zero
+// result is returned for values of different types.
+func compare(l, r interface{}) (int, bool) {
+	switch l := l.(type) {
+	case bool:
+		r, ok := r.(bool)
+		if !ok {
+			return 1, false
+		}
+		if l == r {
+			return 0, true
+		}
+		return 1, false
+	case time.Time:
+		r, ok := r.(time.Time)
+		if !ok {
+			return 1, false
+		}
+		switch {
+		case l.Before(r):
+			return -1, true
+		case l.After(r):
+			return 1, true
+		}
+		return 0, true
+	case time.Duration:
+		r, ok := r.(time.Duration)
+		if !ok {
+			return 1, false
+		}
+		return compareFloat(float64(l), float64(r)), true
+	case string:
+		if r, ok := r.(string); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	lf, ok := toFloat(l)
+	if !ok {
+		return 1, false
+	}
+	rf, ok := toFloat(r)
+	if !ok {
+		return 1, false
+	}
+	return compareFloat(lf, rf), true
+}
+
+func compareFloat(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+type arithNode struct {
+	pos         int
+	op          string
+	left, right node
+}
+
+func (n *arithNode) position() int { return n.pos }
+
+func (n *arithNode) kind() kind {
+	k, _ := arithKind(n.op, n.left.kind(), n.right.kind())
+	return k
+}
+
+func (n *arithNode) eval(env *env) (interface{}, bool) {
+	l, ok := n.left.eval(env)
+	if !ok {
+		return nil, false
+	}
+	r, ok := n.right.eval(env)
+	if !ok {
+		return nil, false
+	}
+
+	switch l := l.(type) {
+	case time.Time:
+		switch r := r.(type) {
+		case time.Time:
+			if n.op == "-" {
+				return l.Sub(r), true
+			}
+		case time.Duration:
+			switch n.op {
+			case "+":
+				return l.Add(r), true
+			case "-":
+				return l.Add(-r), true
+			}
+		}
+		return nil, false
+	case time.Duration:
+		switch r := r.(type) {
+		case time.Time:
+			if n.op == "+" {
+				return r.Add(l), true
+			}
+		case time.Duration:
+			switch n.op {
+			case "+":
+				return l + r, true
+			case "-":
+				return l - r, true
+			}
+		default:
+			f, ok := toFloat(r)
+			if !ok {
+				return nil, false
+			}
+			switch n.op {
+			case "*":
+				return time.Duration(float64(l) * f), true
+			case "/":
+				return time.Duration(float64(l) / f), true
+			}
+		}
+		return nil, false
+	}
+
+	lf, ok := toFloat(l)
+	if !ok {
+		return nil, false
+	}
+	if d, ok := r.(time.Duration); ok {
+		if n.op == "*" {
+			return time.Duration(lf * float64(d)), true
+		}
+		return nil, false
+	}
+	rf, ok := toFloat(r)
+	if !ok {
+		return nil, false
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, true
+	case "-":
+		return lf - rf, true
+	case "*":
+		return lf * rf, true
+	case "/":
+		return lf / rf, true
+	}
+	return nil, false
+}
+
+// arithKind returns the kind of the result of an arithmetic operation, it
+// returns false if the operation is invalid for the kinds.
+func arithKind(op string, l, r kind) (kind, bool) {
+	if l == kindBool || r == kindBool {
+		return kindAny, false
+	}
+	if l == kindAny || r == kindAny {
+		return kindAny, true
+	}
+
+	switch {
+	case l == kindNumber && r == kindNumber:
+		return kindNumber, true
+	case l == kindTime && r == kindTime:
+		return kindDuration, op == "-"
+	case l == kindTime && r == kindDuration:
+		return kindTime, op == "+" || op == "-"
+	case l == kindDuration && r == kindTime:
+		return kindTime, op == "+"
+	case l == kindDuration && r == kindDuration:
+		return kindDuration, op == "+" || op == "-"
+	case l == kindDuration && r == kindNumber:
+		return kindDuration, op == "*" || op == "/"
+	case l == kindNumber && r == kindDuration:
+		return kindDuration, op == "*"
+	}
+	return kindAny, false
+}
+
+func kindOf(v interface{}) kind {
+	switch v.(type) {
+	case bool:
+		return kindBool
+	case int64, uint64, float64:
+		return kindNumber
+	case string:
+		return kindString
+	case time.Time:
+		return kindTime
+	case time.Duration:
+		return kindDuration
+	}
+	return kindAny
+}
+
+// toFloat converts numbers and numeric strings, such as tag values, to a
+// float.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func truth(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
@@ -0,0 +1,149 @@
+package expr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	m := testutil.MustMetric("cpu",
+		map[string]string{
+			"host":   "web01",
+			"cpu":    "cpu-total",
+			"region": "us-east",
+			"rack":   "12",
+		},
+		map[string]interface{}{
+			"usage_idle": 95.5,
+			"count":      int64(42),
+			"total":      uint64(100),
+			"status":     "ok",
+			"healthy":    true,
+		},
+		now.Add(-time.Minute),
+	)
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`name == "cpu"`, true},
+		{`name != "cpu"`, false},
+		{`name == "cpu" && tags.host == "web01"`, true},
+		{`name == "mem" || tags.host == "web01"`, true},
+		{`name == "mem" or tags.host == "db01"`, false},
+		{`not name == "mem"`, true},
+		{`!(name == "cpu")`, false},
+		{`tags.host =~ "^web\\d+$"`, true},
+		{`tags.host =~ '^web\d+$'`, true},
+		{`tags.host !~ "^db"`, true},
+		{`tags["cpu"] == "cpu-total"`, true},
+		{`fields.usage_idle > 90`, true},
+		{`fields.usage_idle <= 90.0`, false},
+		{`fields.count == 42`, true},
+		{`fields.count >= 42 and fields.count < 43`, true},
+		{`fields.total / 4 == 25`, true},
+		{`fields.count * 2 + 1 == 85`, true},
+		{`-fields.count < 0`, true},
+		{`fields.status == "ok"`, true},
+		{`fields.healthy`, true},
+		{`fields.healthy == true`, true},
+		{`!fields.healthy`, false},
+		{`fields["usage_idle"] > fields.count`, true},
+		{`tags.rack > 9`, true},
+		{`tags.rack == 12`, true},
+		{`tags.host > 9`, false},
+		{`fields.status == 1`, false},
+		{`fields.status != 1`, true},
+		{`has(fields.count)`, true},
+		{`has(tags.missing)`, false},
+		{`!has(fields.missing)`, true},
+		{`fields.missing > 1`, false},
+		{`fields.missing < 1`, false},
+		{`fields.missing != 1`, false},
+		{`fields.missing`, false},
+		{`!fields.missing`, true},
+		{`tags.missing =~ ".*"`, false},
+		{`time > now() - 5m`, true},
+		{`time > now() - 30s`, false},
+		{`now() - time == 1m`, true},
+		{`now() - time < 2 * 1m`, true},
+		{`time > "2020-09-13T12:00:00Z"`, true},
+		{`time < "2020-09-13T12:00:00Z"`, false},
+		{`1h30m == 90m`, true},
+		{`1e3 == 1000`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Compile(tt.expr)
+			require.NoError(t, err)
+			e.now = func() time.Time { return now }
+			require.Equal(t, tt.expected, e.Eval(m))
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, `column 1: unexpected end of expression`},
+		{`name ==`, `column 8: unexpected end of expression`},
+		{`name == "cpu`, `column 9: unterminated string`},
+		{`name == "cpu" &&`, `column 17: unexpected end of expression`},
+		{`(name == "cpu"`, `column 15: expected ")", found end of expression`},
+		{`name == "cpu")`, `column 14: unexpected ")"`},
+		{`host == "a"`, `column 1: unknown identifier "host", expected name, time, tags.<key> or fields.<key>`},
+		{`tags == "a"`, `column 6: expected "." or "[" after "tags", found "=="`},
+		{`tags[host] == "a"`, `column 6: expected string key, found "host"`},
+		{`name == "a" # 1`, `column 13: unexpected character '#'`},
+		{`name`, `column 1: operand of the expression must be a boolean, found string`},
+		{`fields.a > 1 && 5`, `column 17: operand of && must be a boolean, found number`},
+		{`!name`, `column 2: operand of ! must be a boolean, found string`},
+		{`name =~ tags.a`, `column 9: the pattern of =~ must be a string`},
+		{`name =~ "("`, "column 9: invalid regular expression: error parsing regexp: missing closing ): `(`"},
+		{`name == 1m`, `column 6: cannot compare string == duration`},
+		{`true < false`, `column 6: cannot order booleans with <`},
+		{`time > "yesterday"`, `column 8: invalid time "yesterday", expected RFC3339 format`},
+		{`time + time > now()`, `column 6: invalid operation time + time`},
+		{`name + 1 > 2`, `column 6: invalid operation string + number`},
+		{`time > 5x`, `column 8: invalid duration "5x"`},
+		{`has(name)`, `column 5: unknown identifier "name", expected name, time, tags.<key> or fields.<key>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	e, err := Compile(`name == "cpu"`)
+	require.NoError(t, err)
+	require.Equal(t, `name == "cpu"`, e.String())
+}
+
+func BenchmarkEval(b *testing.B) {
+	e, err := Compile(`name == "cpu" && tags.host =~ "^web" && fields.usage_idle < 10.0`)
+	require.NoError(b, err)
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "web01"},
+		map[string]interface{}{"usage_idle": 5.0},
+		time.Unix(0, 0),
+	)
+	metrics := []telegraf.Metric{m}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, m := range metrics {
+			e.Eval(m)
+		}
+	}
+}
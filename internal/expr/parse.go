@@ -0,0 +1,556 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenDuration
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{} // parsed value of literals
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators sorted so the longest match is found first.
+var operators = []string{
+	"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "!", "+", "-", "*", "/", "(", ")", "[", "]", ".",
+}
+
+// Error is a syntax or type error found when compiling an expression.
+type Error struct {
+	Pos int // byte offset in the expression
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r >= '0' && r <= '9':
+			t, err := scanNumber(s, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += len(t.text)
+		case r == '"' || r == '\'':
+			t, err := scanString(s, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += len(t.text)
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(s) {
+				r, size := utf8.DecodeRuneInString(s[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: s[i:j], pos: i})
+			i = j
+		default:
+			var op string
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorf(i, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(s)})
+	return tokens, nil
+}
+
+// scanNumber scans an integer, a float or a duration such as 1h30m.
+func scanNumber(s string, start int) (token, error) {
+	i := start
+	for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+		i++
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') &&
+		i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '+' || s[i+1] == '-') {
+		i += 2
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+	}
+
+	// A unit directly following the number makes it a duration.
+	j := i
+	for j < len(s) {
+		r, size := utf8.DecodeRuneInString(s[j:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' {
+			break
+		}
+		j += size
+	}
+	if j > i {
+		text := s[start:j]
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return token{}, errorf(start, "invalid duration %q", text)
+		}
+		return token{kind: tokenDuration, text: text, value: d, pos: start}, nil
+	}
+
+	text := s[start:i]
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return token{kind: tokenNumber, text: text, value: n, pos: start}, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, errorf(start, "invalid number %q", text)
+	}
+	return token{kind: tokenNumber, text: text, value: f, pos: start}, nil
+}
+
+// scanString scans a double quoted string with Go escape sequences or a
+// single quoted string without escapes.
+func scanString(s string, start int) (token, error) {
+	quote := s[start]
+	i := start + 1
+	for i < len(s) && s[i] != quote {
+		if quote == '"' && s[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i >= len(s) {
+		return token{}, errorf(start, "unterminated string")
+	}
+
+	text := s[start : i+1]
+	value := text[1 : len(text)-1]
+	if quote == '"' {
+		var err error
+		value, err = strconv.Unquote(text)
+		if err != nil {
+			return token{}, errorf(start, "invalid string %s", text)
+		}
+	}
+	return token{kind: tokenString, text: text, value: value, pos: start}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser is a recursive descent parser of the grammar:
+//
+//	or      = and { ("||" | "or") and }
+//	and     = not { ("&&" | "and") not }
+//	not     = ("!" | "not") not | compare
+//	compare = sum [ ("==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "!~") sum ]
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | duration | string | "true" | "false" | "(" or ")"
+//	        | "name" | "time" | "now" "(" ")" | "has" "(" ref ")" | ref
+//	ref     = ("tags" | "fields") ( "." ident | "[" string "]" )
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators or keywords.
+func (p *parser) accept(texts ...string) (token, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && t.kind != tokenIdent {
+		return t, false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			return p.next(), true
+		}
+	}
+	return t, false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		t := p.peek()
+		return errorf(t.pos, "expected %q, found %s", text, t)
+	}
+	return nil
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorf(t.pos, "unexpected %s", t)
+	}
+	if err := checkBool(n, "the expression"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("||", "or")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBool(left, t.text); err != nil {
+			return nil, err
+		}
+		if err := checkBool(right, t.text); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{pos: t.pos, or: true, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("&&", "and")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBool(left, t.text); err != nil {
+			return nil, err
+		}
+		if err := checkBool(right, t.text); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{pos: t.pos, left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if t, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBool(operand, t.text); err != nil {
+			return nil, err
+		}
+		return &notNode{pos: t.pos, operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "=~", "!~")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.text == "=~" || t.text == "!~" {
+		lit, ok := right.(*literalNode)
+		if !ok {
+			return nil, errorf(right.position(), "the pattern of %s must be a string", t.text)
+		}
+		pattern, ok := lit.value.(string)
+		if !ok {
+			return nil, errorf(right.position(), "the pattern of %s must be a string", t.text)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errorf(right.position(), "invalid regular expression: %v", err)
+		}
+		return &matchNode{pos: t.pos, negate: t.text == "!~", operand: left, re: re}, nil
+	}
+
+	left, right, err = convertTimeLiterals(left, right)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkComparable(t, left, right); err != nil {
+		return nil, err
+	}
+	return &compareNode{pos: t.pos, op: t.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{pos: t.pos, op: t.text, left: left, right: right}
+		if err := checkArith(left.(*arithNode)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{pos: t.pos, op: t.text, left: left, right: right}
+		if err := checkArith(left.(*arithNode)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if t, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		n := &arithNode{pos: t.pos, op: "-", left: &literalNode{pos: t.pos, value: int64(0)}, right: operand}
+		if err := checkArith(n); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenDuration, tokenString:
+		return &literalNode{pos: t.pos, value: t.value}, nil
+	case tokenOperator:
+		if t.text != "(" {
+			return nil, errorf(t.pos, "unexpected %s", t)
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return &literalNode{pos: t.pos, value: t.text == "true"}, nil
+		case "name":
+			return &nameNode{pos: t.pos}, nil
+		case "time":
+			return &timeNode{pos: t.pos}, nil
+		case "now":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &nowNode{pos: t.pos}, nil
+		case "has":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			ref, err := p.parseRef(p.next())
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &hasNode{pos: t.pos, ref: ref}, nil
+		default:
+			return p.parseRef(t)
+		}
+	default:
+		return nil, errorf(t.pos, "unexpected %s", t)
+	}
+}
+
+func (p *parser) parseRef(t token) (*refNode, error) {
+	if t.kind != tokenIdent || (t.text != "tags" && t.text != "fields") {
+		return nil, errorf(t.pos, "unknown identifier %s, expected name, time, tags.<key> or fields.<key>", t)
+	}
+
+	ref := &refNode{pos: t.pos, field: t.text == "fields"}
+	if _, ok := p.accept("."); ok {
+		key := p.next()
+		if key.kind != tokenIdent {
+			return nil, errorf(key.pos, "expected key after %q, found %s", t.text+".", key)
+		}
+		ref.key = key.text
+		return ref, nil
+	}
+	if _, ok := p.accept("["); ok {
+		key := p.next()
+		if key.kind != tokenString {
+			return nil, errorf(key.pos, "expected string key, found %s", key)
+		}
+		ref.key = key.value.(string)
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return ref, nil
+	}
+	next := p.peek()
+	return nil, errorf(next.pos, "expected %q or \"[\" after %q, found %s", ".", t.text, next)
+}
+
+// convertTimeLiterals parses string literals compared to times as RFC3339
+// timestamps.
+func convertTimeLiterals(left, right node) (node, node, error) {
+	convert := func(n node) (node, error) {
+		lit, ok := n.(*literalNode)
+		if !ok {
+			return n, nil
+		}
+		s, ok := lit.value.(string)
+		if !ok {
+			return n, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, errorf(lit.pos, "invalid time %q, expected RFC3339 format", s)
+		}
+		return &literalNode{pos: lit.pos, value: t}, nil
+	}
+
+	var err error
+	if left.kind() == kindTime {
+		right, err = convert(right)
+	} else if right.kind() == kindTime {
+		left, err = convert(left)
+	}
+	return left, right, err
+}
+
+func checkBool(n node, op string) error {
+	if k := n.kind(); k != kindBool && k != kindAny {
+		return errorf(n.position(), "operand of %s must be a boolean, found %s", op, k)
+	}
+	return nil
+}
+
+func checkComparable(t token, left, right node) error {
+	l, r := left.kind(), right.kind()
+	if l == kindAny || r == kindAny {
+		return nil
+	}
+	if !comparable(l, r) {
+		return errorf(t.pos, "cannot compare %s %s %s", l, t.text, r)
+	}
+	if l == kindBool && t.text != "==" && t.text != "!=" {
+		return errorf(t.pos, "cannot order booleans with %s", t.text)
+	}
+	return nil
+}
+
+func comparable(l, r kind) bool {
+	switch {
+	case l == r:
+		return true
+	case l == kindNumber && r == kindString, l == kindString && r == kindNumber:
+		// Strings are compared as numbers, e.g. tag values.
+		return true
+	}
+	return false
+}
+
+func checkArith(n *arithNode) error {
+	if _, ok := arithKind(n.op, n.left.kind(), n.right.kind()); !ok {
+		return errorf(n.pos, "invalid operation %s %s %s", n.left.kind(), n.op, n.right.kind())
+	}
+	return nil
+}
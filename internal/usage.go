@@ -11,6 +11,8 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config check        check the configuration without running plugins
+  secrets             list, get or set the secrets of a secret store
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
@@ -50,6 +52,12 @@ Examples:
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
+  # check the config files for errors and deprecated options
+  telegraf --config telegraf.conf --config-directory telegraf.d config check
+
+  # store a secret in the secret store with the id "local"
+  telegraf --config telegraf.conf secrets set local password
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/influxdata/wlog"
+)
+
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Entry is a log message with its source.
+type Entry struct {
+	Time  time.Time
+	Level wlog.Level
+	// Source is the component logging the message, e.g. agent or the log
+	// name of a plugin such as inputs.cpu::alias.
+	Source string
+	// PluginType, PluginName and Alias identify the plugin logging the
+	// message, if any.
+	PluginType string
+	PluginName string
+	Alias      string
+	Message    string
+	// Error is the error the message is about, if any.
+	Error string
+}
+
+// entryWriter is implemented by log writers accepting entries.
+type entryWriter interface {
+	WriteEntry(e *Entry) error
+}
+
+// Print writes the entry to the log output.  The entry is written regardless
+// of the log level, the caller is expected to filter entries by level.  If the
+// log output was not set up by SetupLogging the entry is printed as a line
+// subject to the log level.
+func Print(e *Entry) {
+	if w, ok := log.Writer().(entryWriter); ok {
+		if err := w.WriteEntry(e); err == nil {
+			return
+		}
+	}
+	log.Print(e.text())
+}
+
+// text returns the entry in the format of the standard log lines, without
+// the timestamp.
+func (e *Entry) text() string {
+	var b strings.Builder
+	b.WriteByte(wlog.ReverseLevels[e.Level])
+	b.WriteString("! ")
+	if e.Source != "" {
+		b.WriteString("[" + e.Source + "] ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+type jsonEntry struct {
+	Time       string `json:"time"`
+	Level      string `json:"level"`
+	Source     string `json:"source,omitempty"`
+	PluginType string `json:"plugin_type,omitempty"`
+	PluginName string `json:"plugin_name,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+	Message    string `json:"message"`
+	Error      string `json:"error,omitempty"`
+}
+
+// json returns the entry as a JSON object terminated by a newline.
+func (e *Entry) json() ([]byte, error) {
+	je := jsonEntry{
+		Time:       e.Time.UTC().Format(time.RFC3339Nano),
+		Level:      strings.ToLower(levelName(e.Level)),
+		PluginType: e.PluginType,
+		PluginName: e.PluginName,
+		Alias:      e.Alias,
+		Message:    e.Message,
+		Error:      e.Error,
+	}
+	if e.PluginType == "" {
+		je.Source = e.Source
+	}
+
+	buf, err := json.Marshal(je)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+func levelName(level wlog.Level) string {
+	for name, l := range wlog.StringToLevel {
+		if l == level {
+			return name
+		}
+	}
+	return "INFO"
+}
+
+// parseEntry parses a log line of the standard logger, such as
+// "E! [inputs.cpu] message".
+func parseEntry(t time.Time, line string) *Entry {
+	e := &Entry{Time: t, Level: wlog.INFO}
+	if prefixRegex.MatchString(line) {
+		e.Level = wlog.Levels[line[0]]
+		line = strings.TrimPrefix(line[2:], " ")
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	if strings.HasPrefix(line, "[") {
+		if i := strings.Index(line, "] "); i > 0 {
+			e.Source = line[1:i]
+			line = line[i+2:]
+		} else if strings.HasSuffix(line, "]") {
+			e.Source = line[1 : len(line)-1]
+			line = ""
+		}
+	}
+	e.PluginType, e.PluginName, e.Alias = ParseSource(e.Source)
+	e.Message = line
+	return e
+}
+
+// ParseSource splits the log name of a plugin, such as inputs.cpu::alias,
+// into its type, name and alias.  Sources not naming a plugin return empty
+// strings.
+func ParseSource(source string) (pluginType, name, alias string) {
+	i := strings.Index(source, ".")
+	if i < 0 {
+		return "", "", ""
+	}
+	pluginType, name = source[:i], source[i+1:]
+	if j := strings.Index(name, "::"); j >= 0 {
+		name, alias = name[:j], name[j+2:]
+	}
+	return pluginType, name, alias
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf/internal"
@@ -28,6 +29,8 @@ type LogConfig struct {
 	Quiet bool
 	//stderr, stdout, file or eventlog (Windows only)
 	LogTarget string
+	// text or json, the format of the lines written to stderr or a file
+	LogFormat string
 	// will direct the logging output to a file. Empty string is
 	// interpreted as stderr. If there is an error opening the file the
 	// logger will fallback to stderr
@@ -54,18 +57,55 @@ func registerLogger(name string, loggerCreator LoggerCreator) {
 }
 
 type telegrafLog struct {
+	mu             sync.Mutex
 	writer         io.Writer
 	internalWriter io.Writer
+	format         string
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
 	var line []byte
 	if !prefixRegex.Match(b) {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
+		line = append([]byte("I! "), b...)
 	} else {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
+		line = b
 	}
-	return t.writer.Write(line)
+	if wlog.Levels[line[0]] < wlog.LogLevel() {
+		return 0, nil
+	}
+
+	now := time.Now()
+	if t.format == LogFormatJSON {
+		return t.writeJSON(parseEntry(now, string(line)))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.writer.Write(append([]byte(now.UTC().Format(time.RFC3339)+" "), line...))
+}
+
+// WriteEntry writes the entry regardless of the log level.
+func (t *telegrafLog) WriteEntry(e *Entry) error {
+	if t.format == LogFormatJSON {
+		_, err := t.writeJSON(e)
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.writer.Write([]byte(e.Time.UTC().Format(time.RFC3339) + " " + e.text() + "\n"))
+	return err
+}
+
+func (t *telegrafLog) writeJSON(e *Entry) (int, error) {
+	buf, err := e.json()
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.writer.Write(buf)
 }
 
 func (t *telegrafLog) Close() error {
@@ -83,10 +123,11 @@ func (t *telegrafLog) Close() error {
 }
 
 // newTelegrafWriter returns a logging-wrapped writer.
-func newTelegrafWriter(w io.Writer) io.Writer {
+func newTelegrafWriter(w io.Writer, format string) io.Writer {
 	return &telegrafLog{
-		writer:         wlog.NewWriter(w),
+		writer:         w,
 		internalWriter: w,
+		format:         format,
 	}
 }
 
@@ -120,7 +161,17 @@ func (t *telegrafLogCreator) CreateLogger(config LogConfig) (io.Writer, error) {
 		writer = defaultWriter
 	}
 
-	return newTelegrafWriter(writer), nil
+	format := config.LogFormat
+	switch format {
+	case LogFormatText, LogFormatJSON:
+	case "":
+		format = LogFormatText
+	default:
+		log.Printf("E! Unsupported logformat: %s, using text", config.LogFormat)
+		format = LogFormatText
+	}
+
+	return newTelegrafWriter(writer, format), nil
 }
 
 // Keep track what is actually set as a log output, because log package doesn't provide a getter.
@@ -2,14 +2,18 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -134,10 +138,104 @@ func TestLogTargetSettings(t *testing.T) {
 	assert.Equal(t, logger.internalWriter, os.Stderr)
 }
 
+func TestJSONLogFormat(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	config := createBasicLogConfig(tmpfile.Name())
+	config.LogFormat = LogFormatJSON
+	SetupLogging(config)
+	log.Printf("E! [inputs.cpu::total] Error in plugin: failed")
+	log.Printf("I! [agent] Starting")
+	log.Printf("D! [agent] ignored")
+	Print(&Entry{
+		Time:       time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+		Level:      wlog.DEBUG,
+		Source:     "outputs.file",
+		PluginType: "outputs",
+		PluginName: "file",
+		Message:    "Wrote batch: disk full",
+		Error:      "disk full",
+	})
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(f)), "\n")
+	require.Len(t, lines, 3)
+
+	var entries []map[string]interface{}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		require.NotEmpty(t, entry["time"])
+		delete(entry, "time")
+		entries = append(entries, entry)
+	}
+	require.Equal(t, []map[string]interface{}{
+		{
+			"level":       "error",
+			"plugin_type": "inputs",
+			"plugin_name": "cpu",
+			"alias":       "total",
+			"message":     "Error in plugin: failed",
+		},
+		{
+			"level":   "info",
+			"source":  "agent",
+			"message": "Starting",
+		},
+		{
+			"level":       "debug",
+			"plugin_type": "outputs",
+			"plugin_name": "file",
+			"message":     "Wrote batch: disk full",
+			"error":       "disk full",
+		},
+	}, entries)
+	require.Contains(t, lines[2], `"time":"2020-10-01T12:00:00Z"`)
+}
+
+func TestPrintEntryText(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	SetupLogging(createBasicLogConfig(tmpfile.Name()))
+	// Entries are written regardless of the log level.
+	Print(&Entry{
+		Time:    time.Now(),
+		Level:   wlog.DEBUG,
+		Source:  "inputs.cpu",
+		Message: "TEST",
+	})
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+	require.Equal(t, []byte("Z D! [inputs.cpu] TEST\n"), f[19:])
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		source                  string
+		pluginType, name, alias string
+	}{
+		{source: "agent"},
+		{source: "inputs.cpu", pluginType: "inputs", name: "cpu"},
+		{source: "outputs.influxdb::local", pluginType: "outputs", name: "influxdb", alias: "local"},
+	}
+	for _, tt := range tests {
+		pluginType, name, alias := ParseSource(tt.source)
+		require.Equal(t, tt.pluginType, pluginType)
+		require.Equal(t, tt.name, name)
+		require.Equal(t, tt.alias, alias)
+	}
+}
+
 func BenchmarkTelegrafLogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
-	w := newTelegrafWriter(&buf)
+	w := newTelegrafWriter(&buf, LogFormatText)
 	for i := 0; i < b.N; i++ {
 		buf.Reset()
 		w.Write(msg)
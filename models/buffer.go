@@ -12,18 +12,35 @@ var (
 	AgentMetricsDropped = selfstat.Register("agent", "metrics_dropped", map[string]string{})
 )
 
-// Buffer stores metrics in a circular buffer.
-type Buffer struct {
-	sync.Mutex
-	buf   []telegraf.Metric
-	first int // index of the first/oldest metric
-	last  int // one after the index of the last/newest metric
-	size  int // number of metrics currently in the buffer
-	cap   int // the capacity of the buffer
+// MetricBuffer holds the metrics of an output until they are written.
+type MetricBuffer interface {
+	// Len returns the number of metrics currently in the buffer.
+	Len() int
 
-	batchFirst int // index of the first metric in the batch
-	batchSize  int // number of metrics currently in the batch
+	// Add adds metrics to the buffer and returns number of dropped metrics.
+	Add(metrics ...telegraf.Metric) int
+
+	// Batch returns a slice containing up to batchSize of the oldest metrics
+	// not yet dropped.
+	Batch(batchSize int) []telegraf.Metric
+
+	// Accept marks the batch, acquired from Batch(), as successfully written.
+	Accept(batch []telegraf.Metric)
 
+	// Reject returns the batch, acquired from Batch(), to the buffer and marks
+	// it as unsent.
+	Reject(batch []telegraf.Metric)
+
+	// Drop removes the batch, acquired from Batch(), from the buffer and
+	// marks it as dropped.
+	Drop(batch []telegraf.Metric)
+
+	// Close releases any resources held by the buffer.
+	Close() error
+}
+
+// BufferStats are the internal statistics reported by every buffer.
+type BufferStats struct {
 	MetricsAdded   selfstat.Stat
 	MetricsWritten selfstat.Stat
 	MetricsDropped selfstat.Stat
@@ -31,20 +48,8 @@ type Buffer struct {
 	BufferLimit    selfstat.Stat
 }
 
-// NewBuffer returns a new empty Buffer with the given capacity.
-func NewBuffer(name string, alias string, capacity int) *Buffer {
-	tags := map[string]string{"output": name}
-	if alias != "" {
-		tags["alias"] = alias
-	}
-
-	b := &Buffer{
-		buf:   make([]telegraf.Metric, capacity),
-		first: 0,
-		last:  0,
-		size:  0,
-		cap:   capacity,
-
+func newBufferStats(tags map[string]string, capacity int) BufferStats {
+	stats := BufferStats{
 		MetricsAdded: selfstat.Register(
 			"write",
 			"metrics_added",
@@ -71,8 +76,61 @@ func NewBuffer(name string, alias string, capacity int) *Buffer {
 			tags,
 		),
 	}
-	b.BufferSize.Set(int64(0))
-	b.BufferLimit.Set(int64(capacity))
+	stats.BufferSize.Set(int64(0))
+	stats.BufferLimit.Set(int64(capacity))
+	return stats
+}
+
+func bufferTags(name string, alias string) map[string]string {
+	tags := map[string]string{"output": name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	return tags
+}
+
+func (s *BufferStats) metricAdded() {
+	s.MetricsAdded.Incr(1)
+}
+
+func (s *BufferStats) metricWritten(metric telegraf.Metric) {
+	AgentMetricsWritten.Incr(1)
+	s.MetricsWritten.Incr(1)
+	metric.Accept()
+}
+
+func (s *BufferStats) metricDropped(metric telegraf.Metric) {
+	AgentMetricsDropped.Incr(1)
+	s.MetricsDropped.Incr(1)
+	metric.Reject()
+}
+
+// Buffer stores metrics in a circular buffer.
+type Buffer struct {
+	sync.Mutex
+	buf   []telegraf.Metric
+	first int // index of the first/oldest metric
+	last  int // one after the index of the last/newest metric
+	size  int // number of metrics currently in the buffer
+	cap   int // the capacity of the buffer
+
+	batchFirst int // index of the first metric in the batch
+	batchSize  int // number of metrics currently in the batch
+
+	BufferStats
+}
+
+// NewBuffer returns a new empty Buffer with the given capacity.
+func NewBuffer(name string, alias string, capacity int) *Buffer {
+	b := &Buffer{
+		buf:   make([]telegraf.Metric, capacity),
+		first: 0,
+		last:  0,
+		size:  0,
+		cap:   capacity,
+
+		BufferStats: newBufferStats(bufferTags(name, alias), capacity),
+	}
 	return b
 }
 
@@ -88,22 +146,6 @@ func (b *Buffer) length() int {
 	return min(b.size+b.batchSize, b.cap)
 }
 
-func (b *Buffer) metricAdded() {
-	b.MetricsAdded.Incr(1)
-}
-
-func (b *Buffer) metricWritten(metric telegraf.Metric) {
-	AgentMetricsWritten.Incr(1)
-	b.MetricsWritten.Incr(1)
-	metric.Accept()
-}
-
-func (b *Buffer) metricDropped(metric telegraf.Metric) {
-	AgentMetricsDropped.Incr(1)
-	b.MetricsDropped.Incr(1)
-	metric.Reject()
-}
-
 func (b *Buffer) add(m telegraf.Metric) int {
 	dropped := 0
 	// Check if Buffer is full
@@ -187,6 +229,20 @@ func (b *Buffer) Accept(batch []telegraf.Metric) {
 	b.BufferSize.Set(int64(b.length()))
 }
 
+// Drop removes the batch, acquired from Batch(), from the buffer and marks it
+// as dropped.
+func (b *Buffer) Drop(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricDropped(m)
+	}
+
+	b.resetBatch()
+	b.BufferSize.Set(int64(b.length()))
+}
+
 // Reject returns the batch, acquired from Batch(), to the buffer and marks it
 // as unsent.
 func (b *Buffer) Reject(batch []telegraf.Metric) {
@@ -220,6 +276,11 @@ func (b *Buffer) Reject(batch []telegraf.Metric) {
 	b.BufferSize.Set(int64(b.length()))
 }
 
+// Close is a no-op for the in-memory buffer.
+func (b *Buffer) Close() error {
+	return nil
+}
+
 // dist returns the distance between two indexes.  Because this data structure
 // uses a half open range the arguments must both either left side or right
 // side pairs.
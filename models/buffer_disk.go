@@ -0,0 +1,784 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// Extension of the segment files of the write-ahead log.
+	diskSegmentExt = ".wal"
+
+	// Name of the file holding the position of the oldest unwritten metric.
+	diskHeadFile = "head"
+
+	// The size limit is split into this many segments, the oldest segment is
+	// dropped as a whole when the limit is exceeded.
+	diskSegmentsPerLimit = 8
+
+	// Size of the record header: payload length and CRC-32 of the payload.
+	diskRecordHeaderSize = 8
+
+	// Upper bound for a single record, anything larger is treated as
+	// corruption.
+	diskRecordMaxSize = 64 * 1024 * 1024
+)
+
+var errDiskRecordCorrupt = errors.New("corrupt record")
+
+// DiskBuffer stores metrics in a write-ahead log on disk so that unwritten
+// metrics survive a restart of the agent.
+//
+// The log is split into segment files which are removed once all of their
+// metrics have been written.  When the size limit is exceeded the oldest
+// segment is removed and its metrics are dropped.
+type DiskBuffer struct {
+	sync.Mutex
+	path        string
+	limit       int64 // maximum size of all segments in bytes
+	segmentSize int64 // size at which a new segment is started
+	log         telegraf.Logger
+
+	segments []*diskSegment
+	file     *os.File
+	writer   *bufio.Writer
+	nextID   uint64
+	size     int64 // size of all segments in bytes
+	count    int   // number of unwritten metrics, including the batch
+
+	head      diskPosition // position of the oldest unwritten metric
+	batchEnd  diskPosition // position after the newest metric in the batch
+	batchSize int          // number of metrics currently in the batch
+
+	BufferStats
+	BufferSizeBytes  selfstat.Stat
+	BufferLimitBytes selfstat.Stat
+}
+
+type diskSegment struct {
+	id      uint64
+	size    int64
+	records int
+}
+
+// diskPosition is a record boundary in the log.
+type diskPosition struct {
+	segment uint64
+	offset  int64
+	record  int
+}
+
+// diskRecord is the serialized form of a metric.
+type diskRecord struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+	Time   int64
+	Type   telegraf.ValueType
+}
+
+// NewDiskBuffer returns a DiskBuffer stored in directory, restoring any
+// metrics left unwritten by a previous run.
+func NewDiskBuffer(
+	name string,
+	alias string,
+	directory string,
+	limit int64,
+	log telegraf.Logger,
+) (*DiskBuffer, error) {
+	if directory == "" {
+		return nil, errors.New("buffer_directory must be set for the disk buffer")
+	}
+	if limit <= 0 {
+		return nil, errors.New("buffer_size_limit must be greater than zero")
+	}
+
+	if err := os.MkdirAll(directory, 0750); err != nil {
+		return nil, err
+	}
+
+	tags := bufferTags(name, alias)
+	b := &DiskBuffer{
+		path:        directory,
+		limit:       limit,
+		segmentSize: limit / diskSegmentsPerLimit,
+		log:         log,
+
+		BufferStats: newBufferStats(tags, 0),
+		BufferSizeBytes: selfstat.Register(
+			"write",
+			"buffer_size_bytes",
+			tags,
+		),
+		BufferLimitBytes: selfstat.Register(
+			"write",
+			"buffer_limit_bytes",
+			tags,
+		),
+	}
+
+	if err := b.restore(); err != nil {
+		return nil, err
+	}
+
+	b.BufferLimitBytes.Set(limit)
+	b.updateStats()
+	if b.count > 0 {
+		log.Infof("Restored %d unwritten metrics from %q", b.count, directory)
+	}
+	return b, nil
+}
+
+// Len returns the number of metrics currently in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.count
+}
+
+// Size returns the size of the log in bytes and its size limit.
+func (b *DiskBuffer) Size() (size, limit int64) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.size, b.limit
+}
+
+// Add appends metrics to the log and returns number of dropped metrics.
+//
+// Metrics are acknowledged as soon as they are persisted, metrics failing to
+// be written to the segment file are dropped.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	var pending []telegraf.Metric // written since the last flush
+	var start diskSegment         // the active segment before the pending metrics
+	for _, m := range metrics {
+		b.metricAdded()
+
+		payload, err := encodeMetric(m)
+		if err != nil {
+			b.log.Errorf("Encoding metric for disk buffer: %v", err)
+			b.metricDropped(m)
+			dropped++
+			continue
+		}
+
+		if b.segmentFull() {
+			dropped += b.commit(pending, start)
+			pending = pending[:0]
+			if err := b.openSegment(); err != nil {
+				b.log.Errorf("Starting disk buffer segment: %v", err)
+				b.metricDropped(m)
+				dropped++
+				continue
+			}
+		}
+
+		if len(pending) == 0 {
+			start = *b.activeSegment()
+		}
+		if err := b.append(payload); err != nil {
+			b.log.Errorf("Writing metric to disk buffer: %v", err)
+			dropped += b.discard(pending, start)
+			pending = pending[:0]
+			b.metricDropped(m)
+			dropped++
+			continue
+		}
+		pending = append(pending, m)
+	}
+	dropped += b.commit(pending, start)
+
+	dropped += b.enforceLimit()
+	b.updateStats()
+	return dropped
+}
+
+// Batch returns a slice containing up to batchSize of the oldest metrics not
+// yet written.  Metrics are ordered from oldest to newest in the batch.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	out := make([]telegraf.Metric, 0, min(b.count, batchSize))
+	pos := b.head
+	for _, s := range b.segments {
+		if len(out) == batchSize {
+			break
+		}
+		if s.id < pos.segment {
+			continue
+		}
+		if s.id > pos.segment {
+			pos = diskPosition{segment: s.id}
+		}
+
+		var err error
+		out, pos, err = b.readSegment(s, pos, out, batchSize)
+		if err != nil {
+			b.log.Errorf("Reading disk buffer segment %d: %v", s.id, err)
+			break
+		}
+	}
+
+	b.batchEnd = pos
+	b.batchSize = len(out)
+	return out
+}
+
+// Accept marks the batch, acquired from Batch(), as successfully written and
+// removes it from the log.
+func (b *DiskBuffer) Accept(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricWritten(m)
+	}
+	b.removeBatch()
+}
+
+// Drop removes the batch, acquired from Batch(), from the log and marks it as
+// dropped.
+func (b *DiskBuffer) Drop(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricDropped(m)
+	}
+	b.removeBatch()
+}
+
+// removeBatch advances the head past the batch and removes the segments that
+// no longer hold unwritten metrics.
+func (b *DiskBuffer) removeBatch() {
+	if b.batchSize > 0 {
+		b.count -= b.distance(b.head, b.batchEnd)
+		if b.after(b.batchEnd, b.head) {
+			b.head = b.batchEnd
+		}
+	}
+	b.resetBatch()
+	b.compact()
+
+	if err := b.writeHead(); err != nil {
+		b.log.Errorf("Persisting disk buffer position: %v", err)
+	}
+	b.updateStats()
+}
+
+// Reject marks the batch, acquired from Batch(), as unsent.  The metrics
+// remain in the log and are returned again by the next call to Batch().
+func (b *DiskBuffer) Reject(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.resetBatch()
+	b.updateStats()
+}
+
+// SetLimit changes the size limit of the log and returns the number of
+// metrics dropped to fit the new limit.
+func (b *DiskBuffer) SetLimit(limit int64) int {
+	b.Lock()
+	defer b.Unlock()
+
+	b.limit = limit
+	b.segmentSize = limit / diskSegmentsPerLimit
+	b.BufferLimitBytes.Set(limit)
+
+	dropped := b.enforceLimit()
+	b.updateStats()
+	return dropped
+}
+
+// Close flushes the log to disk and closes the active segment.
+func (b *DiskBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if err := b.closeSegment(); err != nil {
+		return err
+	}
+	return b.writeHead()
+}
+
+// restore loads the segments and head position left by a previous run.
+func (b *DiskBuffer) restore() error {
+	files, err := ioutil.ReadDir(b.path)
+	if err != nil {
+		return err
+	}
+
+	var ids []uint64
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, diskSegmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, diskSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	head, err := b.readHead()
+	if err != nil {
+		b.log.Warnf("Ignoring disk buffer position: %v", err)
+		head = diskPosition{}
+	}
+
+	for _, id := range ids {
+		b.nextID = id + 1
+		if id < head.segment {
+			// Fully written before the last shutdown.
+			if err := os.Remove(b.segmentPath(id)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s, headRecord, err := b.scanSegment(id, head)
+		if err != nil {
+			return err
+		}
+		b.segments = append(b.segments, s)
+		b.size += s.size
+		b.count += s.records
+		if id == head.segment {
+			if headRecord < 0 {
+				b.log.Warnf("Disk buffer position %d is invalid for segment %d; rewinding", head.offset, id)
+				head.offset, headRecord = 0, 0
+			}
+			head.record = headRecord
+			b.count -= headRecord
+		}
+	}
+
+	// Segment ids must keep increasing, even if all segments were removed.
+	if b.nextID < head.segment {
+		b.nextID = head.segment
+	}
+
+	if len(b.segments) == 0 {
+		b.head = diskPosition{segment: b.nextID}
+	} else if head.segment != b.segments[0].id {
+		b.head = diskPosition{segment: b.segments[0].id}
+	} else {
+		b.head = head
+	}
+	b.compact()
+	return nil
+}
+
+// scanSegment counts the valid records in a segment, truncating it after the
+// last valid record.  It also returns the number of records before the head
+// offset, or -1 if the head offset is not a record boundary of the segment.
+func (b *DiskBuffer) scanSegment(id uint64, head diskPosition) (*diskSegment, int, error) {
+	f, err := os.OpenFile(b.segmentPath(id), os.O_RDWR, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	s := &diskSegment{id: id}
+	headRecord := -1
+	r := bufio.NewReader(f)
+	for {
+		if id == head.segment && s.size == head.offset {
+			headRecord = s.records
+		}
+
+		n, err := skipRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			b.log.Warnf("Truncating disk buffer segment %d at offset %d: %v", id, s.size, err)
+			if err := f.Truncate(s.size); err != nil {
+				return nil, 0, err
+			}
+			break
+		}
+		s.size += n
+		s.records++
+	}
+	return s, headRecord, nil
+}
+
+// readSegment decodes records of segment s starting at pos until out holds
+// limit metrics or the segment is exhausted.
+func (b *DiskBuffer) readSegment(
+	s *diskSegment,
+	pos diskPosition,
+	out []telegraf.Metric,
+	limit int,
+) ([]telegraf.Metric, diskPosition, error) {
+	if pos.record >= s.records {
+		return out, pos, nil
+	}
+
+	f, err := os.Open(b.segmentPath(s.id))
+	if err != nil {
+		return out, pos, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+		return out, pos, err
+	}
+
+	r := bufio.NewReader(f)
+	for len(out) < limit && pos.record < s.records {
+		payload, n, err := readRecord(r)
+		if err != nil {
+			return out, pos, err
+		}
+		pos.offset += n
+		pos.record++
+
+		m, err := decodeMetric(payload)
+		if err != nil {
+			b.log.Errorf("Dropping undecodable metric from disk buffer: %v", err)
+			AgentMetricsDropped.Incr(1)
+			b.MetricsDropped.Incr(1)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, pos, nil
+}
+
+// segmentFull returns true if a new segment must be started before the next
+// metric is written.
+func (b *DiskBuffer) segmentFull() bool {
+	active := b.activeSegment()
+	return active == nil || (active.records > 0 && active.size >= b.segmentSize)
+}
+
+// append writes the encoded metric to the active segment.  The record is
+// buffered until the segment is flushed.
+func (b *DiskBuffer) append(payload []byte) error {
+	active := b.activeSegment()
+
+	var header [diskRecordHeaderSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload))
+	if _, err := b.writer.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := b.writer.Write(payload); err != nil {
+		return err
+	}
+
+	n := int64(len(header) + len(payload))
+	active.size += n
+	active.records++
+	b.size += n
+	b.count++
+	return nil
+}
+
+// commit flushes the active segment and acknowledges the metrics written to
+// it since start.  The metrics are dropped if the flush fails.  It returns the
+// number of dropped metrics.
+func (b *DiskBuffer) commit(pending []telegraf.Metric, start diskSegment) int {
+	if len(pending) == 0 {
+		return 0
+	}
+	if err := b.writer.Flush(); err != nil {
+		b.log.Errorf("Flushing disk buffer: %v", err)
+		return b.discard(pending, start)
+	}
+
+	for _, m := range pending {
+		m.Accept()
+	}
+	return 0
+}
+
+// discard drops the metrics written to the active segment since start.  The
+// segment is truncated to start and closed, the next metric starts a new
+// segment.  It returns the number of dropped metrics.
+func (b *DiskBuffer) discard(pending []telegraf.Metric, start diskSegment) int {
+	active := b.activeSegment()
+	b.size -= active.size - start.size
+	b.count -= active.records - start.records
+	active.size = start.size
+	active.records = start.records
+
+	// The writer keeps failing once a write failed, the buffered records
+	// are thrown away with it.
+	b.file.Close()
+	b.file = nil
+	b.writer = nil
+	if err := os.Truncate(b.segmentPath(active.id), start.size); err != nil {
+		b.log.Errorf("Truncating disk buffer segment %d: %v", active.id, err)
+	}
+
+	for _, m := range pending {
+		b.metricDropped(m)
+	}
+	return len(pending)
+}
+
+// activeSegment returns the segment currently open for writing.
+func (b *DiskBuffer) activeSegment() *diskSegment {
+	if b.file == nil || len(b.segments) == 0 {
+		return nil
+	}
+	return b.segments[len(b.segments)-1]
+}
+
+// openSegment closes the active segment and starts a new one.
+func (b *DiskBuffer) openSegment() error {
+	if err := b.closeSegment(); err != nil {
+		return err
+	}
+
+	id := b.nextID
+	f, err := os.OpenFile(b.segmentPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+
+	b.nextID++
+	b.file = f
+	b.writer = bufio.NewWriter(f)
+	b.segments = append(b.segments, &diskSegment{id: id})
+	if b.count == 0 {
+		b.head = diskPosition{segment: id}
+	}
+	return nil
+}
+
+// closeSegment flushes and closes the active segment.
+func (b *DiskBuffer) closeSegment() error {
+	if b.file == nil {
+		return nil
+	}
+
+	err := b.writer.Flush()
+	if err == nil {
+		err = b.file.Sync()
+	}
+	if cerr := b.file.Close(); err == nil {
+		err = cerr
+	}
+	b.file = nil
+	b.writer = nil
+	return err
+}
+
+// enforceLimit drops the oldest segments until the log fits the size limit
+// and returns the number of dropped metrics.
+func (b *DiskBuffer) enforceLimit() int {
+	dropped := 0
+	for b.size > b.limit && len(b.segments) > 1 {
+		s := b.segments[0]
+		n := s.records
+		if s.id == b.head.segment {
+			n -= b.head.record
+		}
+
+		if err := os.Remove(b.segmentPath(s.id)); err != nil {
+			b.log.Errorf("Removing disk buffer segment %d: %v", s.id, err)
+			break
+		}
+		b.segments = b.segments[1:]
+		b.size -= s.size
+		b.count -= n
+		b.head = diskPosition{segment: b.segments[0].id}
+
+		dropped += n
+		AgentMetricsDropped.Incr(int64(n))
+		b.MetricsDropped.Incr(int64(n))
+	}
+	return dropped
+}
+
+// compact removes segments that have been fully written.
+func (b *DiskBuffer) compact() {
+	if b.count == 0 {
+		if err := b.closeSegment(); err != nil {
+			b.log.Errorf("Closing disk buffer segment: %v", err)
+		}
+		for _, s := range b.segments {
+			if err := os.Remove(b.segmentPath(s.id)); err != nil {
+				b.log.Errorf("Removing disk buffer segment %d: %v", s.id, err)
+			}
+		}
+		b.segments = b.segments[:0]
+		b.size = 0
+		b.head = diskPosition{segment: b.nextID}
+		return
+	}
+
+	for len(b.segments) > 1 {
+		s := b.segments[0]
+		if s.id > b.head.segment || (s.id == b.head.segment && b.head.record < s.records) {
+			break
+		}
+
+		if err := os.Remove(b.segmentPath(s.id)); err != nil {
+			b.log.Errorf("Removing disk buffer segment %d: %v", s.id, err)
+			break
+		}
+		b.segments = b.segments[1:]
+		b.size -= s.size
+		if s.id == b.head.segment {
+			b.head = diskPosition{segment: b.segments[0].id}
+		}
+	}
+}
+
+// distance returns the number of records between two positions.
+func (b *DiskBuffer) distance(from, to diskPosition) int {
+	if !b.after(to, from) {
+		return 0
+	}
+
+	n := 0
+	for _, s := range b.segments {
+		switch {
+		case s.id < from.segment || s.id > to.segment:
+		case s.id == from.segment && s.id == to.segment:
+			n += to.record - from.record
+		case s.id == from.segment:
+			n += s.records - from.record
+		case s.id == to.segment:
+			n += to.record
+		default:
+			n += s.records
+		}
+	}
+	return n
+}
+
+// after returns true if position a is newer than position b.
+func (b *DiskBuffer) after(x, y diskPosition) bool {
+	if x.segment != y.segment {
+		return x.segment > y.segment
+	}
+	return x.record > y.record
+}
+
+func (b *DiskBuffer) resetBatch() {
+	b.batchEnd = diskPosition{}
+	b.batchSize = 0
+}
+
+func (b *DiskBuffer) updateStats() {
+	b.BufferSize.Set(int64(b.count))
+	b.BufferSizeBytes.Set(b.size)
+}
+
+func (b *DiskBuffer) segmentPath(id uint64) string {
+	return filepath.Join(b.path, fmt.Sprintf("%020d%s", id, diskSegmentExt))
+}
+
+// readHead reads the persisted head position, a missing file is treated as
+// the start of the log.
+func (b *DiskBuffer) readHead() (diskPosition, error) {
+	var pos diskPosition
+	data, err := ioutil.ReadFile(filepath.Join(b.path, diskHeadFile))
+	if os.IsNotExist(err) {
+		return pos, nil
+	}
+	if err != nil {
+		return pos, err
+	}
+
+	_, err = fmt.Sscanf(string(data), "%d %d", &pos.segment, &pos.offset)
+	return pos, err
+}
+
+// writeHead atomically persists the head position.
+func (b *DiskBuffer) writeHead() error {
+	path := filepath.Join(b.path, diskHeadFile)
+	data := fmt.Sprintf("%d %d\n", b.head.segment, b.head.offset)
+	if err := ioutil.WriteFile(path+".tmp", []byte(data), 0640); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// readRecord reads a single record and returns its payload and size on disk.
+func readRecord(r *bufio.Reader) ([]byte, int64, error) {
+	var header [diskRecordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, 0, errDiskRecordCorrupt
+		}
+		return nil, 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > diskRecordMaxSize {
+		return nil, 0, errDiskRecordCorrupt
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, errDiskRecordCorrupt
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errDiskRecordCorrupt
+	}
+	return payload, int64(len(header)) + int64(length), nil
+}
+
+// skipRecord validates a single record and returns its size on disk.
+func skipRecord(r *bufio.Reader) (int64, error) {
+	_, n, err := readRecord(r)
+	return n, err
+}
+
+func encodeMetric(m telegraf.Metric) ([]byte, error) {
+	rec := diskRecord{
+		Name:   m.Name(),
+		Tags:   m.Tags(),
+		Fields: m.Fields(),
+		Time:   m.Time().UnixNano(),
+		Type:   m.Type(),
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&rec); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeMetric(payload []byte) (telegraf.Metric, error) {
+	var rec diskRecord
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+		return nil, err
+	}
+	return metric.New(rec.Name, rec.Tags, rec.Fields, time.Unix(0, rec.Time), rec.Type)
+}
@@ -0,0 +1,259 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, dir string, limit int64) *DiskBuffer {
+	b, err := NewDiskBuffer("test", "", dir, limit, testutil.Logger{})
+	require.NoError(t, err)
+	b.MetricsAdded.Set(0)
+	b.MetricsWritten.Set(0)
+	b.MetricsDropped.Set(0)
+	return b
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// diskRecordSize returns the size on disk of the metrics used by the size
+// limit tests, their timestamps all encode to the same length.
+func diskRecordSize(t *testing.T) int64 {
+	payload, err := encodeMetric(MetricTime(1000))
+	require.NoError(t, err)
+	return int64(diskRecordHeaderSize + len(payload))
+}
+
+func TestDiskBuffer_LenEmpty(t *testing.T) {
+	b := newTestDiskBuffer(t, tempDir(t), 1024*1024)
+
+	require.Equal(t, 0, b.Len())
+	require.Len(t, b.Batch(2), 0)
+}
+
+func TestDiskBuffer_BatchAccept(t *testing.T) {
+	b := newTestDiskBuffer(t, tempDir(t), 1024*1024)
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	require.Equal(t, 3, b.Len())
+
+	batch := b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(2)}, batch)
+	require.Equal(t, 3, b.Len())
+
+	b.Accept(batch)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsWritten.Get())
+
+	batch = b.Batch(2)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(3)}, batch)
+	b.Accept(batch)
+	require.Equal(t, 0, b.Len())
+}
+
+func TestDiskBuffer_RejectKeepsBatch(t *testing.T) {
+	b := newTestDiskBuffer(t, tempDir(t), 1024*1024)
+	b.Add(MetricTime(1), MetricTime(2))
+
+	batch := b.Batch(2)
+	b.Reject(batch)
+	require.Equal(t, 2, b.Len())
+	require.Equal(t, int64(0), b.MetricsDropped.Get())
+
+	b.Add(MetricTime(3))
+	batch = b.Batch(5)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(2), MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_DropRemovesBatch(t *testing.T) {
+	dir := tempDir(t)
+	b := newTestDiskBuffer(t, dir, 1024*1024)
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+
+	b.Drop(b.Batch(2))
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+	require.Equal(t, int64(0), b.MetricsWritten.Get())
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 1024*1024)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(3)}, b.Batch(5))
+}
+
+func TestDiskBuffer_RestoredAfterRestart(t *testing.T) {
+	dir := tempDir(t)
+	b := newTestDiskBuffer(t, dir, 1024*1024)
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	b.Accept(b.Batch(1))
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 1024*1024)
+	require.Equal(t, 2, b.Len())
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(2), MetricTime(3)}, b.Batch(5))
+}
+
+func TestDiskBuffer_RemovesWrittenSegments(t *testing.T) {
+	dir := tempDir(t)
+	b := newTestDiskBuffer(t, dir, 1024*1024)
+	b.Add(MetricTime(1), MetricTime(2))
+	b.Accept(b.Batch(2))
+	require.NoError(t, b.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+diskSegmentExt))
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+	require.Equal(t, int64(0), b.BufferSizeBytes.Get())
+
+	// New segments must not be mistaken for written ones after a restart.
+	b = newTestDiskBuffer(t, dir, 1024*1024)
+	b.Add(MetricTime(3))
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 1024*1024)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(3)}, b.Batch(5))
+}
+
+func TestDiskBuffer_SizeLimitDropsOldest(t *testing.T) {
+	recordSize := diskRecordSize(t)
+
+	// Two records per segment, eight segments.
+	b := newTestDiskBuffer(t, tempDir(t), 2*recordSize*diskSegmentsPerLimit)
+	for i := int64(0); i < 2*diskSegmentsPerLimit; i++ {
+		require.Equal(t, 0, b.Add(MetricTime(1000+i)))
+	}
+	require.Equal(t, 2*diskSegmentsPerLimit, b.Len())
+
+	dropped := b.Add(MetricTime(2000))
+	require.Equal(t, 2, dropped)
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+	require.Equal(t, 2*diskSegmentsPerLimit-1, b.Len())
+	require.LessOrEqual(t, b.BufferSizeBytes.Get(), b.BufferLimitBytes.Get())
+
+	batch := b.Batch(1)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(1002)}, batch)
+}
+
+func TestDiskBuffer_DropWhileBatchInFlight(t *testing.T) {
+	recordSize := diskRecordSize(t)
+
+	b := newTestDiskBuffer(t, tempDir(t), 2*recordSize*diskSegmentsPerLimit)
+	for i := int64(0); i < 2*diskSegmentsPerLimit; i++ {
+		b.Add(MetricTime(1000 + i))
+	}
+
+	batch := b.Batch(3)
+	b.Add(MetricTime(2000))
+	b.Accept(batch)
+
+	require.Equal(t, 2*diskSegmentsPerLimit-2, b.Len())
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(1003)}, b.Batch(1))
+}
+
+func TestDiskBuffer_PreservesTypes(t *testing.T) {
+	m, err := metric.New(
+		"cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{
+			"int":    int64(-42),
+			"uint":   uint64(42),
+			"float":  42.5,
+			"bool":   true,
+			"string": "value",
+		},
+		time.Unix(0, 1257894000000000123),
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+
+	b := newTestDiskBuffer(t, tempDir(t), 1024*1024)
+	b.Add(m.Copy())
+
+	batch := b.Batch(1)
+	require.Len(t, batch, 1)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, batch)
+	require.Equal(t, telegraf.Counter, batch[0].Type())
+}
+
+func TestDiskBuffer_TruncatesTornRecord(t *testing.T) {
+	dir := tempDir(t)
+	b := newTestDiskBuffer(t, dir, 1024*1024)
+	b.Add(MetricTime(1), MetricTime(2))
+	require.NoError(t, b.Close())
+
+	path := b.segmentPath(b.segments[0].id)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 42, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b = newTestDiskBuffer(t, dir, 1024*1024)
+	require.Equal(t, 2, b.Len())
+	b.Add(MetricTime(3))
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(2), MetricTime(3)}, b.Batch(5))
+}
+
+func TestDiskBuffer_AcceptsAddedMetric(t *testing.T) {
+	var accept int
+	mm := &MockMetric{
+		Metric: Metric(),
+		AcceptF: func() {
+			accept++
+		},
+	}
+
+	b := newTestDiskBuffer(t, tempDir(t), 1024*1024)
+	b.Add(mm)
+	require.Equal(t, 1, accept)
+}
+
+func TestDiskBuffer_DropsMetricsFailingToFlush(t *testing.T) {
+	var accept, reject int
+	mm := &MockMetric{
+		Metric: MetricTime(2),
+		AcceptF: func() {
+			accept++
+		},
+		RejectF: func() {
+			reject++
+		},
+	}
+
+	dir := tempDir(t)
+	b := newTestDiskBuffer(t, dir, 1024*1024)
+	b.Add(MetricTime(1))
+
+	// Closing the segment file makes the next flush fail.
+	require.NoError(t, b.file.Close())
+	require.Equal(t, 2, b.Add(mm, mm))
+	require.Equal(t, 0, accept)
+	require.Equal(t, 2, reject)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+
+	// The next metric is written to a new segment.
+	b.Add(MetricTime(3))
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(3)}, b.Batch(5))
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 1024*1024)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(3)}, b.Batch(5))
+}
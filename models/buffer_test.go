@@ -615,6 +615,24 @@ func TestBuffer_AcceptCallsMetricAccept(t *testing.T) {
 	require.Equal(t, 2, accept)
 }
 
+func TestBuffer_DropCallsMetricReject(t *testing.T) {
+	var reject int
+	mm := &MockMetric{
+		Metric: Metric(),
+		RejectF: func() {
+			reject++
+		},
+	}
+	b := setup(NewBuffer("test", "", 5))
+	b.Add(mm, mm, mm)
+	batch := b.Batch(2)
+	b.Drop(batch)
+	require.Equal(t, 2, reject)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+	require.Equal(t, int64(0), b.MetricsWritten.Get())
+}
+
 func TestBuffer_AddCallsMetricRejectWhenNoBatch(t *testing.T) {
 	var reject int
 	mm := &MockMetric{
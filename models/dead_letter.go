@@ -0,0 +1,77 @@
+package models
+
+import (
+	"bytes"
+	"os"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// DeadLetterSink receives the batches an output gave up writing.
+type DeadLetterSink interface {
+	// WriteDeadLetters takes ownership of the metrics.
+	WriteDeadLetters(metrics []telegraf.Metric) error
+}
+
+// DeadLetterFile appends dead letters to a local file in InfluxDB line
+// protocol.
+type DeadLetterFile struct {
+	sync.Mutex
+	path       string
+	serializer *influx.Serializer
+}
+
+// NewDeadLetterFile returns a DeadLetterFile writing to path.  The file is
+// created on the first write.
+func NewDeadLetterFile(path string) *DeadLetterFile {
+	serializer := influx.NewSerializer()
+	serializer.SetFieldTypeSupport(influx.UintSupport)
+	return &DeadLetterFile{
+		path:       path,
+		serializer: serializer,
+	}
+}
+
+// WriteDeadLetters appends the metrics to the file.
+func (f *DeadLetterFile) WriteDeadLetters(metrics []telegraf.Metric) error {
+	f.Lock()
+	defer f.Unlock()
+
+	var buf bytes.Buffer
+	serialized := metrics[:0]
+	for _, m := range metrics {
+		octets, err := f.serializer.Serialize(m)
+		if err != nil {
+			m.Reject()
+			continue
+		}
+		buf.Write(octets)
+		serialized = append(serialized, m)
+	}
+	metrics = serialized
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		rejectAll(metrics)
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		rejectAll(metrics)
+		return err
+	}
+
+	for _, m := range metrics {
+		m.Accept()
+	}
+	return nil
+}
+
+func rejectAll(metrics []telegraf.Metric) {
+	for _, m := range metrics {
+		m.Reject()
+	}
+}
@@ -482,6 +482,34 @@ func TestFilter_FilterTagsPassAndDrop(t *testing.T) {
 
 }
 
+func TestFilter_MetricPass(t *testing.T) {
+	f := Filter{
+		NamePass:   []string{"cpu", "mem"},
+		MetricPass: `tags.host =~ "^web" && fields.value > 10`,
+	}
+	require.NoError(t, f.Compile())
+
+	m := func(name, host string, value int) telegraf.Metric {
+		return testutil.MustMetric(name,
+			map[string]string{"host": host},
+			map[string]interface{}{"value": value},
+			time.Unix(0, 0))
+	}
+	require.True(t, f.Select(m("cpu", "web01", 42)))
+	require.True(t, f.Select(m("mem", "web02", 11)))
+	require.False(t, f.Select(m("cpu", "web01", 10)))
+	require.False(t, f.Select(m("cpu", "db01", 42)))
+	require.False(t, f.Select(m("disk", "web01", 42)))
+}
+
+func TestFilter_MetricPassError(t *testing.T) {
+	f := Filter{
+		MetricPass: `name == `,
+	}
+	require.EqualError(t, f.Compile(),
+		"Error compiling 'metricpass', column 9: unexpected end of expression")
+}
+
 func BenchmarkFilter(b *testing.B) {
 	tests := []struct {
 		name   string
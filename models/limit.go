@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Sample methods of a Limit.
+const (
+	SampleMethodRandom = "random"
+	SampleMethodHash   = "hash"
+)
+
+// Limit restricts the metrics passing an input or output by sampling and by
+// rate.
+type Limit struct {
+	// MaxMetricsPerSecond is the sustained rate of metrics, 0 is unlimited.
+	MaxMetricsPerSecond float64
+	// MetricBurst is the number of metrics passed at once after an idle
+	// period, it defaults to one second of metrics.
+	MetricBurst int
+
+	// SampleRate is the fraction of metrics passed, 0 passes all metrics.
+	SampleRate float64
+	// SampleMethod selects metrics at random, the default, or by the hash of
+	// their series.  A series is passed or dropped as a whole with hash
+	// sampling.
+	SampleMethod string
+}
+
+// Validate checks the settings.
+func (l *Limit) Validate() error {
+	if l.MaxMetricsPerSecond < 0 {
+		return fmt.Errorf("max_metrics_per_second must not be negative")
+	}
+	if l.MetricBurst < 0 {
+		return fmt.Errorf("metric_burst must not be negative")
+	}
+	if l.MetricBurst > 0 && l.MaxMetricsPerSecond == 0 {
+		return fmt.Errorf("metric_burst requires max_metrics_per_second")
+	}
+	if l.SampleRate < 0 || l.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+
+	switch l.SampleMethod {
+	case "", SampleMethodRandom, SampleMethodHash:
+	default:
+		return fmt.Errorf("unknown sample_method %q", l.SampleMethod)
+	}
+	return nil
+}
+
+// metricLimiter applies a Limit to metrics.
+type metricLimiter struct {
+	limit Limit
+
+	// The stats are only registered if sampling or the rate limit is
+	// enabled, they are nil otherwise.
+	MetricsSampledOut  selfstat.Stat
+	MetricsRateLimited selfstat.Stat
+
+	// token bucket of the rate limit
+	mu     sync.Mutex
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	now    func() time.Time
+	random func() float64
+}
+
+func newMetricLimiter(limit Limit, measurement string, tags map[string]string) *metricLimiter {
+	burst := float64(limit.MetricBurst)
+	if burst == 0 {
+		burst = math.Max(1, math.Ceil(limit.MaxMetricsPerSecond))
+	}
+
+	l := &metricLimiter{
+		limit:  limit,
+		burst:  burst,
+		tokens: burst,
+		now:    time.Now,
+		random: rand.Float64,
+	}
+	if l.sampling() {
+		l.MetricsSampledOut = selfstat.Register(
+			measurement,
+			"metrics_sampled_out",
+			tags,
+		)
+	}
+	if l.rateLimited() {
+		l.MetricsRateLimited = selfstat.Register(
+			measurement,
+			"metrics_rate_limited",
+			tags,
+		)
+	}
+	return l
+}
+
+// sampling returns true if only a fraction of the metrics is passed.
+func (l *metricLimiter) sampling() bool {
+	return l.limit.SampleRate > 0 && l.limit.SampleRate < 1
+}
+
+// rateLimited returns true if the rate of metrics is limited.
+func (l *metricLimiter) rateLimited() bool {
+	return l.limit.MaxMetricsPerSecond > 0
+}
+
+// Accept returns true if the metric is selected by the sampling and within
+// the rate limit.  Metrics not accepted are counted by the reason they are
+// dropped.
+func (l *metricLimiter) Accept(metric telegraf.Metric) bool {
+	if !l.sample(metric) {
+		l.MetricsSampledOut.Incr(1)
+		return false
+	}
+	if !l.allow() {
+		l.MetricsRateLimited.Incr(1)
+		return false
+	}
+	return true
+}
+
+func (l *metricLimiter) sample(metric telegraf.Metric) bool {
+	if !l.sampling() {
+		return true
+	}
+
+	if l.limit.SampleMethod == SampleMethodHash {
+		// Use the upper 53 bits of the series hash as a fraction of 1.
+		return float64(mixHash(metric.HashID())>>11)/(1<<53) < l.limit.SampleRate
+	}
+	return l.random() < l.limit.SampleRate
+}
+
+func (l *metricLimiter) allow() bool {
+	if !l.rateLimited() {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last).Seconds()
+		l.tokens = math.Min(l.burst, l.tokens+elapsed*l.limit.MaxMetricsPerSecond)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// mixHash spreads the bits of a hash, the FNV hash of series differing only in
+// the last characters of a tag varies little in the upper bits.  This is the
+// finalizer of MurmurHash3.
+func mixHash(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+		err   string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "valid",
+			limit: Limit{MaxMetricsPerSecond: 0.5, MetricBurst: 10, SampleRate: 0.1, SampleMethod: "hash"},
+		},
+		{
+			name:  "negative rate",
+			limit: Limit{MaxMetricsPerSecond: -1},
+			err:   "max_metrics_per_second must not be negative",
+		},
+		{
+			name:  "burst without rate",
+			limit: Limit{MetricBurst: 10},
+			err:   "metric_burst requires max_metrics_per_second",
+		},
+		{
+			name:  "sample rate too large",
+			limit: Limit{SampleRate: 1.5},
+			err:   "sample_rate must be between 0 and 1",
+		},
+		{
+			name:  "unknown sample method",
+			limit: Limit{SampleRate: 0.5, SampleMethod: "first"},
+			err:   `unknown sample_method "first"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limit.Validate()
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestMetricLimiterRate(t *testing.T) {
+	limiter := newMetricLimiter(Limit{MaxMetricsPerSecond: 2, MetricBurst: 3},
+		"write", map[string]string{"output": "TestMetricLimiterRate"})
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	accepted := func(n int) int {
+		var count int
+		for i := 0; i < n; i++ {
+			if limiter.Accept(testutil.TestMetric(1)) {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The burst is passed at once, then the rate refills the bucket.
+	require.Equal(t, 3, accepted(10))
+	now = now.Add(time.Second)
+	require.Equal(t, 2, accepted(10))
+	now = now.Add(time.Hour)
+	require.Equal(t, 3, accepted(10))
+
+	require.Equal(t, int64(22), limiter.MetricsRateLimited.Get())
+	require.Nil(t, limiter.MetricsSampledOut)
+}
+
+func TestMetricLimiterDefaultBurst(t *testing.T) {
+	limiter := newMetricLimiter(Limit{MaxMetricsPerSecond: 0.5},
+		"write", map[string]string{"output": "TestMetricLimiterDefaultBurst"})
+	now := time.Unix(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	require.True(t, limiter.Accept(testutil.TestMetric(1)))
+	require.False(t, limiter.Accept(testutil.TestMetric(1)))
+	now = now.Add(2 * time.Second)
+	require.True(t, limiter.Accept(testutil.TestMetric(1)))
+}
+
+func TestMetricLimiterRandomSampling(t *testing.T) {
+	limiter := newMetricLimiter(Limit{SampleRate: 0.25},
+		"gather", map[string]string{"input": "TestMetricLimiterRandomSampling"})
+	values := []float64{0.1, 0.3, 0.2, 0.9}
+	limiter.random = func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+
+	var accepted []bool
+	for i := 0; i < 4; i++ {
+		accepted = append(accepted, limiter.Accept(testutil.TestMetric(i)))
+	}
+	require.Equal(t, []bool{true, false, true, false}, accepted)
+	require.Equal(t, int64(2), limiter.MetricsSampledOut.Get())
+}
+
+func TestMetricLimiterHashSampling(t *testing.T) {
+	limiter := newMetricLimiter(Limit{SampleRate: 0.5, SampleMethod: SampleMethodHash},
+		"gather", map[string]string{"input": "TestMetricLimiterHashSampling"})
+
+	series := func(i int) telegraf.Metric {
+		return testutil.MustMetric("cpu",
+			map[string]string{"cpu": fmt.Sprintf("cpu%d", i)},
+			map[string]interface{}{"value": i},
+			time.Unix(int64(i), 0))
+	}
+
+	var passed int
+	for i := 0; i < 1000; i++ {
+		accepted := limiter.Accept(series(i))
+		if accepted {
+			passed++
+		}
+		// All metrics of a series get the same result.
+		require.Equal(t, accepted, limiter.Accept(series(i)))
+	}
+	require.InDelta(t, 500, passed, 100)
+}
+
+func TestRunningInputLimit(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:  "TestRunningInputLimit",
+		Limit: Limit{MaxMetricsPerSecond: 1, MetricBurst: 2},
+	})
+	ri.limiter.now = func() time.Time { return time.Unix(0, 0) }
+
+	var gathered int
+	for i := 0; i < 5; i++ {
+		if ri.MakeMetric(testutil.TestMetric(i)) != nil {
+			gathered++
+		}
+	}
+	require.Equal(t, 2, gathered)
+	require.Equal(t, int64(2), ri.MetricsGathered.Get())
+	require.Equal(t, int64(3), ri.limiter.MetricsRateLimited.Get())
+}
+
+func TestRunningOutputLimit(t *testing.T) {
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{
+		Name:  "TestRunningOutputLimit",
+		Limit: Limit{MaxMetricsPerSecond: 1, MetricBurst: 2},
+	}, 1000, 10000)
+	ro.limiter.now = func() time.Time { return time.Unix(0, 0) }
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Equal(t, 2, ro.BufferLength())
+	require.Equal(t, int64(3), ro.limiter.MetricsRateLimited.Get())
+
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 2)
+}
+
+func TestMetricLimiterRegistersOnlyEnabledStats(t *testing.T) {
+	fields := func(input string) map[string]interface{} {
+		for _, m := range selfstat.Metrics() {
+			if tag, _ := m.GetTag("input"); m.Name() == "internal_gather" && tag == input {
+				return m.Fields()
+			}
+		}
+		return nil
+	}
+
+	NewRunningInput(&testInput{}, &InputConfig{Name: "TestLimitUnlimited"})
+	require.NotContains(t, fields("TestLimitUnlimited"), "metrics_sampled_out")
+	require.NotContains(t, fields("TestLimitUnlimited"), "metrics_rate_limited")
+
+	NewRunningInput(&testInput{}, &InputConfig{
+		Name:  "TestLimitSampled",
+		Limit: Limit{SampleRate: 0.5},
+	})
+	require.Contains(t, fields("TestLimitSampled"), "metrics_sampled_out")
+	require.NotContains(t, fields("TestLimitSampled"), "metrics_rate_limited")
+}
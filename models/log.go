@@ -1,22 +1,39 @@
 package models
 
 import (
-	"log"
+	"fmt"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/wlog"
 )
 
 // Logger defines a logging structure for plugins.
 type Logger struct {
 	OnErrs []func()
 	Name   string // Name is the plugin name, will be printed in the `[]`.
+	// Level overrides the log level of the agent for the plugin, the agent
+	// level is used if it is zero.
+	Level wlog.Level
+
+	pluginType  string
+	pluginName  string
+	alias       string
+	mu          sync.Mutex
+	lastErr     string
+	lastErrTime time.Time
 }
 
 // NewLogger creates a new logger instance
 func NewLogger(pluginType, name, alias string) *Logger {
 	return &Logger{
-		Name: logName(pluginType, name, alias),
+		Name:       logName(pluginType, name, alias),
+		pluginType: pluginType,
+		pluginName: name,
+		alias:      alias,
 	}
 }
 
@@ -25,12 +42,29 @@ func (l *Logger) OnErr(f func()) {
 	l.OnErrs = append(l.OnErrs, f)
 }
 
+// LastError returns the most recent error message and the time it was
+// logged.
+func (l *Logger) LastError() (string, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastErr, l.lastErrTime
+}
+
+func (l *Logger) setLastError(msg string) {
+	l.mu.Lock()
+	l.lastErr = msg
+	l.lastErrTime = time.Now()
+	l.mu.Unlock()
+}
+
 // Errorf logs an error message, patterned after log.Printf.
 func (l *Logger) Errorf(format string, args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
-	log.Printf("E! ["+l.Name+"] "+format, args...)
+	msg := fmt.Sprintf(format, args...)
+	l.setLastError(msg)
+	l.print(wlog.ERROR, msg, args)
 }
 
 // Error logs an error message, patterned after log.Print.
@@ -38,37 +72,76 @@ func (l *Logger) Error(args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
-	log.Print(append([]interface{}{"E! [" + l.Name + "] "}, args...)...)
+	msg := fmt.Sprint(args...)
+	l.setLastError(msg)
+	l.print(wlog.ERROR, msg, args)
 }
 
 // Debugf logs a debug message, patterned after log.Printf.
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	log.Printf("D! ["+l.Name+"] "+format, args...)
+	if l.enabled(wlog.DEBUG) {
+		l.print(wlog.DEBUG, fmt.Sprintf(format, args...), args)
+	}
 }
 
 // Debug logs a debug message, patterned after log.Print.
 func (l *Logger) Debug(args ...interface{}) {
-	log.Print(append([]interface{}{"D! [" + l.Name + "] "}, args...)...)
+	if l.enabled(wlog.DEBUG) {
+		l.print(wlog.DEBUG, fmt.Sprint(args...), args)
+	}
 }
 
 // Warnf logs a warning message, patterned after log.Printf.
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	log.Printf("W! ["+l.Name+"] "+format, args...)
+	l.print(wlog.WARN, fmt.Sprintf(format, args...), args)
 }
 
 // Warn logs a warning message, patterned after log.Print.
 func (l *Logger) Warn(args ...interface{}) {
-	log.Print(append([]interface{}{"W! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.WARN, fmt.Sprint(args...), args)
 }
 
 // Infof logs an information message, patterned after log.Printf.
 func (l *Logger) Infof(format string, args ...interface{}) {
-	log.Printf("I! ["+l.Name+"] "+format, args...)
+	l.print(wlog.INFO, fmt.Sprintf(format, args...), args)
 }
 
 // Info logs an information message, patterned after log.Print.
 func (l *Logger) Info(args ...interface{}) {
-	log.Print(append([]interface{}{"I! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.INFO, fmt.Sprint(args...), args)
+}
+
+// enabled returns true if messages of the level are logged.
+func (l *Logger) enabled(level wlog.Level) bool {
+	if l.Level != 0 {
+		return level >= l.Level
+	}
+	return level >= wlog.LogLevel()
+}
+
+// print writes the message if its level is enabled, the first error of the
+// arguments is logged as the error of the message.
+func (l *Logger) print(level wlog.Level, msg string, args []interface{}) {
+	if !l.enabled(level) {
+		return
+	}
+
+	e := &logger.Entry{
+		Time:       time.Now(),
+		Level:      level,
+		Source:     l.Name,
+		PluginType: l.pluginType,
+		PluginName: l.pluginName,
+		Alias:      l.alias,
+		Message:    msg,
+	}
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			e.Error = err.Error()
+			break
+		}
+	}
+	logger.Print(e)
 }
 
 // logName returns the log-friendly name/type.
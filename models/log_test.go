@@ -1,9 +1,14 @@
 package models
 
 import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
 	"testing"
 
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,3 +27,45 @@ func TestErrorCounting(t *testing.T) {
 
 	require.Equal(t, int64(2), reg.Get())
 }
+
+func TestLastError(t *testing.T) {
+	l := NewLogger("inputs", "test", "")
+	msg, ts := l.LastError()
+	require.Equal(t, "", msg)
+	require.True(t, ts.IsZero())
+
+	l.Errorf("failed %d times", 3)
+	msg, ts = l.LastError()
+	require.Equal(t, "failed 3 times", msg)
+	require.False(t, ts.IsZero())
+
+	l.Error("failed again")
+	msg, _ = l.LastError()
+	require.Equal(t, "failed again", msg)
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	l := NewLogger("inputs", "test", "")
+	l.Debugf("hidden %d", 1)
+	l.Info("shown")
+	require.Equal(t, "I! [inputs.test] shown\n", buf.String())
+
+	buf.Reset()
+	l.Level = wlog.DEBUG
+	l.Debugf("shown %d", 2)
+	require.Equal(t, "D! [inputs.test] shown 2\n", buf.String())
+
+	buf.Reset()
+	l.Level = wlog.ERROR
+	l.Warn("hidden")
+	l.Errorf("failed: %v", errors.New("timeout"))
+	require.Equal(t, "E! [inputs.test] failed: timeout\n", buf.String())
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 )
 
 type RunningAggregator struct {
@@ -31,6 +32,7 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 
 	aggErrorsRegister := selfstat.Register("aggregate", "errors", tags)
 	logger := NewLogger("aggregators", config.Name, config.Alias)
+	logger.Level = config.LogLevel
 	logger.OnErr(func() {
 		aggErrorsRegister.Incr(1)
 	})
@@ -68,6 +70,8 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 type AggregatorConfig struct {
 	Name         string
 	Alias        string
+	ID           string
+	LogLevel     wlog.Level
 	DropOriginal bool
 	Period       time.Duration
 	Delay        time.Duration
@@ -1,10 +1,12 @@
 package models
 
 import (
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 )
 
 var (
@@ -18,9 +20,14 @@ type RunningInput struct {
 
 	log         telegraf.Logger
 	defaultTags map[string]string
+	limiter     *metricLimiter
+	seriesGuard *seriesGuard
 
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+
+	statusMutex sync.Mutex
+	lastGather  time.Time
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
@@ -31,6 +38,7 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 
 	inputErrorsRegister := selfstat.Register("gather", "errors", tags)
 	logger := NewLogger("inputs", config.Name, config.Alias)
+	logger.Level = config.LogLevel
 	logger.OnErr(func() {
 		inputErrorsRegister.Incr(1)
 		GlobalGatherErrors.Incr(1)
@@ -50,7 +58,9 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"gather_time_ns",
 			tags,
 		),
-		log: logger,
+		limiter:     newMetricLimiter(config.Limit, "gather", tags),
+		seriesGuard: newSeriesGuard(config.SeriesLimit, "gather", tags, logger),
+		log:         logger,
 	}
 }
 
@@ -58,6 +68,8 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 type InputConfig struct {
 	Name             string
 	Alias            string
+	ID               string
+	LogLevel         wlog.Level
 	Interval         time.Duration
 	CollectionJitter time.Duration
 	Precision        time.Duration
@@ -67,6 +79,8 @@ type InputConfig struct {
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+	Limit             Limit
+	SeriesLimit       SeriesLimit
 }
 
 func (r *RunningInput) metricFiltered(metric telegraf.Metric) {
@@ -107,6 +121,16 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 		return nil
 	}
 
+	if !r.limiter.Accept(metric) {
+		metric.Drop()
+		return nil
+	}
+
+	if r.seriesGuard != nil && !r.seriesGuard.Apply(metric) {
+		metric.Drop()
+		return nil
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return m
@@ -117,9 +141,26 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	err := r.Input.Gather(acc)
 	elapsed := time.Since(start)
 	r.GatherTime.Incr(elapsed.Nanoseconds())
+
+	r.statusMutex.Lock()
+	r.lastGather = time.Now()
+	r.statusMutex.Unlock()
 	return err
 }
 
+// Status returns the time of the last gather and the last error logged by
+// the input.
+func (r *RunningInput) Status() PluginStatus {
+	r.statusMutex.Lock()
+	status := PluginStatus{LastRun: r.lastGather}
+	r.statusMutex.Unlock()
+
+	if l, ok := r.log.(*Logger); ok {
+		status.setError(l.LastError())
+	}
+	return status
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
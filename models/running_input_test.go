@@ -287,6 +287,19 @@ func TestMetricErrorCounters(t *testing.T) {
 	require.GreaterOrEqual(t, int64(1), GlobalGatherErrors.Get())
 }
 
+func TestRunningInputStatus(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{Name: "TestRunningInputStatus"})
+	require.Equal(t, PluginStatus{}, ri.Status())
+
+	require.NoError(t, ri.Gather(&testutil.Accumulator{}))
+	ri.Log().Errorf("failed to connect to %s", "localhost")
+
+	status := ri.Status()
+	require.False(t, status.LastRun.IsZero())
+	require.Equal(t, "failed to connect to localhost", status.LastError)
+	require.False(t, status.LastErrorTime.Before(status.LastRun))
+}
+
 type testInput struct{}
 
 func (t *testInput) Description() string                   { return "" }
@@ -1,12 +1,15 @@
 package models
 
 import (
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 )
 
 const (
@@ -15,19 +18,51 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
+
+	// Default size limit of the disk buffer.
+	DEFAULT_BUFFER_SIZE_LIMIT = 256 * 1024 * 1024
+
+	// Default upper bound of the retry backoff.
+	DEFAULT_RETRY_MAX_BACKOFF = 5 * time.Minute
+)
+
+// Buffer strategies of an output.
+const (
+	BufferStrategyMemory = "memory"
+	BufferStrategyDisk   = "disk"
 )
 
 // OutputConfig containing name and filter
 type OutputConfig struct {
-	Name   string
-	Alias  string
-	Filter Filter
+	Name     string
+	Alias    string
+	ID       string
+	LogLevel wlog.Level
+	Filter   Filter
+	Limit    Limit
+
+	SeriesLimit SeriesLimit
 
 	FlushInterval     time.Duration
 	FlushJitter       time.Duration
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	BufferStrategy  string
+	BufferDirectory string
+	BufferSizeLimit int64
+
+	// Retry policy for failed writes.
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+	RetryJitter         time.Duration
+	RetryMaxAttempts    int
+
+	// Destination for batches that are not retried, either the alias or
+	// name of another output or a local file.
+	DeadLetterOutput string
+	DeadLetterFile   string
+
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
@@ -45,14 +80,31 @@ type RunningOutput struct {
 	MetricBatchSize   int
 
 	MetricsFiltered selfstat.Stat
+	MetricsRejected selfstat.Stat
 	WriteTime       selfstat.Stat
 
 	BatchReady chan time.Time
 
-	buffer *Buffer
-	log    telegraf.Logger
+	// DeadLetter receives the batches that are not retried.
+	DeadLetter DeadLetterSink
+	// DeadLetterOnly is set on outputs that are the dead letter sink of
+	// another output, they receive no other metrics.
+	DeadLetterOnly bool
+
+	buffer      MetricBuffer
+	bufferTaken bool // the buffer was taken over by another output
+	log         telegraf.Logger
+	limiter     *metricLimiter
+	seriesGuard *seriesGuard
 
 	aggMutex sync.Mutex
+
+	retryMutex sync.Mutex
+	attempts   int       // failed attempts to write the oldest batch
+	nextRetry  time.Time // no writes are attempted before this time
+
+	statusMutex sync.Mutex
+	status      PluginStatus // outcome of the last write
 }
 
 func NewRunningOutput(
@@ -69,6 +121,7 @@ func NewRunningOutput(
 
 	writeErrorsRegister := selfstat.Register("write", "errors", tags)
 	logger := NewLogger("outputs", config.Name, config.Alias)
+	logger.Level = config.LogLevel
 	logger.OnErr(func() {
 		writeErrorsRegister.Incr(1)
 	})
@@ -88,7 +141,6 @@ func NewRunningOutput(
 	}
 
 	ro := &RunningOutput{
-		buffer:            NewBuffer(config.Name, config.Alias, bufferLimit),
 		BatchReady:        make(chan time.Time, 1),
 		Output:            output,
 		Config:            config,
@@ -99,12 +151,29 @@ func NewRunningOutput(
 			"metrics_filtered",
 			tags,
 		),
+		MetricsRejected: selfstat.Register(
+			"write",
+			"metrics_rejected",
+			tags,
+		),
 		WriteTime: selfstat.RegisterTiming(
 			"write",
 			"write_time_ns",
 			tags,
 		),
-		log: logger,
+		limiter:     newMetricLimiter(config.Limit, "write", tags),
+		seriesGuard: newSeriesGuard(config.SeriesLimit, "write", tags, logger),
+		log:         logger,
+	}
+
+	// The disk buffer is opened by Init, an output is only created and
+	// never initialized when it is kept running on reload.
+	if config.BufferStrategy != BufferStrategyDisk {
+		ro.buffer = NewBuffer(config.Name, config.Alias, bufferLimit)
+	}
+
+	if config.DeadLetterFile != "" {
+		ro.DeadLetter = NewDeadLetterFile(config.DeadLetterFile)
 	}
 
 	return ro
@@ -119,7 +188,28 @@ func (ro *RunningOutput) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
 
+// Init opens the disk buffer of the output, if it uses one, and initializes
+// the output plugin.
 func (r *RunningOutput) Init() error {
+	if r.Config.BufferStrategy == BufferStrategyDisk && r.buffer == nil {
+		sizeLimit := r.Config.BufferSizeLimit
+		if sizeLimit == 0 {
+			sizeLimit = DEFAULT_BUFFER_SIZE_LIMIT
+		}
+		buffer, err := NewDiskBuffer(r.Config.Name, r.Config.Alias,
+			r.Config.BufferDirectory, sizeLimit, r.log)
+		if err != nil {
+			return fmt.Errorf("creating disk buffer: %w", err)
+		}
+		r.buffer = buffer
+	}
+	return r.InitPlugin()
+}
+
+// InitPlugin initializes the output plugin without opening the disk buffer.
+// The buffer must be taken over from another output with TakeBuffer before
+// metrics are added.
+func (r *RunningOutput) InitPlugin() error {
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -130,6 +220,25 @@ func (r *RunningOutput) Init() error {
 	return nil
 }
 
+// TakeBuffer moves the buffer of another output to this output, the other
+// output keeps its buffer open when it is closed.  It is used on reload to
+// hand the disk buffer of an output over to the output replacing it, a disk
+// buffer cannot be opened twice.  The other output must not be written to
+// anymore.
+func (r *RunningOutput) TakeBuffer(other *RunningOutput) {
+	r.buffer = other.buffer
+	other.bufferTaken = true
+
+	if b, ok := r.buffer.(*DiskBuffer); ok {
+		limit := r.Config.BufferSizeLimit
+		if limit == 0 {
+			limit = DEFAULT_BUFFER_SIZE_LIMIT
+		}
+		dropped := b.SetLimit(limit)
+		atomic.AddInt64(&r.droppedMetrics, int64(dropped))
+	}
+}
+
 // AddMetric adds a metric to the output.
 //
 // Takes ownership of metric
@@ -145,6 +254,16 @@ func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
 		return
 	}
 
+	if !ro.limiter.Accept(metric) {
+		metric.Drop()
+		return
+	}
+
+	if ro.seriesGuard != nil && !ro.seriesGuard.Apply(metric) {
+		metric.Drop()
+		return
+	}
+
 	if output, ok := ro.Output.(telegraf.AggregatingOutput); ok {
 		ro.aggMutex.Lock()
 		output.Add(metric)
@@ -177,9 +296,30 @@ func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
 	}
 }
 
+// WriteDeadLetters adds metrics another output gave up on to the buffer.
+// The metrics are not filtered or modified.
+//
+// Takes ownership of metrics
+func (ro *RunningOutput) WriteDeadLetters(metrics []telegraf.Metric) error {
+	dropped := ro.buffer.Add(metrics...)
+	atomic.AddInt64(&ro.droppedMetrics, int64(dropped))
+	return nil
+}
+
 // Write writes all metrics to the output, stopping when all have been sent on
-// or error.
+// or error.  Nothing is written while waiting for the retry backoff to
+// expire.
 func (ro *RunningOutput) Write() error {
+	if until, ok := ro.backingOff(); ok {
+		ro.log.Debugf("Retrying write in %s", time.Until(until).Round(time.Millisecond))
+		return nil
+	}
+	return ro.Flush()
+}
+
+// Flush writes all metrics to the output like Write, ignoring any retry
+// backoff in effect.
+func (ro *RunningOutput) Flush() error {
 	if output, ok := ro.Output.(telegraf.AggregatingOutput); ok {
 		ro.aggMutex.Lock()
 		metrics := output.Push()
@@ -202,16 +342,22 @@ func (ro *RunningOutput) Write() error {
 
 		err := ro.write(batch)
 		if err != nil {
-			ro.buffer.Reject(batch)
-			return err
+			if err := ro.writeFailed(batch, err); err != nil {
+				return err
+			}
+			continue
 		}
-		ro.buffer.Accept(batch)
+		ro.writeSucceeded(batch)
 	}
 	return nil
 }
 
 // WriteBatch writes a single batch of metrics to the output.
 func (ro *RunningOutput) WriteBatch() error {
+	if _, ok := ro.backingOff(); ok {
+		return nil
+	}
+
 	batch := ro.buffer.Batch(ro.MetricBatchSize)
 	if len(batch) == 0 {
 		return nil
@@ -219,20 +365,134 @@ func (ro *RunningOutput) WriteBatch() error {
 
 	err := ro.write(batch)
 	if err != nil {
+		return ro.writeFailed(batch, err)
+	}
+	ro.writeSucceeded(batch)
+
+	return nil
+}
+
+func (ro *RunningOutput) writeSucceeded(batch []telegraf.Metric) {
+	ro.retryMutex.Lock()
+	ro.attempts = 0
+	ro.nextRetry = time.Time{}
+	ro.retryMutex.Unlock()
+
+	ro.buffer.Accept(batch)
+}
+
+// writeFailed applies the retry policy to a batch that failed to write.  The
+// batch is returned to the buffer and the error is passed on, unless the
+// error is permanent or the batch ran out of attempts; then the batch is
+// removed from the buffer and handed to the dead letter sink.
+func (ro *RunningOutput) writeFailed(batch []telegraf.Metric, err error) error {
+	ro.retryMutex.Lock()
+	ro.attempts++
+	attempts := ro.attempts
+
+	permanent := false
+	if c, ok := ro.Output.(telegraf.ErrorClassifier); ok {
+		permanent = c.IsPermanentError(err)
+	}
+	exhausted := ro.Config.RetryMaxAttempts > 0 && attempts >= ro.Config.RetryMaxAttempts
+
+	if !permanent && !exhausted {
+		ro.nextRetry = time.Now().Add(ro.backoff(attempts))
+		ro.retryMutex.Unlock()
+
 		ro.buffer.Reject(batch)
 		return err
 	}
-	ro.buffer.Accept(batch)
 
+	ro.attempts = 0
+	ro.nextRetry = time.Time{}
+	ro.retryMutex.Unlock()
+
+	reason := "permanent error"
+	if !permanent {
+		reason = fmt.Sprintf("%d attempts", attempts)
+	}
+	ro.reject(batch, reason, err)
 	return nil
 }
 
+// reject removes a batch from the buffer and passes a copy to the dead
+// letter sink if there is one.  The batch is dropped if there is no sink or
+// the sink fails.
+func (ro *RunningOutput) reject(batch []telegraf.Metric, reason string, err error) {
+	ro.MetricsRejected.Incr(int64(len(batch)))
+
+	if ro.DeadLetter == nil {
+		ro.log.Errorf("Dropping batch of %d metrics after %s: %v", len(batch), reason, err)
+		ro.buffer.Drop(batch)
+		return
+	}
+
+	ro.log.Errorf("Moving batch of %d metrics to dead letters after %s: %v", len(batch), reason, err)
+	dead := make([]telegraf.Metric, 0, len(batch))
+	for _, m := range batch {
+		dead = append(dead, m.Copy())
+	}
+	if err := ro.DeadLetter.WriteDeadLetters(dead); err != nil {
+		ro.log.Errorf("Writing dead letters: %v", err)
+		ro.buffer.Drop(batch)
+		return
+	}
+	ro.buffer.Accept(batch)
+}
+
+// backoff returns the time to wait after the given number of failed
+// attempts.
+func (ro *RunningOutput) backoff(attempts int) time.Duration {
+	initial := ro.Config.RetryInitialBackoff
+	if initial <= 0 {
+		return 0
+	}
+
+	max := ro.Config.RetryMaxBackoff
+	if max <= 0 {
+		max = DEFAULT_RETRY_MAX_BACKOFF
+	}
+
+	backoff := initial
+	for i := 1; i < attempts && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff + internal.RandomDuration(ro.Config.RetryJitter)
+}
+
+// backingOff returns the time of the next retry if it is in the future.
+func (ro *RunningOutput) backingOff() (time.Time, bool) {
+	ro.retryMutex.Lock()
+	defer ro.retryMutex.Unlock()
+
+	return ro.nextRetry, time.Now().Before(ro.nextRetry)
+}
+
 // Close closes the output
 func (r *RunningOutput) Close() {
 	err := r.Output.Close()
 	if err != nil {
 		r.log.Errorf("Error closing output: %v", err)
 	}
+
+	r.CloseBuffer()
+}
+
+// CloseBuffer closes the buffer of the output without closing the output
+// plugin, it is used for outputs that were never connected.  A buffer handed
+// over to another output is not closed.
+func (r *RunningOutput) CloseBuffer() {
+	if r.buffer == nil || r.bufferTaken {
+		return
+	}
+	err := r.buffer.Close()
+	if err != nil {
+		r.log.Errorf("Error closing buffer: %v", err)
+	}
 }
 
 func (r *RunningOutput) write(metrics []telegraf.Metric) error {
@@ -247,14 +507,39 @@ func (r *RunningOutput) write(metrics []telegraf.Metric) error {
 	elapsed := time.Since(start)
 	r.WriteTime.Incr(elapsed.Nanoseconds())
 
+	r.statusMutex.Lock()
+	if err == nil {
+		r.status.LastRun = time.Now()
+	} else {
+		r.status.setError(err.Error(), time.Now())
+	}
+	r.statusMutex.Unlock()
+
 	if err == nil {
 		r.log.Debugf("Wrote batch of %d metrics in %s", len(metrics), elapsed)
 	}
 	return err
 }
 
+// Status returns the time of the last successful write and the last write
+// error or error logged by the output.
+func (r *RunningOutput) Status() PluginStatus {
+	r.statusMutex.Lock()
+	status := r.status
+	r.statusMutex.Unlock()
+
+	if l, ok := r.log.(*Logger); ok {
+		status.setError(l.LastError())
+	}
+	return status
+}
+
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
+	if size, limit, ok := r.BufferSize(); ok {
+		r.log.Debugf("Buffer fullness: %d / %d bytes (%d metrics)", size, limit, nBuffer)
+		return
+	}
 	r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
 }
 
@@ -265,3 +550,14 @@ func (r *RunningOutput) Log() telegraf.Logger {
 func (r *RunningOutput) BufferLength() int {
 	return r.buffer.Len()
 }
+
+// BufferSize returns the size in bytes of the disk buffer and its size limit,
+// ok is false when the output uses the memory buffer.
+func (r *RunningOutput) BufferSize() (size, limit int64, ok bool) {
+	b, ok := r.buffer.(*DiskBuffer)
+	if !ok {
+		return 0, 0, false
+	}
+	size, limit = b.Size()
+	return size, limit, true
+}
@@ -2,6 +2,9 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -326,6 +329,208 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutputStatus(t *testing.T) {
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 4, 12)
+	require.Equal(t, PluginStatus{}, ro.Status())
+
+	ro.AddMetric(testutil.TestMetric(101, "metric1"))
+	require.Error(t, ro.Write())
+	status := ro.Status()
+	require.True(t, status.LastRun.IsZero())
+	require.Equal(t, "Failed Write!", status.LastError)
+	require.False(t, status.LastErrorTime.IsZero())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	status = ro.Status()
+	require.False(t, status.LastRun.IsZero())
+	require.Equal(t, "Failed Write!", status.LastError)
+}
+
+func TestRunningOutputDiskBufferSurvivesRestart(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferStrategy:  BufferStrategyDisk,
+		BufferDirectory: tempDir(t),
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.Init())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	ro.Close()
+
+	m.failWrite = false
+	ro = NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.Init())
+	require.Equal(t, 5, ro.BufferLength())
+
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+	require.Equal(t, 0, ro.BufferLength())
+}
+
+func TestRunningOutputTakeBuffer(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferStrategy:  BufferStrategyDisk,
+		BufferDirectory: tempDir(t),
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.Init())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	next := &mockOutput{}
+	nextRo := NewRunningOutput("test", next, conf, 4, 12)
+	require.NoError(t, nextRo.InitPlugin())
+	nextRo.TakeBuffer(ro)
+	ro.Close()
+
+	require.Equal(t, 5, nextRo.BufferLength())
+	require.NoError(t, nextRo.Write())
+	require.Len(t, next.Metrics(), 5)
+	nextRo.Close()
+}
+
+func TestRunningOutputDiskBufferInitError(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		BufferStrategy: BufferStrategyDisk,
+	}
+
+	ro := NewRunningOutput("test", &mockOutput{}, conf, 4, 12)
+	require.Error(t, ro.Init())
+}
+
+func TestRunningOutputRetryBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:              Filter{},
+		RetryInitialBackoff: time.Hour,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	// Writes are skipped until the backoff expires.
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.NoError(t, ro.WriteBatch())
+	require.Len(t, m.Metrics(), 0)
+
+	// Flushing ignores the backoff.
+	require.NoError(t, ro.Flush())
+	require.Len(t, m.Metrics(), 5)
+}
+
+func TestRunningOutputRetryBackoffGrows(t *testing.T) {
+	conf := &OutputConfig{
+		RetryInitialBackoff: time.Second,
+		RetryMaxBackoff:     5 * time.Second,
+	}
+	ro := NewRunningOutput("test", &mockOutput{}, conf, 1000, 10000)
+
+	require.Equal(t, time.Second, ro.backoff(1))
+	require.Equal(t, 2*time.Second, ro.backoff(2))
+	require.Equal(t, 4*time.Second, ro.backoff(3))
+	require.Equal(t, 5*time.Second, ro.backoff(4))
+	require.Equal(t, 5*time.Second, ro.backoff(100))
+}
+
+func TestRunningOutputMaxAttemptsDeadLetter(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:           Filter{},
+		RetryMaxAttempts: 2,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	dlm := &mockOutput{}
+	dl := NewRunningOutput("dead", dlm, &OutputConfig{Filter: Filter{}}, 1000, 10000)
+	ro.DeadLetter = dl
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Equal(t, 5, ro.BufferLength())
+
+	// The second failure exhausts the attempts.
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+	require.Equal(t, int64(5), ro.MetricsRejected.Get())
+
+	require.NoError(t, dl.Write())
+	testutil.RequireMetricsEqual(t, first5, dlm.Metrics())
+}
+
+func TestRunningOutputPermanentErrorDeadLetterFile(t *testing.T) {
+	path := filepath.Join(tempDir(t), "dead.out")
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		DeadLetterFile: path,
+	}
+
+	m := &permanentErrorOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 5, strings.Count(string(data), "\n"))
+}
+
+func TestRunningOutputPermanentErrorDropsBatch(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	var accept, reject int
+	mm := &MockMetric{
+		Metric:  Metric(),
+		AcceptF: func() { accept++ },
+		RejectF: func() { reject++ },
+	}
+
+	ro := NewRunningOutput("test", &permanentErrorOutput{}, conf, 1000, 10000)
+	buffer := setup(ro.buffer.(*Buffer))
+
+	for i := 0; i < 5; i++ {
+		ro.AddMetric(mm)
+	}
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+	require.Equal(t, int64(5), buffer.MetricsDropped.Get())
+	require.Equal(t, int64(0), buffer.MetricsWritten.Get())
+	require.Equal(t, 0, accept)
+	require.Equal(t, 5, reject)
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -494,6 +699,7 @@ func TestInternalMetrics(t *testing.T) {
 				"metrics_added":    0,
 				"metrics_dropped":  0,
 				"metrics_filtered": 0,
+				"metrics_rejected": 0,
 				"metrics_written":  0,
 				"write_time_ns":    0,
 			},
@@ -587,3 +793,15 @@ func (m *perfOutput) Write(metrics []telegraf.Metric) error {
 	}
 	return nil
 }
+
+type permanentErrorOutput struct {
+	mockOutput
+}
+
+func (m *permanentErrorOutput) Write(metrics []telegraf.Metric) error {
+	return fmt.Errorf("bad request")
+}
+
+func (m *permanentErrorOutput) IsPermanentError(err error) bool {
+	return true
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 )
 
 type RunningProcessor struct {
@@ -22,10 +23,12 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 
 // FilterConfig containing a name and filter
 type ProcessorConfig struct {
-	Name   string
-	Alias  string
-	Order  int64
-	Filter Filter
+	Name     string
+	Alias    string
+	ID       string
+	LogLevel wlog.Level
+	Order    int64
+	Filter   Filter
 }
 
 func NewRunningProcessor(processor telegraf.StreamingProcessor, config *ProcessorConfig) *RunningProcessor {
@@ -36,6 +39,7 @@ func NewRunningProcessor(processor telegraf.StreamingProcessor, config *Processo
 
 	processErrorsRegister := selfstat.Register("process", "errors", tags)
 	logger := NewLogger("processors", config.Name, config.Alias)
+	logger.Level = config.LogLevel
 	logger.OnErr(func() {
 		processErrorsRegister.Incr(1)
 	})
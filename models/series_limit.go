@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Policies applied to new series above a SeriesLimit.
+const (
+	SeriesPolicyDrop     = "drop"
+	SeriesPolicyStripTag = "strip_tag"
+	SeriesPolicyAlert    = "alert"
+)
+
+// Default duration of the window series are counted in.
+const DEFAULT_SERIES_WINDOW = time.Hour
+
+// SeriesLimit restricts the number of unique series, a name and tag set,
+// passing an input or output within a window of time.
+type SeriesLimit struct {
+	// Limit is the maximum number of series per window, 0 is unlimited.
+	Limit int
+	// Window is the duration the series are counted for, the count starts
+	// over at the end of each window.
+	Window time.Duration
+	// Policy is applied to the metrics of new series once the limit is
+	// reached, it defaults to dropping the metrics.
+	Policy string
+	// StripTags are the tags removed from the metrics of new series by the
+	// strip_tag policy.
+	StripTags []string
+}
+
+// Validate checks the settings.
+func (s *SeriesLimit) Validate() error {
+	if s.Limit < 0 {
+		return fmt.Errorf("series_limit must not be negative")
+	}
+	if s.Window < 0 {
+		return fmt.Errorf("series_window must not be negative")
+	}
+
+	switch s.Policy {
+	case "", SeriesPolicyDrop, SeriesPolicyAlert:
+	case SeriesPolicyStripTag:
+		if len(s.StripTags) == 0 {
+			return fmt.Errorf("series_strip_tags is required by series_policy %q", s.Policy)
+		}
+	default:
+		return fmt.Errorf("unknown series_policy %q", s.Policy)
+	}
+	return nil
+}
+
+// seriesGuard applies a SeriesLimit to metrics.
+type seriesGuard struct {
+	limit  SeriesLimit
+	policy string
+	window time.Duration
+	log    telegraf.Logger
+
+	SeriesCardinality selfstat.Stat
+	SeriesLimited     selfstat.Stat
+
+	mu        sync.Mutex
+	series    map[uint64]struct{}
+	windowEnd time.Time
+	warned    bool
+
+	now func() time.Time
+}
+
+// newSeriesGuard returns a guard for the limit, or nil if the limit is not
+// set.  The series are only tracked when a limit is set.
+func newSeriesGuard(limit SeriesLimit, measurement string, tags map[string]string, log telegraf.Logger) *seriesGuard {
+	if limit.Limit == 0 {
+		return nil
+	}
+
+	g := &seriesGuard{
+		limit:  limit,
+		policy: limit.Policy,
+		window: limit.Window,
+		log:    log,
+		SeriesCardinality: selfstat.Register(
+			measurement,
+			"series_cardinality",
+			tags,
+		),
+		SeriesLimited: selfstat.Register(
+			measurement,
+			"series_limited",
+			tags,
+		),
+		series: make(map[uint64]struct{}),
+		now:    time.Now,
+	}
+	if g.policy == "" {
+		g.policy = SeriesPolicyDrop
+	}
+	if g.window == 0 {
+		g.window = DEFAULT_SERIES_WINDOW
+	}
+	return g
+}
+
+// Apply counts the series of the metric and applies the policy to metrics of
+// new series above the limit.  It returns false if the metric is dropped.
+func (g *seriesGuard) Apply(metric telegraf.Metric) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if !now.Before(g.windowEnd) {
+		g.series = make(map[uint64]struct{}, len(g.series))
+		g.windowEnd = now.Add(g.window)
+		g.warned = false
+	}
+
+	id := metric.HashID()
+	if _, ok := g.series[id]; ok {
+		return true
+	}
+	if len(g.series) < g.limit.Limit {
+		g.add(id)
+		return true
+	}
+
+	g.SeriesLimited.Incr(1)
+	if !g.warned {
+		g.warned = true
+		g.log.Warnf("Series limit of %d reached, policy %q is applied to new series until %s",
+			g.limit.Limit, g.policy, g.windowEnd.Format(time.RFC3339))
+	}
+
+	// Series above the limit are not tracked so the memory used stays
+	// bounded by the limit, their metrics are only counted as limited.
+	switch g.policy {
+	case SeriesPolicyStripTag:
+		for _, tag := range g.limit.StripTags {
+			metric.RemoveTag(tag)
+		}
+		return true
+	case SeriesPolicyAlert:
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *seriesGuard) add(id uint64) {
+	g.series[id] = struct{}{}
+	g.SeriesCardinality.Set(int64(len(g.series)))
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func seriesMetric(i int) telegraf.Metric {
+	return testutil.MustMetric("http",
+		map[string]string{"host": "a", "request_id": fmt.Sprintf("%d", i)},
+		map[string]interface{}{"value": i},
+		time.Unix(0, 0))
+}
+
+func newTestSeriesGuard(t *testing.T, limit SeriesLimit) (*seriesGuard, *time.Time) {
+	require.NoError(t, limit.Validate())
+	g := newSeriesGuard(limit, "gather", map[string]string{"input": t.Name()}, testutil.Logger{})
+	now := time.Unix(0, 0)
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestSeriesLimitValidate(t *testing.T) {
+	require.NoError(t, (&SeriesLimit{}).Validate())
+	require.NoError(t, (&SeriesLimit{Limit: 10, Policy: "alert"}).Validate())
+	require.EqualError(t, (&SeriesLimit{Limit: -1}).Validate(),
+		"series_limit must not be negative")
+	require.EqualError(t, (&SeriesLimit{Limit: 10, Policy: "strip_tag"}).Validate(),
+		`series_strip_tags is required by series_policy "strip_tag"`)
+	require.EqualError(t, (&SeriesLimit{Limit: 10, Policy: "ignore"}).Validate(),
+		`unknown series_policy "ignore"`)
+}
+
+func TestSeriesGuardDisabled(t *testing.T) {
+	require.Nil(t, newSeriesGuard(SeriesLimit{}, "gather", nil, testutil.Logger{}))
+}
+
+func TestSeriesGuardDrop(t *testing.T) {
+	g, now := newTestSeriesGuard(t, SeriesLimit{Limit: 2, Window: time.Minute})
+
+	require.True(t, g.Apply(seriesMetric(1)))
+	require.True(t, g.Apply(seriesMetric(2)))
+	require.False(t, g.Apply(seriesMetric(3)))
+	// Known series still pass.
+	require.True(t, g.Apply(seriesMetric(1)))
+	require.Equal(t, int64(2), g.SeriesCardinality.Get())
+	require.Equal(t, int64(1), g.SeriesLimited.Get())
+
+	// The count starts over with the next window.
+	*now = now.Add(time.Minute)
+	require.True(t, g.Apply(seriesMetric(3)))
+	require.Equal(t, int64(1), g.SeriesCardinality.Get())
+}
+
+func TestSeriesGuardStripTag(t *testing.T) {
+	g, _ := newTestSeriesGuard(t, SeriesLimit{
+		Limit:     1,
+		Policy:    SeriesPolicyStripTag,
+		StripTags: []string{"request_id"},
+	})
+
+	m := seriesMetric(1)
+	require.True(t, g.Apply(m))
+	require.True(t, m.HasTag("request_id"))
+
+	for i := 2; i < 10; i++ {
+		m := seriesMetric(i)
+		require.True(t, g.Apply(m))
+		require.False(t, m.HasTag("request_id"))
+		require.True(t, m.HasTag("host"))
+	}
+	require.Equal(t, int64(1), g.SeriesCardinality.Get())
+	require.Equal(t, int64(8), g.SeriesLimited.Get())
+	require.Len(t, g.series, 1)
+}
+
+func TestSeriesGuardAlert(t *testing.T) {
+	g, _ := newTestSeriesGuard(t, SeriesLimit{Limit: 1, Policy: SeriesPolicyAlert})
+
+	for i := 0; i < 5; i++ {
+		require.True(t, g.Apply(seriesMetric(i)))
+	}
+	require.Equal(t, int64(1), g.SeriesCardinality.Get())
+	require.Equal(t, int64(4), g.SeriesLimited.Get())
+}
+
+func TestSeriesGuardBoundedAboveLimit(t *testing.T) {
+	for _, policy := range []string{SeriesPolicyAlert, SeriesPolicyStripTag} {
+		t.Run(policy, func(t *testing.T) {
+			g, _ := newTestSeriesGuard(t, SeriesLimit{
+				Limit:     10,
+				Policy:    policy,
+				StripTags: []string{"request_id"},
+			})
+
+			for i := 0; i < 1000; i++ {
+				require.True(t, g.Apply(seriesMetric(i)))
+			}
+			require.Len(t, g.series, 10)
+			require.Equal(t, int64(10), g.SeriesCardinality.Get())
+			require.Equal(t, int64(990), g.SeriesLimited.Get())
+		})
+	}
+}
+
+func TestRunningOutputSeriesLimit(t *testing.T) {
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{
+		Name:        "TestRunningOutputSeriesLimit",
+		SeriesLimit: SeriesLimit{Limit: 3},
+	}, 1000, 10000)
+
+	for _, metric := range append(first5, next5...) {
+		ro.AddMetric(metric)
+	}
+	require.Equal(t, 3, ro.BufferLength())
+	require.Equal(t, int64(3), ro.seriesGuard.SeriesCardinality.Get())
+	require.Equal(t, int64(7), ro.seriesGuard.SeriesLimited.Get())
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// PluginStatus describes the most recent activity of a running plugin.
+type PluginStatus struct {
+	// LastRun is when the plugin last completed a gather or write.
+	LastRun time.Time
+	// LastError is the message of the last error and LastErrorTime the time
+	// it occurred.
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// setError records the error if it is newer than the one already set.
+func (s *PluginStatus) setError(msg string, t time.Time) {
+	if t.After(s.LastErrorTime) {
+		s.LastError = msg
+		s.LastErrorTime = t
+	}
+}
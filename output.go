@@ -27,3 +27,12 @@ type AggregatingOutput interface {
 	// Reset signals the the aggregator period is completed.
 	Reset()
 }
+
+// ErrorClassifier may be implemented by an Output that can tell write errors
+// which will never succeed, such as a rejected request, apart from transient
+// ones.  Batches failing with a permanent error are not retried.
+type ErrorClassifier interface {
+	// IsPermanentError returns true if writing the same batch again would
+	// fail with the same error.
+	IsPermanentError(err error) bool
+}
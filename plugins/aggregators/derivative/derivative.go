@@ -0,0 +1,247 @@
+package derivative
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Derivative struct {
+	Suffix      string `toml:"suffix"`
+	Counter     bool   `toml:"counter"`
+	CounterWrap int    `toml:"counter_wrap"`
+	MaxRollOver int    `toml:"max_roll_over"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	cache map[uint64]*aggregate
+}
+
+type aggregate struct {
+	name     string
+	tags     map[string]string
+	fields   map[string]*derivative
+	updated  bool
+	rollOver int
+}
+
+// derivative holds the change of a field over the samples of the period.
+// The last sample of a period is the start of the next one, so that no
+// change between two periods is lost.
+type derivative struct {
+	start time.Time
+	last  time.Time
+	value interface{}
+	delta float64
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.  Enable it to
+  ## only keep the rates of the raw counters.
+  drop_original = false
+
+  ## Suffix appended to the name of each field for the derivative.
+  # suffix = "_rate"
+
+  ## Treat the fields as monotonically increasing counters.  A decrease of the
+  ## value is handled as counter reset or wraparound, so that the rate is
+  ## never negative.  If false the derivative of the fields is computed as is.
+  # counter = false
+
+  ## Bit width at which the counters wrap around, either 32 or 64.  A decrease
+  ## of a counter whose previous value was in the upper half of the range is
+  ## handled as wraparound, any other decrease as reset.  If 0 all decreases
+  ## are handled as reset.
+  # counter_wrap = 0
+
+  ## The last sample of a period is used as start of the next period.  Series
+  ## without new samples are kept for this many periods.
+  # max_roll_over = 10
+`
+
+func (d *Derivative) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Derivative) Description() string {
+	return "Calculates the per second rate of change of each numeric field."
+}
+
+func (d *Derivative) Init() error {
+	switch d.CounterWrap {
+	case 0, 32, 64:
+	default:
+		return fmt.Errorf("invalid counter_wrap %d, must be 0, 32 or 64", d.CounterWrap)
+	}
+	if d.MaxRollOver < 0 {
+		return fmt.Errorf("max_roll_over must not be negative")
+	}
+
+	d.cache = make(map[uint64]*aggregate)
+	return nil
+}
+
+func (d *Derivative) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := d.cache[id]
+	if !ok {
+		a = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*derivative),
+		}
+		d.cache[id] = a
+	}
+	a.updated = true
+
+	for _, field := range in.FieldList() {
+		if !isNumeric(field.Value) {
+			continue
+		}
+
+		f, ok := a.fields[field.Key]
+		if !ok {
+			a.fields[field.Key] = &derivative{
+				start: in.Time(),
+				last:  in.Time(),
+				value: field.Value,
+			}
+			continue
+		}
+
+		if !in.Time().After(f.last) {
+			d.Log.Debugf("Ignoring out of order sample of field %q", field.Key)
+			continue
+		}
+
+		f.delta += d.difference(f.value, field.Value)
+		f.value = field.Value
+		f.last = in.Time()
+	}
+}
+
+func (d *Derivative) Push(acc telegraf.Accumulator) {
+	for _, a := range d.cache {
+		fields := make(map[string]interface{}, len(a.fields))
+		for k, f := range a.fields {
+			if !f.last.After(f.start) {
+				continue
+			}
+			fields[k+d.Suffix] = f.delta / f.last.Sub(f.start).Seconds()
+		}
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags)
+		}
+	}
+}
+
+func (d *Derivative) Reset() {
+	for id, a := range d.cache {
+		if a.updated {
+			a.rollOver = 0
+		} else {
+			a.rollOver++
+		}
+		if a.rollOver > d.MaxRollOver {
+			delete(d.cache, id)
+			continue
+		}
+
+		a.updated = false
+		for _, f := range a.fields {
+			f.start = f.last
+			f.delta = 0
+		}
+	}
+}
+
+// difference returns the change from the previous to the current value.  For
+// counters a decrease is handled as wraparound or reset.
+func (d *Derivative) difference(previous, current interface{}) float64 {
+	if !d.Counter {
+		return toFloat(current) - toFloat(previous)
+	}
+
+	prev, pok := toUint(previous)
+	cur, cok := toUint(current)
+	if !pok || !cok {
+		// Floating point or negative counter values
+		p, c := toFloat(previous), toFloat(current)
+		if c < p {
+			return c
+		}
+		return c - p
+	}
+
+	if cur >= prev {
+		return float64(cur - prev)
+	}
+
+	switch d.CounterWrap {
+	case 32:
+		if prev <= math.MaxUint32 && prev > math.MaxUint32/2 {
+			return float64((cur - prev) & math.MaxUint32)
+		}
+	case 64:
+		if prev > math.MaxUint64/2 {
+			return float64(cur - prev)
+		}
+	}
+
+	// Counter reset, the counter restarted from zero.
+	return float64(cur)
+}
+
+func isNumeric(v interface{}) bool {
+	switch v.(type) {
+	case int64, uint64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+// toUint returns the value of non-negative integers.
+func toUint(v interface{}) (uint64, bool) {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("derivative", func() telegraf.Aggregator {
+		return &Derivative{
+			Suffix:      "_rate",
+			MaxRollOver: 10,
+		}
+	})
+}
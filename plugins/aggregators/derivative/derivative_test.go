@@ -0,0 +1,207 @@
+package derivative
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1600000000, 0)
+
+func newDerivative() *Derivative {
+	return &Derivative{
+		Suffix:      "_rate",
+		MaxRollOver: 10,
+		Log:         testutil.Logger{},
+	}
+}
+
+func sample(value interface{}, seconds int) telegraf.Metric {
+	return testutil.MustMetric("net",
+		map[string]string{"interface": "eth0"},
+		map[string]interface{}{"bytes": value},
+		start.Add(time.Duration(seconds)*time.Second),
+	)
+}
+
+func rates(values ...float64) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, len(values))
+	for _, v := range values {
+		metrics = append(metrics, testutil.MustMetric("net",
+			map[string]string{"interface": "eth0"},
+			map[string]interface{}{"bytes_rate": v},
+			time.Unix(0, 0),
+		))
+	}
+	return metrics
+}
+
+func TestInvalidConfig(t *testing.T) {
+	d := newDerivative()
+	d.CounterWrap = 16
+	require.Error(t, d.Init())
+
+	d = newDerivative()
+	d.MaxRollOver = -1
+	require.Error(t, d.Init())
+}
+
+func TestDerivative(t *testing.T) {
+	d := newDerivative()
+	require.NoError(t, d.Init())
+
+	d.Add(sample(int64(100), 0))
+	d.Add(sample(int64(50), 5))
+	d.Add(sample(int64(300), 10))
+	d.Add(testutil.MustMetric("net",
+		map[string]string{"interface": "eth0"},
+		map[string]interface{}{"name": "eth0"},
+		start,
+	))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	testutil.RequireMetricsEqual(t, rates(20), acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGaugeMayBeNegative(t *testing.T) {
+	d := newDerivative()
+	require.NoError(t, d.Init())
+
+	d.Add(sample(10.0, 0))
+	d.Add(sample(5.0, 10))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	testutil.RequireMetricsEqual(t, rates(-0.5), acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestRollOver(t *testing.T) {
+	d := newDerivative()
+	d.MaxRollOver = 1
+	require.NoError(t, d.Init())
+
+	// A single sample does not produce a rate.
+	acc := testutil.Accumulator{}
+	d.Add(sample(uint64(100), 0))
+	d.Push(&acc)
+	d.Reset()
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	// The last sample of the previous period is the start of the next one.
+	d.Add(sample(uint64(200), 10))
+	d.Push(&acc)
+	d.Reset()
+	testutil.RequireMetricsEqual(t, rates(10), acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// The series is kept for max_roll_over periods without samples.
+	acc.ClearMetrics()
+	d.Push(&acc)
+	d.Reset()
+	require.Len(t, d.cache, 1)
+	d.Push(&acc)
+	d.Reset()
+	require.Len(t, d.cache, 0)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestOutOfOrder(t *testing.T) {
+	d := newDerivative()
+	require.NoError(t, d.Init())
+
+	d.Add(sample(int64(100), 10))
+	d.Add(sample(int64(0), 5))
+	d.Add(sample(int64(200), 20))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	testutil.RequireMetricsEqual(t, rates(10), acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestCounter(t *testing.T) {
+	tests := []struct {
+		name     string
+		wrap     int
+		values   []interface{}
+		expected float64
+	}{
+		{
+			name:     "increasing",
+			values:   []interface{}{int64(100), int64(200), int64(300)},
+			expected: 10,
+		},
+		{
+			name:     "reset",
+			values:   []interface{}{int64(100), int64(200), int64(50)},
+			expected: 7.5,
+		},
+		{
+			name:     "reset without wrap",
+			values:   []interface{}{uint64(math.MaxUint32 - 49), uint64(50)},
+			expected: 5,
+		},
+		{
+			name:     "wrap 32 bits",
+			wrap:     32,
+			values:   []interface{}{uint64(math.MaxUint32 - 49), uint64(50)},
+			expected: 10,
+		},
+		{
+			name:     "reset in lower half of 32 bits",
+			wrap:     32,
+			values:   []interface{}{int64(1000), int64(100)},
+			expected: 10,
+		},
+		{
+			name:     "32 bit wrap of larger value is reset",
+			wrap:     32,
+			values:   []interface{}{uint64(math.MaxUint64 - 49), uint64(50)},
+			expected: 5,
+		},
+		{
+			name:     "wrap 64 bits",
+			wrap:     64,
+			values:   []interface{}{uint64(math.MaxUint64 - 49), uint64(50)},
+			expected: 10,
+		},
+		{
+			name:     "float reset",
+			values:   []interface{}{100.0, 200.0, 50.0},
+			expected: 7.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDerivative()
+			d.Counter = true
+			d.CounterWrap = tt.wrap
+			require.NoError(t, d.Init())
+
+			for i, v := range tt.values {
+				d.Add(sample(v, i*10))
+			}
+
+			acc := testutil.Accumulator{}
+			d.Push(&acc)
+			testutil.RequireMetricsEqual(t, rates(tt.expected), acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+		})
+	}
+}
+
+func TestSuffix(t *testing.T) {
+	d := newDerivative()
+	d.Suffix = "_per_second"
+	require.NoError(t, d.Init())
+
+	d.Add(sample(int64(0), 0))
+	d.Add(sample(int64(10), 10))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, map[string]interface{}{"bytes_per_second": 1.0}, acc.Metrics[0].Fields)
+}
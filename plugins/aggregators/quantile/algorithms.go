@@ -0,0 +1,34 @@
+package quantile
+
+import (
+	"github.com/caio/go-tdigest"
+)
+
+type algorithm interface {
+	Add(value float64) error
+	Quantile(q float64) float64
+}
+
+type algorithmFactory interface {
+	New() (algorithm, error)
+}
+
+// tdigestFactory creates t-digests, the accuracy is best for the extreme
+// quantiles and controlled by the compression.
+type tdigestFactory struct {
+	compression uint32
+}
+
+func (f *tdigestFactory) New() (algorithm, error) {
+	return tdigest.New(tdigest.Compression(f.compression))
+}
+
+// ddsketchFactory creates DDSketches with a guaranteed relative accuracy.
+type ddsketchFactory struct {
+	relativeAccuracy float64
+	maxBins          int
+}
+
+func (f *ddsketchFactory) New() (algorithm, error) {
+	return newDDSketch(f.relativeAccuracy, f.maxBins)
+}
@@ -0,0 +1,175 @@
+package quantile
+
+import (
+	"errors"
+	"math"
+)
+
+// ddsketch is a quantile sketch with relative-error guarantees as described
+// in "DDSketch: A Fast and Fully-Mergeable Quantile Sketch with Relative-Error
+// Guarantees" (Masson, Rim, Lee; VLDB 2019).
+//
+// Values are mapped to logarithmically sized buckets, so that the value
+// returned for any quantile is within the relative accuracy alpha of the
+// exact value.  The number of buckets per sign is limited to maxBins, if the
+// limit is reached the buckets of the values with the smallest magnitude are
+// collapsed and only the quantiles of those values lose their guarantee.
+type ddsketch struct {
+	gamma        float64
+	multiplier   float64
+	minIndexable float64
+
+	positive  *ddsketchStore
+	negative  *ddsketchStore
+	zeroCount uint64
+}
+
+func newDDSketch(relativeAccuracy float64, maxBins int) (*ddsketch, error) {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		return nil, errors.New("relative accuracy must be between 0 and 1")
+	}
+	if maxBins < 1 {
+		return nil, errors.New("maximum number of bins must be positive")
+	}
+
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &ddsketch{
+		gamma:        gamma,
+		multiplier:   1 / math.Log(gamma),
+		minIndexable: math.SmallestNonzeroFloat64 * gamma,
+		positive:     &ddsketchStore{maxBins: maxBins},
+		negative:     &ddsketchStore{maxBins: maxBins},
+	}, nil
+}
+
+// Add adds the value to the sketch.
+func (s *ddsketch) Add(value float64) error {
+	switch {
+	case math.IsNaN(value) || math.IsInf(value, 0):
+		return errors.New("value must be finite")
+	case value > s.minIndexable:
+		s.positive.add(s.index(value))
+	case value < -s.minIndexable:
+		s.negative.add(s.index(-value))
+	default:
+		s.zeroCount++
+	}
+	return nil
+}
+
+// Quantile returns the estimated value at quantile q in the range [0,1].
+func (s *ddsketch) Quantile(q float64) float64 {
+	count := s.count()
+	if count == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+
+	rank := uint64(q * float64(count-1))
+
+	// Negative values are ordered from the highest magnitude down.
+	if rank < s.negative.count {
+		index := s.negative.indexAtRank(s.negative.count - 1 - rank)
+		return -s.value(index)
+	}
+	rank -= s.negative.count
+
+	if rank < s.zeroCount {
+		return 0
+	}
+	rank -= s.zeroCount
+
+	return s.value(s.positive.indexAtRank(rank))
+}
+
+func (s *ddsketch) count() uint64 {
+	return s.negative.count + s.zeroCount + s.positive.count
+}
+
+// index returns the bucket of the positive value, bucket i holds the values
+// in (gamma^(i-1), gamma^i].
+func (s *ddsketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) * s.multiplier))
+}
+
+// value returns the representative of the bucket, it is within the relative
+// accuracy of all values in the bucket.
+func (s *ddsketch) value(index int) float64 {
+	return 2 * math.Pow(s.gamma, float64(index)) / (1 + s.gamma)
+}
+
+// ddsketchStore counts the values of a contiguous range of buckets.
+type ddsketchStore struct {
+	bins    []uint64
+	offset  int
+	count   uint64
+	maxBins int
+}
+
+func (s *ddsketchStore) add(index int) {
+	if len(s.bins) == 0 {
+		s.bins = []uint64{0}
+		s.offset = index
+	}
+
+	highest := s.offset + len(s.bins) - 1
+	switch {
+	case index > highest:
+		// The buckets falling out of the range are merged before the range
+		// is extended, so it never grows beyond the limit.
+		if lowest := index - s.maxBins + 1; lowest > s.offset {
+			s.collapse(lowest)
+			highest = s.offset + len(s.bins) - 1
+		}
+		s.bins = append(s.bins, make([]uint64, index-highest)...)
+	case index < s.offset:
+		// Values below the range are collapsed into the lowest bucket if
+		// the range is at its limit.
+		if lowest := highest - s.maxBins + 1; index < lowest {
+			index = lowest
+		}
+		if index < s.offset {
+			grow := make([]uint64, s.offset-index, s.offset-index+len(s.bins))
+			s.bins = append(grow, s.bins...)
+			s.offset = index
+		}
+	}
+
+	s.bins[index-s.offset]++
+	s.count++
+}
+
+// collapse merges the buckets below the lowest bucket into it, the lowest
+// bucket is the first bucket of the range afterwards.
+func (s *ddsketchStore) collapse(lowest int) {
+	n := lowest - s.offset
+	if n >= len(s.bins) {
+		var sum uint64
+		for _, c := range s.bins {
+			sum += c
+		}
+		s.bins = append(s.bins[:0], sum)
+		s.offset = lowest
+		return
+	}
+
+	var sum uint64
+	for _, c := range s.bins[:n] {
+		sum += c
+	}
+	s.bins = s.bins[n:]
+	s.bins[0] += sum
+	s.offset = lowest
+}
+
+// indexAtRank returns the bucket holding the value of the given rank in
+// ascending order.
+func (s *ddsketchStore) indexAtRank(rank uint64) int {
+	var n uint64
+	for i, c := range s.bins {
+		n += c
+		if n > rank {
+			return s.offset + i
+		}
+	}
+	return s.offset + len(s.bins) - 1
+}
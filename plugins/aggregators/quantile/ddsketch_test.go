@@ -0,0 +1,114 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDDSketchRelativeAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+
+	values := make([]float64, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		values = append(values, math.Exp(rng.NormFloat64()*3)-5)
+	}
+
+	sketch, err := newDDSketch(0.01, 2048)
+	require.NoError(t, err)
+	for _, v := range values {
+		require.NoError(t, sketch.Add(v))
+	}
+
+	sort.Float64s(values)
+	for _, q := range []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 0.999, 1} {
+		expected := values[int(q*float64(len(values)-1))]
+		actual := sketch.Quantile(q)
+		require.InDelta(t, expected, actual, math.Abs(expected)*0.01, "quantile %v", q)
+	}
+}
+
+func TestDDSketchZeroAndSign(t *testing.T) {
+	sketch, err := newDDSketch(0.01, 2048)
+	require.NoError(t, err)
+	for _, v := range []float64{-10, 0, 0, 10} {
+		require.NoError(t, sketch.Add(v))
+	}
+
+	require.InDelta(t, -10, sketch.Quantile(0), 0.1)
+	require.Equal(t, 0.0, sketch.Quantile(0.5))
+	require.InDelta(t, 10, sketch.Quantile(1), 0.1)
+}
+
+func TestDDSketchEmpty(t *testing.T) {
+	sketch, err := newDDSketch(0.01, 2048)
+	require.NoError(t, err)
+	require.True(t, math.IsNaN(sketch.Quantile(0.5)))
+}
+
+func TestDDSketchCollapse(t *testing.T) {
+	sketch, err := newDDSketch(0.01, 16)
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, sketch.Add(float64(i+1)))
+	}
+	require.LessOrEqual(t, len(sketch.positive.bins), 16)
+	require.Equal(t, uint64(1000), sketch.count())
+
+	// The highest values keep their accuracy.
+	require.InDelta(t, 1000, sketch.Quantile(1), 10)
+	require.InDelta(t, 999, sketch.Quantile(0.999), 10)
+
+	// Values lower than the range end up in the lowest bucket.
+	require.NoError(t, sketch.Add(0.001))
+	require.LessOrEqual(t, len(sketch.positive.bins), 16)
+	require.Equal(t, uint64(1001), sketch.count())
+}
+
+func TestDDSketchCollapseWideRange(t *testing.T) {
+	sketch, err := newDDSketch(0.01, 16)
+	require.NoError(t, err)
+
+	// The range between the values spans far more buckets than the limit,
+	// the range never grows beyond it.
+	require.NoError(t, sketch.Add(1e-300))
+	require.NoError(t, sketch.Add(2e-300))
+	require.NoError(t, sketch.Add(1e300))
+	require.Len(t, sketch.positive.bins, 16)
+	require.NoError(t, sketch.Add(0.9e300))
+	require.Len(t, sketch.positive.bins, 16)
+	require.Equal(t, uint64(4), sketch.count())
+
+	require.InEpsilon(t, 1e300, sketch.Quantile(1), 0.01)
+	require.InEpsilon(t, 0.9e300, sketch.Quantile(0.75), 0.01)
+}
+
+func TestDDSketchCollapseBoundsAllocation(t *testing.T) {
+	sketch, err := newDDSketch(0.0001, 16)
+	require.NoError(t, err)
+	require.NoError(t, sketch.Add(1e-300))
+
+	// Extending the range up to 1e300 spans millions of buckets.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	require.NoError(t, sketch.Add(1e300))
+	runtime.ReadMemStats(&after)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64*1024))
+	require.Len(t, sketch.positive.bins, 16)
+}
+
+func TestDDSketchInvalid(t *testing.T) {
+	_, err := newDDSketch(0, 2048)
+	require.Error(t, err)
+	_, err = newDDSketch(0.01, 0)
+	require.Error(t, err)
+
+	sketch, err := newDDSketch(0.01, 2048)
+	require.NoError(t, err)
+	require.Error(t, sketch.Add(math.NaN()))
+	require.Error(t, sketch.Add(math.Inf(1)))
+}
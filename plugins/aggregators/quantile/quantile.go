@@ -0,0 +1,201 @@
+package quantile
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type Quantile struct {
+	Quantiles        []float64 `toml:"quantiles"`
+	Algorithm        string    `toml:"algorithm"`
+	Compression      int       `toml:"compression"`
+	RelativeAccuracy float64   `toml:"relative_accuracy"`
+	MaxBins          int       `toml:"max_bins"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	cache    map[uint64]aggregate
+	suffixes []string
+	newAlgo  algorithmFactory
+}
+
+type aggregate struct {
+	name   string
+	fields map[string]algorithm
+	tags   map[string]string
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to output in the range [0,1]
+  # quantiles = [0.25, 0.5, 0.75]
+
+  ## Type of aggregation algorithm
+  ## Supported are:
+  ##  "t-digest" -- approximation using centroids, most accurate for extreme
+  ##                quantiles
+  ##  "ddsketch" -- approximation using logarithmic buckets, the values of all
+  ##                quantiles are within the relative accuracy
+  # algorithm = "t-digest"
+
+  ## Compression for the t-digest algorithm, the value needs to be greater or
+  ## equal to 1.  Smaller values result in more performance but less accuracy.
+  # compression = 100
+
+  ## Relative accuracy of the quantile values for the ddsketch algorithm.
+  # relative_accuracy = 0.01
+
+  ## Maximum number of buckets per series and field for the ddsketch
+  ## algorithm, each bucket uses 8 bytes.  If exceeded the buckets of the
+  ## smallest values are merged.
+  # max_bins = 2048
+`
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+func (q *Quantile) Init() error {
+	switch q.Algorithm {
+	case "t-digest", "":
+		if q.Compression < 1 {
+			return fmt.Errorf("compression must be greater or equal to 1")
+		}
+		q.newAlgo = &tdigestFactory{compression: uint32(q.Compression)}
+	case "ddsketch":
+		if q.RelativeAccuracy <= 0 || q.RelativeAccuracy >= 1 {
+			return fmt.Errorf("relative_accuracy must be between 0 and 1")
+		}
+		if q.MaxBins < 1 {
+			return fmt.Errorf("max_bins must be greater or equal to 1")
+		}
+		q.newAlgo = &ddsketchFactory{relativeAccuracy: q.RelativeAccuracy, maxBins: q.MaxBins}
+	default:
+		return fmt.Errorf("unknown algorithm type %q", q.Algorithm)
+	}
+
+	if len(q.Quantiles) == 0 {
+		q.Quantiles = []float64{0.25, 0.5, 0.75}
+	}
+
+	duplicates := make(map[string]bool)
+	q.suffixes = make([]string, 0, len(q.Quantiles))
+	for _, qtl := range q.Quantiles {
+		if qtl < 0.0 || qtl > 1.0 {
+			return fmt.Errorf("quantile %v out of range [0,1]", qtl)
+		}
+		suffix := quantileSuffix(qtl)
+		if duplicates[suffix] {
+			return fmt.Errorf("duplicate quantile %v", qtl)
+		}
+		duplicates[suffix] = true
+		q.suffixes = append(q.suffixes, suffix)
+	}
+
+	q.Reset()
+
+	return nil
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]algorithm),
+		}
+		q.cache[id] = a
+	}
+
+	for _, field := range in.FieldList() {
+		fv, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+
+		algo, ok := a.fields[field.Key]
+		if !ok {
+			var err error
+			algo, err = q.newAlgo.New()
+			if err != nil {
+				q.Log.Errorf("Creating aggregator for field %q failed: %v", field.Key, err)
+				continue
+			}
+			a.fields[field.Key] = algo
+		}
+
+		if err := algo.Add(fv); err != nil {
+			q.Log.Errorf("Adding value of field %q failed: %v", field.Key, err)
+		}
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	for _, aggregate := range q.cache {
+		fields := make(map[string]interface{}, len(aggregate.fields)*len(q.Quantiles))
+		for k, algo := range aggregate.fields {
+			for i, qtl := range q.Quantiles {
+				fields[k+q.suffixes[i]] = algo.Quantile(qtl)
+			}
+		}
+		acc.AddFields(aggregate.name, fields, aggregate.tags)
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+// quantileSuffix returns the field suffix of the quantile, the percentage
+// with three digits such as _050 for the median, or with the decimals
+// separated by an underscore such as _99_9 if needed.
+func quantileSuffix(q float64) string {
+	p := q * 100
+	if r := math.Round(p); math.Abs(p-r) < 1e-9 {
+		return fmt.Sprintf("_%03d", int(r))
+	}
+	return "_" + strings.Replace(strconv.FormatFloat(math.Round(p*1e6)/1e6, 'f', -1, 64), ".", "_", 1)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return &Quantile{
+			Algorithm:        "t-digest",
+			Compression:      100,
+			RelativeAccuracy: 0.01,
+			MaxBins:          2048,
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package quantile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigInvalidAlgorithm(t *testing.T) {
+	q := Quantile{Algorithm: "a strange one"}
+	err := q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown algorithm type")
+}
+
+func TestConfigInvalidCompression(t *testing.T) {
+	q := Quantile{Compression: 0, Algorithm: "t-digest"}
+	err := q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "compression must be")
+}
+
+func TestConfigInvalidRelativeAccuracy(t *testing.T) {
+	q := Quantile{RelativeAccuracy: 1.5, MaxBins: 2048, Algorithm: "ddsketch"}
+	err := q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "relative_accuracy must be")
+}
+
+func TestConfigInvalidQuantiles(t *testing.T) {
+	q := Quantile{Compression: 100, Quantiles: []float64{-0.5}}
+	err := q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "out of range")
+
+	q = Quantile{Compression: 100, Quantiles: []float64{0.5, 0.50}}
+	err = q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "duplicate quantile")
+}
+
+func TestQuantileSuffix(t *testing.T) {
+	require.Equal(t, "_000", quantileSuffix(0))
+	require.Equal(t, "_050", quantileSuffix(0.5))
+	require.Equal(t, "_099", quantileSuffix(0.99))
+	require.Equal(t, "_100", quantileSuffix(1))
+	require.Equal(t, "_99_9", quantileSuffix(0.999))
+	require.Equal(t, "_12_5", quantileSuffix(0.125))
+}
+
+func TestSingleMetric(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		expected  map[string]float64
+		epsilon   float64
+	}{
+		{
+			// The t-digest interpolates between the samples.
+			algorithm: "t-digest",
+			expected: map[string]float64{
+				"a_025": 25.75, "a_050": 50.5, "a_075": 75.25,
+				"b_025": 2.575, "b_050": 5.05, "b_075": 7.525,
+				"c_025": 257.5, "c_050": 505, "c_075": 752.5,
+			},
+			epsilon: 0.02,
+		},
+		{
+			// The DDSketch returns the sample of the rank within the
+			// relative accuracy.
+			algorithm: "ddsketch",
+			expected: map[string]float64{
+				"a_025": 25, "a_050": 50, "a_075": 75,
+				"b_025": 2.5, "b_050": 5, "b_075": 7.5,
+				"c_025": 250, "c_050": 500, "c_075": 750,
+			},
+			epsilon: 0.01,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			q := Quantile{
+				Algorithm:        tt.algorithm,
+				Compression:      100,
+				RelativeAccuracy: 0.01,
+				MaxBins:          2048,
+				Log:              testutil.Logger{},
+			}
+			require.NoError(t, q.Init())
+
+			acc := testutil.Accumulator{}
+			for i := 1; i <= 100; i++ {
+				q.Add(testutil.MustMetric(
+					"test",
+					map[string]string{"foo": "bar"},
+					map[string]interface{}{
+						"a": int64(i),
+						"b": float64(i) / 10,
+						"c": uint64(i * 10),
+						"x": "a string",
+						"y": true,
+					},
+					time.Now(),
+				))
+			}
+			q.Push(&acc)
+
+			require.Len(t, acc.Metrics, 1)
+			m := acc.Metrics[0]
+			require.Equal(t, "test", m.Measurement)
+			require.Equal(t, map[string]string{"foo": "bar"}, m.Tags)
+			require.Len(t, m.Fields, len(tt.expected))
+			for k, v := range tt.expected {
+				require.Contains(t, m.Fields, k)
+				require.InEpsilon(t, v, m.Fields[k], tt.epsilon, k)
+			}
+		})
+	}
+}
+
+func TestMultipleSeriesAndReset(t *testing.T) {
+	q := Quantile{
+		Algorithm:   "t-digest",
+		Compression: 100,
+		Quantiles:   []float64{0.5},
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, q.Init())
+
+	for _, host := range []string{"a", "b"} {
+		for i := 0; i < 3; i++ {
+			q.Add(testutil.MustMetric(
+				"cpu",
+				map[string]string{"host": host},
+				map[string]interface{}{"usage": 42.0},
+				time.Unix(0, 0),
+			))
+		}
+	}
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage_050": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"usage_050": 42.0}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+
+	q.Reset()
+	acc.ClearMetrics()
+	q.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
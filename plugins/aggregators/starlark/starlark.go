@@ -0,0 +1,139 @@
+package starlark
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
+	"go.starlark.net/starlark"
+)
+
+const (
+	description  = "Aggregate metrics using a Starlark script"
+	sampleConfig = `
+  ## The Starlark source can be set as a string in this configuration file, or
+  ## by referencing a file containing the script.  Only one source or script
+  ## should be set at once.
+  ##
+  ## Source of the Starlark script.
+  source = '''
+def add(metric):
+  state["last"] = metric
+
+def push():
+  return state.get("last")
+
+def reset():
+  state.clear()
+'''
+
+  ## File containing a Starlark script.
+  # script = "/usr/local/bin/myscript.star"
+
+  ## File used to persist the shared state dictionary between restarts.  The
+  ## state is restored on start and saved after each reset.
+  # state_file = "/var/lib/telegraf/starlark_aggregator.state"
+`
+)
+
+type Starlark struct {
+	common.StarlarkCommon
+}
+
+func (s *Starlark) Init() error {
+	err := s.StarlarkCommon.Init()
+	if err != nil {
+		return err
+	}
+
+	// The source should define an add, push and reset function.
+	err = s.AddFunction("add", &common.Metric{})
+	if err != nil {
+		return err
+	}
+	err = s.AddFunction("push")
+	if err != nil {
+		return err
+	}
+	err = s.AddFunction("reset")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Starlark) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Starlark) Description() string {
+	return description
+}
+
+func (s *Starlark) Add(metric telegraf.Metric) {
+	parameters, found := s.GetParameters("add")
+	if !found {
+		s.Log.Errorf("The parameters of the add function could not be found")
+		return
+	}
+
+	// The metric is a copy owned by the aggregator, a new wrapper is used for
+	// each call so that the script can keep a reference to it in the state.
+	m := &common.Metric{}
+	m.Wrap(metric)
+	parameters[0] = m
+
+	_, err := s.Call("add")
+	if err != nil {
+		s.LogError(err)
+	}
+}
+
+// Push adds the metrics returned by the push function.  The metrics are
+// copied since the script may still reference them in its state.
+func (s *Starlark) Push(acc telegraf.Accumulator) {
+	rv, err := s.Call("push")
+	if err != nil {
+		s.LogError(err)
+		return
+	}
+
+	switch rv := rv.(type) {
+	case *starlark.List:
+		iter := rv.Iterate()
+		defer iter.Done()
+		var v starlark.Value
+		for iter.Next(&v) {
+			switch v := v.(type) {
+			case *common.Metric:
+				acc.AddMetric(v.Unwrap().Copy())
+			default:
+				s.Log.Errorf("Invalid type returned in list: %s", v.Type())
+			}
+		}
+	case *common.Metric:
+		acc.AddMetric(rv.Unwrap().Copy())
+	case starlark.NoneType:
+	default:
+		s.Log.Errorf("Invalid type returned: %T", rv)
+	}
+}
+
+func (s *Starlark) Reset() {
+	_, err := s.Call("reset")
+	if err != nil {
+		s.LogError(err)
+	}
+
+	// Aggregators are not stopped on shutdown, so the state is saved at the
+	// end of every period.
+	if err := s.SaveState(); err != nil {
+		s.Log.Error(err)
+	}
+}
+
+func init() {
+	aggregators.Add("starlark", func() telegraf.Aggregator {
+		return &Starlark{}
+	})
+}
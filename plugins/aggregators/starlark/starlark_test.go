@@ -0,0 +1,234 @@
+package starlark
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for runtime errors in the aggregators Init function.
+func TestInitError(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{
+			name: "source must define add",
+			source: `
+def push():
+	return None
+
+def reset():
+	pass
+`,
+		},
+		{
+			name: "push must be a function",
+			source: `
+push = 42
+
+def add(metric):
+	pass
+
+def reset():
+	pass
+`,
+		},
+		{
+			name: "add function must take one arg",
+			source: `
+def add():
+	pass
+
+def push():
+	return None
+
+def reset():
+	pass
+`,
+		},
+		{
+			name: "reset function must take no args",
+			source: `
+def add(metric):
+	pass
+
+def push():
+	return None
+
+def reset(metric):
+	pass
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newStarlarkFromSource(tt.source)
+			require.Error(t, plugin.Init())
+		})
+	}
+}
+
+func TestAddPushReset(t *testing.T) {
+	plugin := newStarlarkFromSource(`
+def add(metric):
+	state.setdefault("metrics", []).append(metric)
+
+def push():
+	result = Metric("count")
+	result.fields["value"] = len(state.get("metrics", []))
+	return [result] + state.get("metrics", [])
+
+def reset():
+	state.clear()
+`)
+	require.NoError(t, plugin.Init())
+
+	m1 := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	m2 := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 43}, time.Unix(0, 0))
+	plugin.Add(m1)
+	plugin.Add(m2)
+
+	acc := &testutil.Accumulator{}
+	plugin.Push(acc)
+	plugin.Reset()
+	plugin.Push(acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("count", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		m1,
+		m2,
+		testutil.MustMetric("count", map[string]string{}, map[string]interface{}{"value": 0}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestStatePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	source := `
+def add(metric):
+	state["total"] = state.get("total", 0) + metric.fields["value"]
+
+def push():
+	result = Metric("total")
+	result.fields["value"] = state.get("total", 0)
+	return result
+
+def reset():
+	pass
+`
+	statefile := filepath.Join(dir, "state.json")
+
+	for _, value := range []int64{1, 2} {
+		plugin := newStarlarkFromSource(source)
+		plugin.StateFile = statefile
+		require.NoError(t, plugin.Init())
+		plugin.Add(testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": value}, time.Unix(0, 0)))
+		plugin.Reset()
+	}
+
+	plugin := newStarlarkFromSource(source)
+	plugin.StateFile = statefile
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	plugin.Push(acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("total", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestAllScriptTestData(t *testing.T) {
+	// can be run from multiple folders
+	paths := []string{"testdata", "plugins/aggregators/starlark/testdata"}
+	for _, testdataPath := range paths {
+		filepath.Walk(testdataPath, func(path string, info os.FileInfo, err error) error {
+			if info == nil || info.IsDir() {
+				return nil
+			}
+			fn := path
+			t.Run(fn, func(t *testing.T) {
+				b, err := ioutil.ReadFile(fn)
+				require.NoError(t, err)
+				lines := strings.Split(string(b), "\n")
+				inputMetrics := parseMetricsFrom(t, lines, "Example Input:")
+				outputMetrics := parseMetricsFrom(t, lines, "Example Output:")
+
+				plugin := newStarlarkFromScript(fn)
+				require.NoError(t, plugin.Init())
+
+				for _, m := range inputMetrics {
+					plugin.Add(m)
+				}
+
+				acc := &testutil.Accumulator{}
+				plugin.Push(acc)
+
+				testutil.RequireMetricsEqual(t, outputMetrics, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+			})
+			return nil
+		})
+	}
+}
+
+var parser, _ = parsers.NewInfluxParser() // literally never returns errors.
+
+// parses metric lines out of line protocol following a header, with a trailing blank line
+func parseMetricsFrom(t *testing.T, lines []string, header string) (metrics []telegraf.Metric) {
+	require.NotZero(t, len(lines), "Expected some lines to parse from .star file, found none")
+	startIdx := -1
+	endIdx := len(lines)
+	for i := range lines {
+		if strings.TrimLeft(lines[i], "# ") == header {
+			startIdx = i + 1
+			break
+		}
+	}
+	require.NotEqual(t, -1, startIdx, fmt.Sprintf("Header %q must exist in file", header))
+	for i := startIdx; i < len(lines); i++ {
+		line := strings.TrimLeft(lines[i], "# ")
+		if line == "" {
+			endIdx = i
+			break
+		}
+	}
+	for i := startIdx; i < endIdx; i++ {
+		m, err := parser.ParseLine(strings.TrimLeft(lines[i], "# "))
+		require.NoError(t, err, fmt.Sprintf("Expected to be able to parse %q metric, but found error", header))
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func newStarlarkFromSource(source string) *Starlark {
+	return &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Source: source,
+			Log:    testutil.Logger{},
+		},
+	}
+}
+
+func newStarlarkFromScript(script string) *Starlark {
+	return &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Script: script,
+			Log:    testutil.Logger{},
+		},
+	}
+}
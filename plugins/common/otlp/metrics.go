@@ -0,0 +1,640 @@
+package otlp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// temporalityCumulative is the cumulative AggregationTemporality.
+const temporalityCumulative = 2
+
+// ScopeName is the name of the instrumentation scope of encoded metrics.
+const ScopeName = "telegraf"
+
+// Marshal encodes the request in the format, either "protobuf" or "json".
+func Marshal(req *dynamic.Message, format string) ([]byte, error) {
+	switch format {
+	case "", "protobuf":
+		return req.Marshal()
+	case "json":
+		return req.MarshalJSONPB(&jsonpb.Marshaler{EnumsAsInts: true})
+	default:
+		return nil, fmt.Errorf("unknown OTLP format %q", format)
+	}
+}
+
+// Unmarshal decodes a request in the format, either "protobuf" or "json".
+func Unmarshal(buf []byte, format string) (*dynamic.Message, error) {
+	req := NewRequest()
+	var err error
+	switch format {
+	case "", "protobuf":
+		err = req.Unmarshal(buf)
+	case "json":
+		err = req.UnmarshalJSONPB(&jsonpb.Unmarshaler{AllowUnknownFields: true}, buf)
+	default:
+		err = fmt.Errorf("unknown OTLP format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// resource collects the metrics sharing the same resource attributes.
+type resource struct {
+	attributes []telegraf.Tag
+	metrics    []*series
+	index      map[string]*series
+}
+
+// series collects the data points of an OTLP metric.
+type series struct {
+	name   string
+	tp     telegraf.ValueType
+	points []*point
+	index  map[string]*point
+}
+
+// point is a data point, histogram and summary points are assembled from
+// the fields of multiple metrics.
+type point struct {
+	attributes []telegraf.Tag
+	time       time.Time
+	value      interface{}
+
+	count     uint64
+	sum       float64
+	buckets   map[float64]uint64
+	quantiles map[float64]float64
+}
+
+// FromMetrics converts the metrics to an ExportMetricsServiceRequest.  Tags
+// listed in resourceTags become resource attributes, the other tags data
+// point attributes.
+//
+// The OTLP metric names are the measurement name joined with the field key
+// by an underscore; the measurement name is omitted if it is "prometheus".
+// Histograms and summaries are expected in the format of the prometheus
+// input, using the "le" and "quantile" tags.
+func FromMetrics(metrics []telegraf.Metric, resourceTags []string) (*dynamic.Message, error) {
+	isResourceTag := make(map[string]bool, len(resourceTags))
+	for _, tag := range resourceTags {
+		isResourceTag[tag] = true
+	}
+
+	var resources []*resource
+	resourceIndex := make(map[string]*resource)
+	for _, m := range metrics {
+		var resourceAttrs, pointAttrs []telegraf.Tag
+		var le, quantile string
+		for _, tag := range m.TagList() {
+			switch {
+			case isResourceTag[tag.Key]:
+				resourceAttrs = append(resourceAttrs, *tag)
+			case m.Type() == telegraf.Histogram && tag.Key == "le":
+				le = tag.Value
+			case m.Type() == telegraf.Summary && tag.Key == "quantile":
+				quantile = tag.Value
+			default:
+				pointAttrs = append(pointAttrs, *tag)
+			}
+		}
+
+		rkey := tagsKey(resourceAttrs)
+		r, ok := resourceIndex[rkey]
+		if !ok {
+			r = &resource{attributes: resourceAttrs, index: make(map[string]*series)}
+			resourceIndex[rkey] = r
+			resources = append(resources, r)
+		}
+
+		for _, field := range m.FieldList() {
+			name, suffix := metricName(m.Name(), field.Key, m.Type())
+			s := r.series(name, m.Type())
+
+			if m.Type() != telegraf.Histogram && m.Type() != telegraf.Summary {
+				if v, ok := numberValue(field.Value); ok {
+					s.points = append(s.points, &point{attributes: pointAttrs, time: m.Time(), value: v})
+				}
+				continue
+			}
+
+			v, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+			p := s.point(pointAttrs, m.Time())
+			switch suffix {
+			case "_count":
+				p.count = uint64(v)
+			case "_sum":
+				p.sum = v
+			case "_bucket":
+				bound, err := strconv.ParseFloat(le, 64)
+				if err != nil {
+					continue
+				}
+				p.buckets[bound] = uint64(v)
+			default:
+				if m.Type() != telegraf.Summary {
+					continue
+				}
+				q, err := strconv.ParseFloat(quantile, 64)
+				if err != nil {
+					continue
+				}
+				p.quantiles[q] = v
+			}
+		}
+	}
+
+	req := NewRequest()
+	for _, r := range resources {
+		rm := newField(req, "resource_metrics")
+		res := newField(rm, "resource")
+		res.SetFieldByName("attributes", keyValues(res, r.attributes))
+		rm.SetFieldByName("resource", res)
+
+		sm := newField(rm, "scope_metrics")
+		scope := newField(sm, "scope")
+		scope.SetFieldByName("name", ScopeName)
+		sm.SetFieldByName("scope", scope)
+		for _, s := range r.metrics {
+			if len(s.points) == 0 {
+				continue
+			}
+			sm.AddRepeatedFieldByName("metrics", s.message(sm))
+		}
+		rm.AddRepeatedFieldByName("scope_metrics", sm)
+		req.AddRepeatedFieldByName("resource_metrics", rm)
+	}
+	return req, nil
+}
+
+func (r *resource) series(name string, tp telegraf.ValueType) *series {
+	key := name + "\x00" + strconv.Itoa(int(tp))
+	s, ok := r.index[key]
+	if !ok {
+		s = &series{name: name, tp: tp, index: make(map[string]*point)}
+		r.index[key] = s
+		r.metrics = append(r.metrics, s)
+	}
+	return s
+}
+
+func (s *series) point(attributes []telegraf.Tag, t time.Time) *point {
+	key := tagsKey(attributes) + "\x00" + strconv.FormatInt(t.UnixNano(), 10)
+	p, ok := s.index[key]
+	if !ok {
+		p = &point{
+			attributes: attributes,
+			time:       t,
+			buckets:    make(map[float64]uint64),
+			quantiles:  make(map[float64]float64),
+		}
+		s.index[key] = p
+		s.points = append(s.points, p)
+	}
+	return p
+}
+
+// message creates the Metric message of the series.
+func (s *series) message(parent *dynamic.Message) *dynamic.Message {
+	msg := newField(parent, "metrics")
+	msg.SetFieldByName("name", s.name)
+
+	switch s.tp {
+	case telegraf.Counter:
+		sum := newField(msg, "sum")
+		sum.SetFieldByName("aggregation_temporality", int32(temporalityCumulative))
+		sum.SetFieldByName("is_monotonic", true)
+		for _, p := range s.points {
+			sum.AddRepeatedFieldByName("data_points", p.numberDataPoint(sum))
+		}
+		msg.SetFieldByName("sum", sum)
+	case telegraf.Histogram:
+		histogram := newField(msg, "histogram")
+		histogram.SetFieldByName("aggregation_temporality", int32(temporalityCumulative))
+		for _, p := range s.points {
+			histogram.AddRepeatedFieldByName("data_points", p.histogramDataPoint(histogram))
+		}
+		msg.SetFieldByName("histogram", histogram)
+	case telegraf.Summary:
+		summary := newField(msg, "summary")
+		for _, p := range s.points {
+			summary.AddRepeatedFieldByName("data_points", p.summaryDataPoint(summary))
+		}
+		msg.SetFieldByName("summary", summary)
+	default:
+		gauge := newField(msg, "gauge")
+		for _, p := range s.points {
+			gauge.AddRepeatedFieldByName("data_points", p.numberDataPoint(gauge))
+		}
+		msg.SetFieldByName("gauge", gauge)
+	}
+	return msg
+}
+
+func (p *point) numberDataPoint(parent *dynamic.Message) *dynamic.Message {
+	dp := newField(parent, "data_points")
+	dp.SetFieldByName("attributes", keyValues(dp, p.attributes))
+	dp.SetFieldByName("time_unix_nano", uint64(p.time.UnixNano()))
+	switch v := p.value.(type) {
+	case int64:
+		dp.SetFieldByName("as_int", v)
+	case float64:
+		dp.SetFieldByName("as_double", v)
+	}
+	return dp
+}
+
+// histogramDataPoint converts the cumulative bucket counts of the point to
+// the counts of the explicit bounds.
+func (p *point) histogramDataPoint(parent *dynamic.Message) *dynamic.Message {
+	dp := newField(parent, "data_points")
+	dp.SetFieldByName("attributes", keyValues(dp, p.attributes))
+	dp.SetFieldByName("time_unix_nano", uint64(p.time.UnixNano()))
+
+	bounds := make([]float64, 0, len(p.buckets))
+	for bound := range p.buckets {
+		if !math.IsInf(bound, 1) {
+			bounds = append(bounds, bound)
+		}
+	}
+	sort.Float64s(bounds)
+
+	count := p.count
+	if inf, ok := p.buckets[math.Inf(1)]; ok && count == 0 {
+		count = inf
+	}
+
+	var previous uint64
+	counts := make([]uint64, 0, len(bounds)+1)
+	for _, bound := range bounds {
+		cumulative := p.buckets[bound]
+		if cumulative < previous {
+			cumulative = previous
+		}
+		counts = append(counts, cumulative-previous)
+		previous = cumulative
+	}
+	if count < previous {
+		count = previous
+	}
+	counts = append(counts, count-previous)
+
+	dp.SetFieldByName("count", count)
+	dp.SetFieldByName("sum", p.sum)
+	dp.SetFieldByName("explicit_bounds", bounds)
+	dp.SetFieldByName("bucket_counts", counts)
+	return dp
+}
+
+func (p *point) summaryDataPoint(parent *dynamic.Message) *dynamic.Message {
+	dp := newField(parent, "data_points")
+	dp.SetFieldByName("attributes", keyValues(dp, p.attributes))
+	dp.SetFieldByName("time_unix_nano", uint64(p.time.UnixNano()))
+	dp.SetFieldByName("count", p.count)
+	dp.SetFieldByName("sum", p.sum)
+
+	quantiles := make([]float64, 0, len(p.quantiles))
+	for q := range p.quantiles {
+		quantiles = append(quantiles, q)
+	}
+	sort.Float64s(quantiles)
+	for _, q := range quantiles {
+		qv := newField(dp, "quantile_values")
+		qv.SetFieldByName("quantile", q)
+		qv.SetFieldByName("value", p.quantiles[q])
+		dp.AddRepeatedFieldByName("quantile_values", qv)
+	}
+	return dp
+}
+
+// ToMetrics converts an ExportMetricsServiceRequest to metrics named after
+// the measurement, the OTLP metric names are used as field keys.  Resource,
+// scope and data point attributes become tags.  Data points without timestamp
+// use the time now.
+func ToMetrics(req *dynamic.Message, measurement string, now time.Time) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for _, rm := range messages(req, "resource_metrics") {
+		resourceTags := make(map[string]string)
+		if res, ok := rm.GetFieldByName("resource").(*dynamic.Message); ok && res != nil {
+			addAttributes(resourceTags, res)
+		}
+
+		scopes := messages(rm, "scope_metrics")
+		for _, sm := range scopes {
+			scopeTags := resourceTags
+			if scope, ok := sm.GetFieldByName("scope").(*dynamic.Message); ok && scope != nil {
+				scopeTags = make(map[string]string, len(resourceTags))
+				for k, v := range resourceTags {
+					scopeTags[k] = v
+				}
+				addAttributes(scopeTags, scope)
+			}
+
+			for _, msg := range messages(sm, "metrics") {
+				name, _ := msg.GetFieldByName("name").(string)
+				c := &converter{
+					measurement: measurement,
+					name:        name,
+					scopeTags:   scopeTags,
+					now:         now,
+				}
+
+				var err error
+				switch {
+				case msg.HasFieldName("gauge"):
+					err = c.numberDataPoints(msg.GetFieldByName("gauge").(*dynamic.Message), telegraf.Gauge)
+				case msg.HasFieldName("sum"):
+					sum := msg.GetFieldByName("sum").(*dynamic.Message)
+					tp := telegraf.Gauge
+					if monotonic, _ := sum.GetFieldByName("is_monotonic").(bool); monotonic {
+						tp = telegraf.Counter
+					}
+					err = c.numberDataPoints(sum, tp)
+				case msg.HasFieldName("histogram"):
+					err = c.histogramDataPoints(msg.GetFieldByName("histogram").(*dynamic.Message))
+				case msg.HasFieldName("summary"):
+					err = c.summaryDataPoints(msg.GetFieldByName("summary").(*dynamic.Message))
+				}
+				if err != nil {
+					return nil, fmt.Errorf("metric %q: %v", name, err)
+				}
+				metrics = append(metrics, c.metrics...)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// converter creates the metrics of an OTLP metric.
+type converter struct {
+	measurement string
+	name        string
+	scopeTags   map[string]string
+	now         time.Time
+	metrics     []telegraf.Metric
+}
+
+func (c *converter) tags(dp *dynamic.Message) map[string]string {
+	tags := make(map[string]string, len(c.scopeTags))
+	for k, v := range c.scopeTags {
+		tags[k] = v
+	}
+	addAttributes(tags, dp)
+	return tags
+}
+
+func (c *converter) time(dp *dynamic.Message) time.Time {
+	ns, _ := dp.GetFieldByName("time_unix_nano").(uint64)
+	if ns == 0 {
+		return c.now
+	}
+	return time.Unix(0, int64(ns))
+}
+
+func (c *converter) add(tags map[string]string, fields map[string]interface{}, t time.Time, tp telegraf.ValueType) error {
+	m, err := metric.New(c.measurement, tags, fields, t, tp)
+	if err != nil {
+		return err
+	}
+	c.metrics = append(c.metrics, m)
+	return nil
+}
+
+func (c *converter) numberDataPoints(data *dynamic.Message, tp telegraf.ValueType) error {
+	for _, dp := range messages(data, "data_points") {
+		var value interface{}
+		switch {
+		case dp.HasFieldName("as_int"):
+			value = dp.GetFieldByName("as_int")
+		case dp.HasFieldName("as_double"):
+			value = dp.GetFieldByName("as_double")
+		default:
+			continue
+		}
+
+		fields := map[string]interface{}{c.name: value}
+		if err := c.add(c.tags(dp), fields, c.time(dp), tp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// histogramDataPoints creates a metric with the count and sum of each data
+// point, and a metric for each bucket with its cumulative count and upper
+// bound as "le" tag.
+func (c *converter) histogramDataPoints(data *dynamic.Message) error {
+	for _, dp := range messages(data, "data_points") {
+		tags := c.tags(dp)
+		t := c.time(dp)
+
+		count, _ := dp.GetFieldByName("count").(uint64)
+		sum, _ := dp.GetFieldByName("sum").(float64)
+		fields := map[string]interface{}{
+			c.name + "_count": count,
+			c.name + "_sum":   sum,
+		}
+		if err := c.add(tags, fields, t, telegraf.Histogram); err != nil {
+			return err
+		}
+
+		bounds, _ := dp.GetFieldByName("explicit_bounds").([]interface{})
+		counts, _ := dp.GetFieldByName("bucket_counts").([]interface{})
+		if len(counts) != 0 && len(counts) != len(bounds)+1 {
+			return fmt.Errorf("%d bucket counts for %d bounds", len(counts), len(bounds))
+		}
+
+		var cumulative uint64
+		for i, bound := range bounds {
+			if len(counts) != 0 {
+				cumulative += counts[i].(uint64)
+			}
+			if err := c.addBucket(tags, strconv.FormatFloat(bound.(float64), 'f', -1, 64), cumulative, t); err != nil {
+				return err
+			}
+		}
+		if err := c.addBucket(tags, "+Inf", count, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *converter) addBucket(tags map[string]string, le string, count uint64, t time.Time) error {
+	bucketTags := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		bucketTags[k] = v
+	}
+	bucketTags["le"] = le
+	return c.add(bucketTags, map[string]interface{}{c.name + "_bucket": count}, t, telegraf.Histogram)
+}
+
+// summaryDataPoints creates a metric with the count and sum of each data
+// point, and a metric for each quantile with the quantile as tag.
+func (c *converter) summaryDataPoints(data *dynamic.Message) error {
+	for _, dp := range messages(data, "data_points") {
+		tags := c.tags(dp)
+		t := c.time(dp)
+
+		count, _ := dp.GetFieldByName("count").(uint64)
+		sum, _ := dp.GetFieldByName("sum").(float64)
+		fields := map[string]interface{}{
+			c.name + "_count": count,
+			c.name + "_sum":   sum,
+		}
+		if err := c.add(tags, fields, t, telegraf.Summary); err != nil {
+			return err
+		}
+
+		for _, qv := range messages(dp, "quantile_values") {
+			quantile, _ := qv.GetFieldByName("quantile").(float64)
+			value, _ := qv.GetFieldByName("value").(float64)
+
+			quantileTags := make(map[string]string, len(tags)+1)
+			for k, v := range tags {
+				quantileTags[k] = v
+			}
+			quantileTags["quantile"] = strconv.FormatFloat(quantile, 'f', -1, 64)
+			if err := c.add(quantileTags, map[string]interface{}{c.name: value}, t, telegraf.Summary); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// metricName returns the OTLP metric name of the field and the suffix of
+// histogram and summary fields.
+func metricName(measurement, fieldKey string, tp telegraf.ValueType) (string, string) {
+	var suffix string
+	if tp == telegraf.Histogram || tp == telegraf.Summary {
+		for _, s := range []string{"_bucket", "_sum", "_count"} {
+			if strings.HasSuffix(fieldKey, s) {
+				fieldKey = strings.TrimSuffix(fieldKey, s)
+				suffix = s
+				break
+			}
+		}
+	}
+
+	if measurement == "prometheus" {
+		return fieldKey, suffix
+	}
+	return measurement + "_" + fieldKey, suffix
+}
+
+// numberValue returns the value of a number data point, either int64 or
+// float64.
+func numberValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		if v > math.MaxInt64 {
+			return float64(v), true
+		}
+		return int64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return int64(1), true
+		}
+		return int64(0), true
+	default:
+		return nil, false
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func tagsKey(tags []telegraf.Tag) string {
+	var b strings.Builder
+	for _, tag := range tags {
+		b.WriteString(tag.Key)
+		b.WriteByte(0)
+		b.WriteString(tag.Value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// newField creates an empty message of the type of the message field.
+func newField(parent *dynamic.Message, name string) *dynamic.Message {
+	fd := parent.GetMessageDescriptor().FindFieldByName(name)
+	return dynamic.NewMessage(fd.GetMessageType())
+}
+
+// keyValues converts tags to the KeyValue messages of the attributes field.
+func keyValues(parent *dynamic.Message, tags []telegraf.Tag) []*dynamic.Message {
+	attributes := make([]*dynamic.Message, 0, len(tags))
+	for _, tag := range tags {
+		kv := newField(parent, "attributes")
+		kv.SetFieldByName("key", tag.Key)
+		value := newField(kv, "value")
+		value.SetFieldByName("string_value", tag.Value)
+		kv.SetFieldByName("value", value)
+		attributes = append(attributes, kv)
+	}
+	return attributes
+}
+
+// addAttributes adds the attributes of the message as tags, values other
+// than strings, booleans and numbers are ignored.
+func addAttributes(tags map[string]string, msg *dynamic.Message) {
+	for _, kv := range messages(msg, "attributes") {
+		key, _ := kv.GetFieldByName("key").(string)
+		value, ok := kv.GetFieldByName("value").(*dynamic.Message)
+		if !ok || value == nil {
+			continue
+		}
+
+		switch {
+		case value.HasFieldName("string_value"):
+			tags[key] = value.GetFieldByName("string_value").(string)
+		case value.HasFieldName("bool_value"):
+			tags[key] = strconv.FormatBool(value.GetFieldByName("bool_value").(bool))
+		case value.HasFieldName("int_value"):
+			tags[key] = strconv.FormatInt(value.GetFieldByName("int_value").(int64), 10)
+		case value.HasFieldName("double_value"):
+			tags[key] = strconv.FormatFloat(value.GetFieldByName("double_value").(float64), 'f', -1, 64)
+		}
+	}
+}
+
+// messages returns the messages of the repeated field.
+func messages(msg *dynamic.Message, name string) []*dynamic.Message {
+	items, _ := msg.GetFieldByName(name).([]interface{})
+	result := make([]*dynamic.Message, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(*dynamic.Message); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
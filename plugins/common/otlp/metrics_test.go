@@ -0,0 +1,276 @@
+package otlp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	now := time.Unix(1600000000, 5)
+	input := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5, "running": true},
+			now,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"net",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"bytes_recv": uint64(42), "name": "eth0"},
+			now,
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "b", "path": "/"},
+			map[string]interface{}{"latency_sum": 12.5, "latency_count": 4.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "b", "path": "/", "le": "0.5"},
+			map[string]interface{}{"latency_bucket": 1.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "b", "path": "/", "le": "+Inf"},
+			map[string]interface{}{"latency_bucket": 4.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "b", "path": "/", "le": "1"},
+			map[string]interface{}{"latency_bucket": 3.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"rpc",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"duration_sum": 3.0, "duration_count": uint64(2)},
+			now,
+			telegraf.Summary,
+		),
+		testutil.MustMetric(
+			"rpc",
+			map[string]string{"host": "b", "quantile": "0.99"},
+			map[string]interface{}{"duration": 2.5},
+			now,
+			telegraf.Summary,
+		),
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"cpu_usage_idle": 91.5},
+			now,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"cpu_running": int64(1)},
+			now,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"net_bytes_recv": int64(42)},
+			now,
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "b", "path": "/"},
+			map[string]interface{}{"latency_sum": 12.5, "latency_count": uint64(4)},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "b", "path": "/", "le": "0.5"},
+			map[string]interface{}{"latency_bucket": uint64(1)},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "b", "path": "/", "le": "1"},
+			map[string]interface{}{"latency_bucket": uint64(3)},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "b", "path": "/", "le": "+Inf"},
+			map[string]interface{}{"latency_bucket": uint64(4)},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"rpc_duration_sum": 3.0, "rpc_duration_count": uint64(2)},
+			now,
+			telegraf.Summary,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "b", "quantile": "0.99"},
+			map[string]interface{}{"rpc_duration": 2.5},
+			now,
+			telegraf.Summary,
+		),
+	}
+
+	for _, format := range []string{"protobuf", "json"} {
+		t.Run(format, func(t *testing.T) {
+			req, err := FromMetrics(input, []string{"host"})
+			require.NoError(t, err)
+			require.Len(t, messages(req, "resource_metrics"), 2)
+
+			buf, err := Marshal(req, format)
+			require.NoError(t, err)
+
+			decoded, err := Unmarshal(buf, format)
+			require.NoError(t, err)
+
+			actual, err := ToMetrics(decoded, "otlp", time.Unix(0, 0))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, expected, actual, testutil.SortMetrics())
+			for _, m := range actual {
+				if m.HasField("cpu_usage_idle") || m.HasField("cpu_running") {
+					require.Equal(t, telegraf.Gauge, m.Type())
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	req, err := FromMetrics([]telegraf.Metric{
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"le": "1"},
+			map[string]interface{}{"size_bucket": 2.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"le": "+Inf"},
+			map[string]interface{}{"size_bucket": 5.0},
+			now,
+			telegraf.Histogram,
+		),
+	}, nil)
+	require.NoError(t, err)
+
+	rm := messages(req, "resource_metrics")[0]
+	sm := messages(rm, "scope_metrics")[0]
+	m := messages(sm, "metrics")[0]
+	require.Equal(t, "size", m.GetFieldByName("name"))
+
+	require.True(t, m.HasFieldName("histogram"))
+	dp := messages(m.GetFieldByName("histogram").(*dynamic.Message), "data_points")[0]
+	require.Equal(t, uint64(5), dp.GetFieldByName("count"))
+	require.Equal(t, []interface{}{1.0}, dp.GetFieldByName("explicit_bounds"))
+	require.Equal(t, []interface{}{uint64(2), uint64(3)}, dp.GetFieldByName("bucket_counts"))
+}
+
+func TestParseJSON(t *testing.T) {
+	doc := `{
+  "resourceMetrics": [{
+    "resource": {
+      "attributes": [
+        {"key": "service.name", "value": {"stringValue": "api"}},
+        {"key": "replica", "value": {"intValue": "2"}}
+      ]
+    },
+    "scopeMetrics": [{
+      "scope": {
+        "name": "example",
+        "attributes": [{"key": "library", "value": {"stringValue": "otel-go"}}]
+      },
+      "metrics": [
+        {
+          "name": "requests",
+          "unit": "1",
+          "sum": {
+            "aggregationTemporality": "AGGREGATION_TEMPORALITY_CUMULATIVE",
+            "isMonotonic": true,
+            "dataPoints": [
+              {
+                "attributes": [{"key": "code", "value": {"intValue": 200}}],
+                "timeUnixNano": "1600000000000000000",
+                "asInt": "17"
+              }
+            ]
+          }
+        },
+        {
+          "name": "temperature",
+          "gauge": {
+            "dataPoints": [
+              {"timeUnixNano": 1600000000000000000, "asDouble": "NaN"},
+              {"timeUnixNano": 1600000000000000000, "asDouble": 21.5},
+              {"timeUnixNano": 1600000000000000000}
+            ]
+          },
+          "exemplars": []
+        }
+      ]
+    }]
+  }]
+}`
+
+	req, err := Unmarshal([]byte(doc), "json")
+	require.NoError(t, err)
+
+	actual, err := ToMetrics(req, "otlp", time.Unix(0, 0))
+	require.NoError(t, err)
+	require.Len(t, actual, 3)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"service.name": "api", "replica": "2", "library": "otel-go", "code": "200"},
+			map[string]interface{}{"requests": int64(17)},
+			time.Unix(1600000000, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"service.name": "api", "replica": "2", "library": "otel-go"},
+			map[string]interface{}{"temperature": 21.5},
+			time.Unix(1600000000, 0),
+			telegraf.Gauge,
+		),
+	}
+	require.True(t, math.IsNaN(actual[1].Fields()["temperature"].(float64)))
+	testutil.RequireMetricsEqual(t, expected, []telegraf.Metric{actual[0], actual[2]})
+}
+
+func TestInvalidFormat(t *testing.T) {
+	_, err := Marshal(NewRequest(), "xml")
+	require.Error(t, err)
+
+	_, err = Unmarshal(nil, "xml")
+	require.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package otlp
+
+import (
+	"fmt"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// schema contains the parts of the OpenTelemetry protocol definitions
+// required for metrics, fields not listed here are preserved as unknown
+// fields when decoding.
+var schema = map[string]string{
+	"opentelemetry/proto/common/v1/common.proto": `
+syntax = "proto3";
+
+package opentelemetry.proto.common.v1;
+
+message AnyValue {
+  oneof value {
+    string string_value = 1;
+    bool bool_value = 2;
+    int64 int_value = 3;
+    double double_value = 4;
+    ArrayValue array_value = 5;
+    KeyValueList kvlist_value = 6;
+    bytes bytes_value = 7;
+  }
+}
+
+message ArrayValue {
+  repeated AnyValue values = 1;
+}
+
+message KeyValueList {
+  repeated KeyValue values = 1;
+}
+
+message KeyValue {
+  string key = 1;
+  AnyValue value = 2;
+}
+
+message InstrumentationScope {
+  string name = 1;
+  string version = 2;
+  repeated KeyValue attributes = 3;
+  uint32 dropped_attributes_count = 4;
+}
+`,
+	"opentelemetry/proto/resource/v1/resource.proto": `
+syntax = "proto3";
+
+package opentelemetry.proto.resource.v1;
+
+import "opentelemetry/proto/common/v1/common.proto";
+
+message Resource {
+  repeated opentelemetry.proto.common.v1.KeyValue attributes = 1;
+  uint32 dropped_attributes_count = 2;
+}
+`,
+	"opentelemetry/proto/metrics/v1/metrics.proto": `
+syntax = "proto3";
+
+package opentelemetry.proto.metrics.v1;
+
+import "opentelemetry/proto/common/v1/common.proto";
+import "opentelemetry/proto/resource/v1/resource.proto";
+
+message ResourceMetrics {
+  opentelemetry.proto.resource.v1.Resource resource = 1;
+  repeated ScopeMetrics scope_metrics = 2;
+  string schema_url = 3;
+}
+
+message ScopeMetrics {
+  opentelemetry.proto.common.v1.InstrumentationScope scope = 1;
+  repeated Metric metrics = 2;
+  string schema_url = 3;
+}
+
+message Metric {
+  string name = 1;
+  string description = 2;
+  string unit = 3;
+  oneof data {
+    Gauge gauge = 5;
+    Sum sum = 7;
+    Histogram histogram = 9;
+    Summary summary = 11;
+  }
+}
+
+message Gauge {
+  repeated NumberDataPoint data_points = 1;
+}
+
+message Sum {
+  repeated NumberDataPoint data_points = 1;
+  AggregationTemporality aggregation_temporality = 2;
+  bool is_monotonic = 3;
+}
+
+message Histogram {
+  repeated HistogramDataPoint data_points = 1;
+  AggregationTemporality aggregation_temporality = 2;
+}
+
+message Summary {
+  repeated SummaryDataPoint data_points = 1;
+}
+
+enum AggregationTemporality {
+  AGGREGATION_TEMPORALITY_UNSPECIFIED = 0;
+  AGGREGATION_TEMPORALITY_DELTA = 1;
+  AGGREGATION_TEMPORALITY_CUMULATIVE = 2;
+}
+
+message NumberDataPoint {
+  repeated opentelemetry.proto.common.v1.KeyValue attributes = 7;
+  fixed64 start_time_unix_nano = 2;
+  fixed64 time_unix_nano = 3;
+  oneof value {
+    double as_double = 4;
+    sfixed64 as_int = 6;
+  }
+  uint32 flags = 8;
+}
+
+message HistogramDataPoint {
+  repeated opentelemetry.proto.common.v1.KeyValue attributes = 9;
+  fixed64 start_time_unix_nano = 2;
+  fixed64 time_unix_nano = 3;
+  fixed64 count = 4;
+  double sum = 5;
+  repeated fixed64 bucket_counts = 6;
+  repeated double explicit_bounds = 7;
+  uint32 flags = 10;
+}
+
+message SummaryDataPoint {
+  repeated opentelemetry.proto.common.v1.KeyValue attributes = 7;
+  fixed64 start_time_unix_nano = 2;
+  fixed64 time_unix_nano = 3;
+  fixed64 count = 4;
+  double sum = 5;
+
+  message ValueAtQuantile {
+    double quantile = 1;
+    double value = 2;
+  }
+  repeated ValueAtQuantile quantile_values = 6;
+  uint32 flags = 8;
+}
+`,
+	"opentelemetry/proto/collector/metrics/v1/metrics_service.proto": `
+syntax = "proto3";
+
+package opentelemetry.proto.collector.metrics.v1;
+
+import "opentelemetry/proto/metrics/v1/metrics.proto";
+
+service MetricsService {
+  rpc Export(ExportMetricsServiceRequest) returns (ExportMetricsServiceResponse) {}
+}
+
+message ExportMetricsServiceRequest {
+  repeated opentelemetry.proto.metrics.v1.ResourceMetrics resource_metrics = 1;
+}
+
+message ExportMetricsServiceResponse {
+}
+`,
+}
+
+var (
+	// MetricsService is the OTLP service receiving metrics.
+	MetricsService *desc.ServiceDescriptor
+
+	// ExportMethod is the full gRPC method name of the Export call of the
+	// MetricsService.
+	ExportMethod string
+
+	messageTypes = make(map[string]*desc.MessageDescriptor)
+)
+
+func init() {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(schema),
+	}
+
+	files := make([]string, 0, len(schema))
+	for name := range schema {
+		files = append(files, name)
+	}
+	fds, err := parser.ParseFiles(files...)
+	if err != nil {
+		panic(fmt.Sprintf("parsing OTLP schema: %v", err))
+	}
+
+	for _, fd := range fds {
+		for _, md := range fd.GetMessageTypes() {
+			messageTypes[md.GetName()] = md
+		}
+		if sd := fd.FindService("opentelemetry.proto.collector.metrics.v1.MetricsService"); sd != nil {
+			MetricsService = sd
+			ExportMethod = "/" + sd.GetFullyQualifiedName() + "/Export"
+		}
+	}
+}
+
+// newMessage creates an empty message of the OTLP message type.
+func newMessage(name string) *dynamic.Message {
+	return dynamic.NewMessage(messageTypes[name])
+}
+
+// NewRequest creates an empty ExportMetricsServiceRequest.
+func NewRequest() *dynamic.Message {
+	return newMessage("ExportMetricsServiceRequest")
+}
+
+// NewResponse creates an empty ExportMetricsServiceResponse.
+func NewResponse() *dynamic.Message {
+	return newMessage("ExportMetricsServiceResponse")
+}
@@ -0,0 +1,47 @@
+package record
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SplitConfluent splits a message in the Confluent wire format, a zero magic
+// byte followed by the schema ID as 32 bit big endian integer and the
+// payload, into the schema ID and payload.
+func SplitConfluent(buf []byte) (int, []byte, error) {
+	if len(buf) < 5 {
+		return 0, nil, fmt.Errorf("message too short for confluent wire format: %d bytes", len(buf))
+	}
+	if buf[0] != 0 {
+		return 0, nil, fmt.Errorf("unknown magic byte %d of confluent wire format", buf[0])
+	}
+	return int(binary.BigEndian.Uint32(buf[1:5])), buf[5:], nil
+}
+
+// SchemaFiles returns the schema files in dir by schema ID.  Schema files are
+// named after the ID of the schema in the registry and have the extension
+// ext, e.g. 42.avsc; other files are ignored.
+func SchemaFiles(dir string, ext string) (map[int]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[int]string)
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || filepath.Ext(name) != ext {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(name, ext))
+		if err != nil {
+			continue
+		}
+		files[id] = filepath.Join(dir, name)
+	}
+	return files, nil
+}
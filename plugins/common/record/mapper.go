@@ -0,0 +1,222 @@
+// Package record converts decoded records of schema based data formats, such
+// as Protocol Buffers and Avro, into metrics.
+package record
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Config selects the metric name, tags, fields and timestamp from records.
+// Keys refer to the flattened record, where the names of nested values are
+// joined with an underscore.
+type Config struct {
+	MetricName   string
+	TagKeys      []string
+	NameKey      string
+	StringFields []string
+	TimeKey      string
+	TimeFormat   string
+	Timezone     string
+	DefaultTags  map[string]string
+}
+
+// Mapper converts records into metrics.
+type Mapper struct {
+	metricName   string
+	tagKeys      filter.Filter
+	stringFields filter.Filter
+	nameKey      string
+	timeKey      string
+	timeFormat   string
+	timezone     string
+	defaultTags  map[string]string
+}
+
+// NewMapper creates a mapper from the config.
+func NewMapper(config *Config) (*Mapper, error) {
+	tagKeys, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, err
+	}
+	stringFields, err := filter.Compile(config.StringFields)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mapper{
+		metricName:   config.MetricName,
+		tagKeys:      tagKeys,
+		stringFields: stringFields,
+		nameKey:      config.NameKey,
+		timeKey:      config.TimeKey,
+		timeFormat:   config.TimeFormat,
+		timezone:     config.Timezone,
+		defaultTags:  config.DefaultTags,
+	}, nil
+}
+
+// SetDefaultTags sets the tags added to all metrics.
+func (m *Mapper) SetDefaultTags(tags map[string]string) {
+	m.defaultTags = tags
+}
+
+// Metric converts the record into a metric.  Values of the record can be
+// nested maps and slices of integers, floats, strings, bytes, booleans and
+// timestamps; values of other types are ignored.  Bytes are base64 encoded
+// and handled as strings, timestamps are unix nanoseconds unless selected as
+// string fields.  The timestamp of the metric is t unless a time key is
+// configured.
+func (m *Mapper) Metric(rec map[string]interface{}, t time.Time) (telegraf.Metric, error) {
+	values := make(map[string]interface{})
+	flatten(values, "", rec)
+
+	name := m.metricName
+	if m.nameKey != "" {
+		if v, ok := values[m.nameKey].(string); ok {
+			name = v
+		}
+	}
+
+	if m.timeKey != "" {
+		v, ok := values[m.timeKey]
+		if !ok {
+			return nil, fmt.Errorf("time key %q could not be found", m.timeKey)
+		}
+
+		switch v := v.(type) {
+		case time.Time:
+			t = v
+		default:
+			if m.timeFormat == "" {
+				return nil, fmt.Errorf("time key %q requires a time format", m.timeKey)
+			}
+			var err error
+			t, err = internal.ParseTimestamp(m.timeFormat, toTimestamp(v), m.timezone)
+			if err != nil {
+				return nil, err
+			}
+		}
+		delete(values, m.timeKey)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range m.defaultTags {
+		tags[k] = v
+	}
+
+	fields := make(map[string]interface{})
+	for k, v := range values {
+		if m.tagKeys != nil && m.tagKeys.Match(k) {
+			tags[k] = toString(v)
+			continue
+		}
+
+		switch v := v.(type) {
+		case string:
+			if m.stringFields != nil && m.stringFields.Match(k) {
+				fields[k] = v
+			}
+		case bool:
+			if m.stringFields != nil && m.stringFields.Match(k) {
+				fields[k] = v
+			}
+		case time.Time:
+			if m.stringFields != nil && m.stringFields.Match(k) {
+				fields[k] = v.Format(time.RFC3339Nano)
+				continue
+			}
+			fields[k] = v.UnixNano()
+		default:
+			fields[k] = v
+		}
+	}
+
+	return metric.New(name, tags, fields, t)
+}
+
+// flatten adds the values of v to values, converting numbers to 64 bit
+// types and bytes to base64 encoded strings.
+func flatten(values map[string]interface{}, key string, v interface{}) {
+	join := func(k string) string {
+		if key == "" {
+			return k
+		}
+		return key + "_" + k
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			flatten(values, join(k), item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(values, join(strconv.Itoa(i)), item)
+		}
+	case int:
+		values[key] = int64(v)
+	case int8:
+		values[key] = int64(v)
+	case int16:
+		values[key] = int64(v)
+	case int32:
+		values[key] = int64(v)
+	case int64:
+		values[key] = v
+	case uint:
+		values[key] = uint64(v)
+	case uint8:
+		values[key] = uint64(v)
+	case uint16:
+		values[key] = uint64(v)
+	case uint32:
+		values[key] = uint64(v)
+	case uint64:
+		values[key] = v
+	case float32:
+		values[key] = float64(v)
+	case float64:
+		values[key] = v
+	case *big.Rat:
+		f, _ := v.Float64()
+		values[key] = f
+	case []byte:
+		values[key] = base64.StdEncoding.EncodeToString(v)
+	case string, bool, time.Time:
+		values[key] = v
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toTimestamp returns the value in a type accepted by ParseTimestamp.
+func toTimestamp(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64, string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,123 @@
+package record
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapperMetric(t *testing.T) {
+	mapper, err := NewMapper(&Config{
+		MetricName:   "record",
+		TagKeys:      []string{"host", "id"},
+		StringFields: []string{"state_*"},
+		TimeKey:      "ts",
+		TimeFormat:   "unix_ms",
+		DefaultTags:  map[string]string{"source": "test"},
+	})
+	require.NoError(t, err)
+
+	m, err := mapper.Metric(map[string]interface{}{
+		"host": "server01",
+		"id":   int32(12),
+		"ts":   int64(1600000000123),
+		"state": map[string]interface{}{
+			"name":    "running",
+			"healthy": true,
+		},
+		"ratio":  big.NewRat(1, 4),
+		"bytes":  []byte("ignored"),
+		"label":  "dropped",
+		"counts": []interface{}{uint32(1), float32(0.5)},
+	}, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"record",
+		map[string]string{
+			"host":   "server01",
+			"id":     "12",
+			"source": "test",
+		},
+		map[string]interface{}{
+			"state_name":    "running",
+			"state_healthy": true,
+			"ratio":         0.25,
+			"counts_0":      uint64(1),
+			"counts_1":      0.5,
+		},
+		time.Unix(0, 1600000000123*int64(time.Millisecond)),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestMapperConvertsTypes(t *testing.T) {
+	mapper, err := NewMapper(&Config{
+		MetricName:   "record",
+		TagKeys:      []string{"tag_*"},
+		StringFields: []string{"payload", "created_string"},
+	})
+	require.NoError(t, err)
+
+	created := time.Date(2020, 9, 13, 12, 26, 40, 5, time.UTC)
+	m, err := mapper.Metric(map[string]interface{}{
+		"int8":           int8(-8),
+		"int16":          int16(-16),
+		"uint":           uint(1),
+		"uint8":          uint8(8),
+		"uint16":         uint16(16),
+		"payload":        []byte{0xff, 0x00, 'a'},
+		"tag_id":         []byte("id"),
+		"created":        created,
+		"created_string": created,
+		"tag_created":    created,
+	}, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"record",
+		map[string]string{
+			"tag_id":      "aWQ=",
+			"tag_created": "2020-09-13T12:26:40.000000005Z",
+		},
+		map[string]interface{}{
+			"int8":           int64(-8),
+			"int16":          int64(-16),
+			"uint":           uint64(1),
+			"uint8":          uint64(8),
+			"uint16":         uint64(16),
+			"payload":        "/wBh",
+			"created":        created.UnixNano(),
+			"created_string": "2020-09-13T12:26:40.000000005Z",
+		},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestMapperMissingTimeKey(t *testing.T) {
+	mapper, err := NewMapper(&Config{
+		MetricName: "record",
+		TimeKey:    "ts",
+		TimeFormat: "unix",
+	})
+	require.NoError(t, err)
+
+	_, err = mapper.Metric(map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	require.Error(t, err)
+}
+
+func TestSplitConfluent(t *testing.T) {
+	id, payload, err := SplitConfluent([]byte{0, 0, 0, 1, 2, 42})
+	require.NoError(t, err)
+	require.Equal(t, 258, id)
+	require.Equal(t, []byte{42}, payload)
+
+	_, _, err = SplitConfluent([]byte{0, 0, 0})
+	require.Error(t, err)
+	_, _, err = SplitConfluent([]byte{1, 0, 0, 0, 1})
+	require.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package starlark
+
+import (
+	"fmt"
+	"math"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// MathModule is a module with the mathematical constants and functions of the
+// Go math package, all functions accept int and float arguments and return a
+// float.
+var MathModule = &starlarkstruct.Module{
+	Name: "math",
+	Members: starlark.StringDict{
+		"pi":  starlark.Float(math.Pi),
+		"e":   starlark.Float(math.E),
+		"inf": starlark.Float(math.Inf(1)),
+		"nan": starlark.Float(math.NaN()),
+
+		"ceil":    mathFunc1("ceil", math.Ceil),
+		"floor":   mathFunc1("floor", math.Floor),
+		"round":   mathFunc1("round", math.Round),
+		"trunc":   mathFunc1("trunc", math.Trunc),
+		"fabs":    mathFunc1("fabs", math.Abs),
+		"sqrt":    mathFunc1("sqrt", math.Sqrt),
+		"exp":     mathFunc1("exp", math.Exp),
+		"log10":   mathFunc1("log10", math.Log10),
+		"log2":    mathFunc1("log2", math.Log2),
+		"sin":     mathFunc1("sin", math.Sin),
+		"cos":     mathFunc1("cos", math.Cos),
+		"tan":     mathFunc1("tan", math.Tan),
+		"asin":    mathFunc1("asin", math.Asin),
+		"acos":    mathFunc1("acos", math.Acos),
+		"atan":    mathFunc1("atan", math.Atan),
+		"degrees": mathFunc1("degrees", func(x float64) float64 { return x * 180 / math.Pi }),
+		"radians": mathFunc1("radians", func(x float64) float64 { return x * math.Pi / 180 }),
+		"pow":     mathFunc2("pow", math.Pow),
+		"atan2":   mathFunc2("atan2", math.Atan2),
+		"hypot":   mathFunc2("hypot", math.Hypot),
+		"mod":     mathFunc2("mod", math.Mod),
+		"log":     starlark.NewBuiltin("math.log", mathLog),
+		"isnan":   starlark.NewBuiltin("math.isnan", mathIsNaN),
+		"isinf":   starlark.NewBuiltin("math.isinf", mathIsInf),
+	},
+}
+
+func mathFunc1(name string, fn func(float64) float64) *starlark.Builtin {
+	return starlark.NewBuiltin("math."+name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &x); err != nil {
+			return nil, err
+		}
+		fx, err := asFloat(b, x)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(fn(fx)), nil
+	})
+}
+
+func mathFunc2(name string, fn func(float64, float64) float64) *starlark.Builtin {
+	return starlark.NewBuiltin("math."+name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x, y starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &x, &y); err != nil {
+			return nil, err
+		}
+		fx, err := asFloat(b, x)
+		if err != nil {
+			return nil, err
+		}
+		fy, err := asFloat(b, y)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(fn(fx, fy)), nil
+	})
+}
+
+// mathLog returns the natural logarithm of x, or the logarithm to the given
+// base.
+func mathLog(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x, base starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &x, &base); err != nil {
+		return nil, err
+	}
+	fx, err := asFloat(b, x)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return starlark.Float(math.Log(fx)), nil
+	}
+	fbase, err := asFloat(b, base)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Float(math.Log(fx) / math.Log(fbase)), nil
+}
+
+func mathIsNaN(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &x); err != nil {
+		return nil, err
+	}
+	fx, err := asFloat(b, x)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Bool(math.IsNaN(fx)), nil
+}
+
+func mathIsInf(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &x); err != nil {
+		return nil, err
+	}
+	fx, err := asFloat(b, x)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Bool(math.IsInf(fx, 0)), nil
+}
+
+// asFloat converts an int or float argument to float64.
+func asFloat(b *starlark.Builtin, x starlark.Value) (float64, error) {
+	f, ok := starlark.AsFloat(x)
+	if !ok {
+		return 0, fmt.Errorf("%s: got %s, want float or int", b.Name(), x.Type())
+	}
+	return f, nil
+}
@@ -0,0 +1,136 @@
+package starlark
+
+import (
+	"regexp"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// RegexModule is a module with regular expression functions using the Go
+// regexp syntax.  Compiled patterns are cached, so it is cheap to call the
+// functions with the same pattern for every metric.
+var RegexModule = &starlarkstruct.Module{
+	Name: "regex",
+	Members: starlark.StringDict{
+		"match":    starlark.NewBuiltin("regex.match", regexMatch),
+		"find":     starlark.NewBuiltin("regex.find", regexFind),
+		"find_all": starlark.NewBuiltin("regex.find_all", regexFindAll),
+		"submatch": starlark.NewBuiltin("regex.submatch", regexSubmatch),
+		"replace":  starlark.NewBuiltin("regex.replace", regexReplace),
+		"split":    starlark.NewBuiltin("regex.split", regexSplit),
+	},
+}
+
+var regexCache sync.Map
+
+// compileRegex returns the compiled pattern from the cache, compiling it on
+// first use.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+func unpackRegex(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (*regexp.Regexp, string, error) {
+	var pattern, s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &s); err != nil {
+		return nil, "", err
+	}
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	return re, s, nil
+}
+
+// regexMatch reports whether the string contains a match of the pattern.
+func regexMatch(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Bool(re.MatchString(s)), nil
+}
+
+// regexFind returns the leftmost match or None.
+func regexFind(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	loc := re.FindStringIndex(s)
+	if loc == nil {
+		return starlark.None, nil
+	}
+	return starlark.String(s[loc[0]:loc[1]]), nil
+}
+
+// regexFindAll returns a list of all matches.
+func regexFindAll(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return stringList(re.FindAllString(s, -1)), nil
+}
+
+// regexSubmatch returns the leftmost match and its groups as list, or None.
+// Groups that did not participate in the match are None.
+func regexSubmatch(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return starlark.None, nil
+	}
+	values := make([]starlark.Value, 0, len(loc)/2)
+	for i := 0; i < len(loc); i += 2 {
+		if loc[i] < 0 {
+			values = append(values, starlark.None)
+			continue
+		}
+		values = append(values, starlark.String(s[loc[i]:loc[i+1]]))
+	}
+	return starlark.NewList(values), nil
+}
+
+// regexReplace replaces all matches of the pattern, the replacement may
+// reference groups using $1 or ${name}.
+func regexReplace(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s, repl string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 3, &pattern, &s, &repl); err != nil {
+		return nil, err
+	}
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.String(re.ReplaceAllString(s, repl)), nil
+}
+
+// regexSplit splits the string around the matches of the pattern.
+func regexSplit(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return stringList(re.Split(s, -1)), nil
+}
+
+func stringList(values []string) *starlark.List {
+	list := make([]starlark.Value, 0, len(values))
+	for _, v := range values {
+		list = append(list, starlark.String(v))
+	}
+	return starlark.NewList(list)
+}
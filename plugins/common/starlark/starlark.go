@@ -0,0 +1,217 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+)
+
+// StarlarkCommon holds the options and the interpreter shared by the
+// plugins running Starlark scripts.
+type StarlarkCommon struct {
+	Source    string `toml:"source"`
+	Script    string `toml:"script"`
+	StateFile string `toml:"state_file"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	// mu serializes the calls of the script and saving the state, the
+	// state may be saved while the plugin runs.
+	mu sync.Mutex
+
+	thread     *starlark.Thread
+	state      *starlark.Dict
+	globals    starlark.StringDict
+	functions  map[string]*starlark.Function
+	parameters map[string]starlark.Tuple
+}
+
+// Init compiles and executes the script.  Functions called by the plugin
+// must be registered afterwards using AddFunction.
+func (s *StarlarkCommon) Init() error {
+	if s.Source == "" && s.Script == "" {
+		return errors.New("one of source or script must be set")
+	}
+	if s.Source != "" && s.Script != "" {
+		return errors.New("both source or script cannot be set")
+	}
+
+	s.thread = &starlark.Thread{
+		Print: func(_ *starlark.Thread, msg string) { s.Log.Debug(msg) },
+		Load: func(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+			return LoadFunc(module, s.Log)
+		},
+	}
+
+	builtins := starlark.StringDict{}
+	builtins["Metric"] = starlark.NewBuiltin("Metric", newMetric)
+	builtins["deepcopy"] = starlark.NewBuiltin("deepcopy", deepcopy)
+	builtins["catch"] = starlark.NewBuiltin("catch", catch)
+
+	// Make available a shared state to the script, the state is restored
+	// before the source is executed so that it can be used at package scope.
+	s.state = starlark.NewDict(0)
+	if s.StateFile != "" {
+		if err := loadState(s.StateFile, s.state); err != nil {
+			return err
+		}
+	}
+	builtins["state"] = s.state
+
+	program, err := s.sourceProgram(builtins)
+	if err != nil {
+		return err
+	}
+
+	// Execute source
+	globals, err := program.Init(s.thread, builtins)
+	if err != nil {
+		return err
+	}
+
+	// Freeze the global state.  This prevents modifications to the plugin
+	// state and prevents scripts from containing errors storing tracking
+	// metrics.  Tasks that require global state should use the shared state
+	// dictionary, a global named state defined by the script is left mutable
+	// for compatibility but is not persisted.
+	for name, value := range globals {
+		if name == "state" {
+			if s.StateFile != "" {
+				s.Log.Warnf("Script defines a global named state, it shadows the shared state and will not be persisted")
+			}
+			continue
+		}
+		value.Freeze()
+	}
+
+	s.globals = globals
+	s.functions = make(map[string]*starlark.Function)
+	s.parameters = make(map[string]starlark.Tuple)
+
+	return nil
+}
+
+func (s *StarlarkCommon) sourceProgram(builtins starlark.StringDict) (*starlark.Program, error) {
+	if s.Source != "" {
+		_, program, err := starlark.SourceProgram("processor.starlark", s.Source, builtins.Has)
+		return program, err
+	}
+	_, program, err := starlark.SourceProgram(s.Script, nil, builtins.Has)
+	return program, err
+}
+
+// AddFunction checks that the script defines the function taking the given
+// parameters.  The parameters are reused for every call of the function and
+// can be updated in place using GetParameters.
+func (s *StarlarkCommon) AddFunction(name string, params ...starlark.Value) error {
+	value := s.globals[name]
+	if value == nil {
+		return fmt.Errorf("%s is not defined", name)
+	}
+
+	fn, ok := value.(*starlark.Function)
+	if !ok {
+		return fmt.Errorf("%s is not a function", name)
+	}
+
+	if fn.NumParams() != len(params) {
+		if len(params) == 1 {
+			return fmt.Errorf("%s function must take one parameter", name)
+		}
+		return fmt.Errorf("%s function must take %d parameters", name, len(params))
+	}
+
+	s.functions[name] = fn
+	s.parameters[name] = params
+	return nil
+}
+
+// GetParameters returns the parameters of the function registered with
+// AddFunction.
+func (s *StarlarkCommon) GetParameters(name string) (starlark.Tuple, bool) {
+	params, ok := s.parameters[name]
+	return params, ok
+}
+
+// Call calls the function with its current parameters.
+func (s *StarlarkCommon) Call(name string) (starlark.Value, error) {
+	fn, ok := s.functions[name]
+	if !ok {
+		return nil, fmt.Errorf("function %q was not added", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return starlark.Call(s.thread, fn, s.parameters[name], nil)
+}
+
+// LogError logs the error, for errors raised by the script the backtrace is
+// logged.
+func (s *StarlarkCommon) LogError(err error) {
+	if err, ok := err.(*starlark.EvalError); ok {
+		for _, line := range strings.Split(err.Backtrace(), "\n") {
+			s.Log.Error(line)
+		}
+	} else {
+		s.Log.Error(err)
+	}
+}
+
+// SaveState writes the shared state to the state file if one is configured.
+// It is safe to call while the plugin runs.
+func (s *StarlarkCommon) SaveState() error {
+	if s.StateFile == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := saveState(s.StateFile, s.state); err != nil {
+		return fmt.Errorf("saving state: %v", err)
+	}
+	return nil
+}
+
+func init() {
+	// https://github.com/bazelbuild/starlark/issues/20
+	resolve.AllowNestedDef = true
+	resolve.AllowLambda = true
+	resolve.AllowFloat = true
+	resolve.AllowSet = true
+	resolve.AllowGlobalReassign = true
+	resolve.AllowRecursion = true
+}
+
+// LoadFunc returns the modules available to the load statement of scripts.
+func LoadFunc(module string, logger telegraf.Logger) (starlark.StringDict, error) {
+	switch module {
+	case "json.star":
+		return starlark.StringDict{
+			"json": starlarkjson.Module,
+		}, nil
+	case "logging.star":
+		return starlark.StringDict{
+			"log": LogModule(logger),
+		}, nil
+	case "math.star":
+		return starlark.StringDict{
+			"math": MathModule,
+		}, nil
+	case "regex.star":
+		return starlark.StringDict{
+			"regex": RegexModule,
+		}, nil
+	case "time.star":
+		return starlark.StringDict{
+			"time": TimeModule,
+		}, nil
+	default:
+		return nil, errors.New("module " + module + " is not available")
+	}
+}
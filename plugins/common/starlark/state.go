@@ -0,0 +1,296 @@
+package starlark
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"go.starlark.net/starlark"
+)
+
+// metricKey is the key of the JSON object used to store a Metric in the
+// state file.
+const metricKey = "$metric"
+
+// loadState reads the state saved by saveState into the dictionary.  A
+// missing file is not an error, the state is left empty.
+func loadState(filename string, state *starlark.Dict) error {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	var data map[string]interface{}
+	if err := dec.Decode(&data); err != nil {
+		return fmt.Errorf("decoding state file %q: %v", filename, err)
+	}
+
+	for k, v := range data {
+		value, err := fromJSONValue(v)
+		if err != nil {
+			return fmt.Errorf("state key %q: %v", k, err)
+		}
+		if err := state.SetKey(starlark.String(k), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveState writes the dictionary as JSON object to the file.  The file is
+// replaced atomically so that a crash does not leave a truncated state.
+func saveState(filename string, state *starlark.Dict) error {
+	data, err := toJSONValue(state)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// jsonFloat is a float that is always encoded with a decimal point or
+// exponent, so that it is restored as float and not as int.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("unsupported float value %v", v)
+	}
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if !bytes.ContainsAny([]byte(s), ".eE") {
+		s += ".0"
+	}
+	return []byte(s), nil
+}
+
+// toJSONValue converts the starlark value into a value that can be encoded
+// by encoding/json.
+func toJSONValue(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if n, ok := v.Int64(); ok {
+			return n, nil
+		}
+		return json.Number(v.String()), nil
+	case starlark.Float:
+		return jsonFloat(v), nil
+	case starlark.String:
+		return string(v), nil
+	case *starlark.List:
+		return toJSONList(v)
+	case starlark.Tuple:
+		return toJSONList(v)
+	case *starlark.Dict:
+		obj := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("unsupported dict key type %s", item[0].Type())
+			}
+			value, err := toJSONValue(item[1])
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %v", string(key), err)
+			}
+			obj[string(key)] = value
+		}
+		return obj, nil
+	case *Metric:
+		return toJSONMetric(v.Unwrap())
+	default:
+		return nil, fmt.Errorf("unsupported type %s", value.Type())
+	}
+}
+
+func toJSONList(list starlark.Indexable) ([]interface{}, error) {
+	values := make([]interface{}, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		value, err := toJSONValue(list.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func toJSONMetric(m telegraf.Metric) (interface{}, error) {
+	fields := make(map[string]interface{}, len(m.FieldList()))
+	for _, field := range m.FieldList() {
+		switch v := field.Value.(type) {
+		case float64:
+			fields[field.Key] = jsonFloat(v)
+		default:
+			fields[field.Key] = v
+		}
+	}
+
+	return map[string]interface{}{
+		metricKey: map[string]interface{}{
+			"name":   m.Name(),
+			"tags":   m.Tags(),
+			"fields": fields,
+			"time":   m.Time().UnixNano(),
+		},
+	}, nil
+}
+
+// fromJSONValue converts a value decoded by encoding/json with numbers
+// enabled into a starlark value.
+func fromJSONValue(value interface{}) (starlark.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case json.Number:
+		return fromJSONNumber(v)
+	case string:
+		return starlark.String(v), nil
+	case []interface{}:
+		values := make([]starlark.Value, 0, len(v))
+		for _, e := range v {
+			value, err := fromJSONValue(e)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return starlark.NewList(values), nil
+	case map[string]interface{}:
+		if m, ok := v[metricKey]; ok && len(v) == 1 {
+			return fromJSONMetric(m)
+		}
+
+		// Insert the keys in a stable order, the iteration order of a
+		// starlark dict is the insertion order.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		dict := starlark.NewDict(len(v))
+		for _, k := range keys {
+			value, err := fromJSONValue(v[k])
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %v", k, err)
+			}
+			if err := dict.SetKey(starlark.String(k), value); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func fromJSONNumber(n json.Number) (starlark.Value, error) {
+	if i, err := n.Int64(); err == nil {
+		return starlark.MakeInt64(i), nil
+	}
+	if b, ok := new(big.Int).SetString(n.String(), 10); ok {
+		return starlark.MakeBigInt(b), nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Float(f), nil
+}
+
+func fromJSONMetric(value interface{}) (starlark.Value, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid metric")
+	}
+
+	name, _ := obj["name"].(string)
+
+	tags := make(map[string]string)
+	if t, ok := obj["tags"].(map[string]interface{}); ok {
+		for k, v := range t {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid value of tag %q", k)
+			}
+			tags[k] = s
+		}
+	}
+
+	fields := make(map[string]interface{})
+	if f, ok := obj["fields"].(map[string]interface{}); ok {
+		for k, v := range f {
+			switch v := v.(type) {
+			case json.Number:
+				if i, err := v.Int64(); err == nil {
+					fields[k] = i
+				} else if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+					fields[k] = u
+				} else if f, err := v.Float64(); err == nil {
+					fields[k] = f
+				} else {
+					return nil, fmt.Errorf("invalid value of field %q", k)
+				}
+			case bool, string:
+				fields[k] = v
+			default:
+				return nil, fmt.Errorf("invalid value of field %q", k)
+			}
+		}
+	}
+
+	var ns int64
+	if n, ok := obj["time"].(json.Number); ok {
+		var err error
+		if ns, err = n.Int64(); err != nil {
+			return nil, fmt.Errorf("invalid metric time: %v", err)
+		}
+	}
+
+	m, err := metric.New(name, tags, fields, time.Unix(0, ns))
+	if err != nil {
+		return nil, err
+	}
+	return &Metric{metric: m}, nil
+}
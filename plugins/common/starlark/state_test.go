@@ -0,0 +1,105 @@
+package starlark
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"go.starlark.net/starlark"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	m := &Metric{}
+	m.Wrap(testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{
+			"int":    int64(-42),
+			"uint":   uint64(1 << 63),
+			"float":  1.0,
+			"string": "a",
+			"bool":   true,
+		},
+		time.Unix(0, 1606000000000000042),
+	))
+
+	state := starlark.NewDict(0)
+	require.NoError(t, state.SetKey(starlark.String("none"), starlark.None))
+	require.NoError(t, state.SetKey(starlark.String("float"), starlark.Float(1)))
+	require.NoError(t, state.SetKey(starlark.String("big"), starlark.MakeUint64(1<<63).Mul(starlark.MakeInt(4))))
+	require.NoError(t, state.SetKey(starlark.String("list"), starlark.NewList([]starlark.Value{starlark.True, starlark.String("a")})))
+	require.NoError(t, state.SetKey(starlark.String("tuple"), starlark.Tuple{starlark.MakeInt(1)}))
+	require.NoError(t, state.SetKey(starlark.String("metric"), m))
+
+	filename := filepath.Join(dir, "state.json")
+	require.NoError(t, saveState(filename, state))
+
+	restored := starlark.NewDict(0)
+	require.NoError(t, loadState(filename, restored))
+
+	get := func(key string) starlark.Value {
+		v, found, err := restored.Get(starlark.String(key))
+		require.NoError(t, err)
+		require.True(t, found, key)
+		return v
+	}
+	require.Equal(t, starlark.None, get("none"))
+	require.Equal(t, starlark.Float(1), get("float"))
+	require.Equal(t, "36893488147419103232", get("big").String())
+	require.Equal(t, `[True, "a"]`, get("list").String())
+	require.Equal(t, `[1]`, get("tuple").String())
+
+	rm, ok := get("metric").(*Metric)
+	require.True(t, ok)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m.Unwrap()}, []telegraf.Metric{rm.Unwrap()})
+}
+
+func TestStateMissingFile(t *testing.T) {
+	state := starlark.NewDict(0)
+	require.NoError(t, loadState("testdata/does_not_exist.json", state))
+	require.Equal(t, 0, state.Len())
+}
+
+func TestStateUnsupportedValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   starlark.Value
+		value starlark.Value
+	}{
+		{
+			name:  "non string key",
+			key:   starlark.MakeInt(1),
+			value: starlark.None,
+		},
+		{
+			name:  "nan",
+			key:   starlark.String("nan"),
+			value: starlark.Float(math.NaN()),
+		},
+		{
+			name:  "builtin",
+			key:   starlark.String("fn"),
+			value: starlark.NewBuiltin("fn", catch),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "starlark")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			state := starlark.NewDict(0)
+			require.NoError(t, state.SetKey(tt.key, tt.value))
+			require.Error(t, saveState(filepath.Join(dir, "state.json"), state))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package starlark
+
+import (
+	"fmt"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// TimeModule is a module for working with timestamps, times are represented
+// as int nanoseconds since the Unix epoch like the metric.time attribute, and
+// durations as int nanoseconds.
+var TimeModule = &starlarkstruct.Module{
+	Name: "time",
+	Members: starlark.StringDict{
+		"nanosecond":  starlark.MakeInt64(int64(time.Nanosecond)),
+		"microsecond": starlark.MakeInt64(int64(time.Microsecond)),
+		"millisecond": starlark.MakeInt64(int64(time.Millisecond)),
+		"second":      starlark.MakeInt64(int64(time.Second)),
+		"minute":      starlark.MakeInt64(int64(time.Minute)),
+		"hour":        starlark.MakeInt64(int64(time.Hour)),
+
+		"now":            starlark.NewBuiltin("time.now", timeNow),
+		"parse_time":     starlark.NewBuiltin("time.parse_time", timeParseTime),
+		"format_time":    starlark.NewBuiltin("time.format_time", timeFormatTime),
+		"parse_duration": starlark.NewBuiltin("time.parse_duration", timeParseDuration),
+	},
+}
+
+// timeNow returns the current time.
+func timeNow(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return starlark.MakeInt64(time.Now().UnixNano()), nil
+}
+
+// timeParseTime parses the string with the Go reference time layout, by
+// default RFC3339.  The location is used for layouts without a zone.
+func timeParseTime(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var value string
+	format := time.RFC3339Nano
+	location := "UTC"
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs,
+		"value", &value, "format?", &format, "location?", &location); err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.ParseInLocation(format, value, loc)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.MakeInt64(t.UnixNano()), nil
+}
+
+// timeFormatTime formats the time with the Go reference time layout in the
+// location.
+func timeFormatTime(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var t starlark.Int
+	format := time.RFC3339Nano
+	location := "UTC"
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs,
+		"time", &t, "format?", &format, "location?", &location); err != nil {
+		return nil, err
+	}
+
+	ns, ok := t.Int64()
+	if !ok {
+		return nil, fmt.Errorf("%s: time out of range", b.Name())
+	}
+
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.String(time.Unix(0, ns).In(loc).Format(format)), nil
+}
+
+// timeParseDuration parses a duration string such as "1h30m".
+func timeParseDuration(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var value string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &value); err != nil {
+		return nil, err
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.MakeInt64(int64(d)), nil
+}
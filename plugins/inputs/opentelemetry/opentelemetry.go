@@ -0,0 +1,157 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/otlp"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // Register GRPC gzip decoder to support compressed requests
+	"google.golang.org/grpc/status"
+)
+
+const sampleConfig = `
+  ## Address and port to listen on for OTLP requests over gRPC.
+  # service_address = "0.0.0.0:4317"
+
+  ## Name of the metrics, the OTLP metric names are used as field keys.
+  # metric_name = "opentelemetry"
+
+  ## Maximum size of a request, larger requests are rejected.
+  # max_msg_size = "4MB"
+
+  ## Optional TLS Config
+  # tls_allowed_cacerts = ["/etc/telegraf/ca.pem"]
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+`
+
+type OpenTelemetry struct {
+	ServiceAddress string        `toml:"service_address"`
+	MetricName     string        `toml:"metric_name"`
+	MaxMsgSize     internal.Size `toml:"max_msg_size"`
+	tls.ServerConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	acc      telegraf.Accumulator
+	server   *grpc.Server
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Receive OpenTelemetry metrics over gRPC"
+}
+
+func (o *OpenTelemetry) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (o *OpenTelemetry) Start(acc telegraf.Accumulator) error {
+	o.acc = acc
+
+	var opts []grpc.ServerOption
+	tlsConfig, err := o.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	if o.MaxMsgSize.Size > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(int(o.MaxMsgSize.Size)))
+	}
+
+	o.listener, err = net.Listen("tcp", o.ServiceAddress)
+	if err != nil {
+		return err
+	}
+
+	o.server = grpc.NewServer(opts...)
+	o.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: otlp.MetricsService.GetFullyQualifiedName(),
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Export",
+				Handler:    o.handleExport,
+			},
+		},
+		Metadata: otlp.MetricsService.GetFile().GetName(),
+	}, o)
+
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		if err := o.server.Serve(o.listener); err != nil {
+			o.acc.AddError(fmt.Errorf("serving gRPC failed: %v", err))
+		}
+	}()
+
+	o.Log.Infof("Listening on %s", o.listener.Addr())
+	return nil
+}
+
+// handleExport is the gRPC handler of the Export method of the metrics
+// service, the messages are decoded using the OTLP schema.
+func (o *OpenTelemetry) handleExport(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := otlp.NewRequest()
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return o.export(ctx, req)
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     o,
+		FullMethod: otlp.ExportMethod,
+	}
+	return interceptor(ctx, req, info, handler)
+}
+
+func (o *OpenTelemetry) export(_ context.Context, req interface{}) (interface{}, error) {
+	metrics, err := otlp.ToMetrics(req.(*dynamic.Message), o.MetricName, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "converting metrics failed: %v", err)
+	}
+
+	for _, m := range metrics {
+		o.acc.AddMetric(m)
+	}
+	return otlp.NewResponse(), nil
+}
+
+func (o *OpenTelemetry) Stop() {
+	if o.server != nil {
+		o.server.GracefulStop()
+	}
+	o.wg.Wait()
+}
+
+func init() {
+	inputs.Add("opentelemetry", func() telegraf.Input {
+		return &OpenTelemetry{
+			ServiceAddress: "0.0.0.0:4317",
+			MetricName:     "opentelemetry",
+		}
+	})
+}
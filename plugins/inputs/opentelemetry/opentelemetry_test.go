@@ -0,0 +1,156 @@
+package opentelemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/otlp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+)
+
+func newOpenTelemetry(t *testing.T, acc telegraf.Accumulator) *OpenTelemetry {
+	plugin := &OpenTelemetry{
+		ServiceAddress: "127.0.0.1:0",
+		MetricName:     "opentelemetry",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Start(acc))
+	return plugin
+}
+
+func dial(t *testing.T, plugin *OpenTelemetry) *grpc.ClientConn {
+	conn, err := grpc.Dial(plugin.listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	return conn
+}
+
+func TestExport(t *testing.T) {
+	acc := &testutil.Accumulator{}
+	plugin := newOpenTelemetry(t, acc)
+	defer plugin.Stop()
+
+	conn := dial(t, plugin)
+	defer conn.Close()
+
+	now := time.Unix(1600000000, 0)
+	input := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5},
+			now,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"latency_sum": 12.5, "latency_count": 4.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "a", "le": "0.5"},
+			map[string]interface{}{"latency_bucket": 1.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "a", "le": "+Inf"},
+			map[string]interface{}{"latency_bucket": 4.0},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"rpc_sum": 3.0, "rpc_count": 2.0},
+			now,
+			telegraf.Summary,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"host": "a", "quantile": "0.5"},
+			map[string]interface{}{"rpc": 1.5},
+			now,
+			telegraf.Summary,
+		),
+	}
+	req, err := otlp.FromMetrics(input, []string{"host"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = conn.Invoke(ctx, otlp.ExportMethod, req, otlp.NewResponse(), grpc.UseCompressor(gzip.Name))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"opentelemetry",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"cpu_usage_idle": 91.5},
+			now,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"opentelemetry",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"latency_sum": 12.5, "latency_count": uint64(4)},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"opentelemetry",
+			map[string]string{"host": "a", "le": "0.5"},
+			map[string]interface{}{"latency_bucket": uint64(1)},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"opentelemetry",
+			map[string]string{"host": "a", "le": "+Inf"},
+			map[string]interface{}{"latency_bucket": uint64(4)},
+			now,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"opentelemetry",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"rpc_sum": 3.0, "rpc_count": uint64(2)},
+			now,
+			telegraf.Summary,
+		),
+		testutil.MustMetric(
+			"opentelemetry",
+			map[string]string{"host": "a", "quantile": "0.5"},
+			map[string]interface{}{"rpc": 1.5},
+			now,
+			telegraf.Summary,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestUnknownMethod(t *testing.T) {
+	acc := &testutil.Accumulator{}
+	plugin := newOpenTelemetry(t, acc)
+	defer plugin.Stop()
+
+	conn := dial(t, plugin)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	method := "/" + otlp.MetricsService.GetFullyQualifiedName() + "/Unknown"
+	err := conn.Invoke(ctx, method, otlp.NewRequest(), otlp.NewResponse())
+	require.Error(t, err)
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,15 @@
+package sql
+
+import (
+	// Blank imports to register the drivers
+	_ "github.com/ClickHouse/clickhouse-go"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+// driverAliases maps alternative names to the names of the registered drivers.
+var driverAliases = map[string]string{
+	"postgres":   "pgx",
+	"postgresql": "pgx",
+	"sqlite3":    "sqlite",
+}
@@ -0,0 +1,8 @@
+// +build linux
+// +build 386 amd64 arm arm64
+
+package sql
+
+// The SQLite driver is only available on the platforms supported by the
+// modernc.org/sqlite package.
+import _ "modernc.org/sqlite" // register driver
@@ -0,0 +1,154 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *SQL
+	}{
+		{
+			name: "unknown driver",
+			plugin: &SQL{
+				Driver:  "oracle",
+				Dsn:     "dsn",
+				Timeout: internal.Duration{Duration: time.Second},
+				Queries: []Query{{Query: "SELECT 1"}},
+			},
+		},
+		{
+			name: "missing dsn",
+			plugin: &SQL{
+				Driver:  "mysql",
+				Timeout: internal.Duration{Duration: time.Second},
+				Queries: []Query{{Query: "SELECT 1"}},
+			},
+		},
+		{
+			name: "no queries",
+			plugin: &SQL{
+				Driver:  "mysql",
+				Dsn:     "dsn",
+				Timeout: internal.Duration{Duration: time.Second},
+			},
+		},
+		{
+			name: "query and script",
+			plugin: &SQL{
+				Driver:  "mysql",
+				Dsn:     "dsn",
+				Timeout: internal.Duration{Duration: time.Second},
+				Queries: []Query{{Query: "SELECT 1", Script: "testdata/query.sql"}},
+			},
+		},
+		{
+			name: "empty query",
+			plugin: &SQL{
+				Driver:  "mysql",
+				Dsn:     "dsn",
+				Timeout: internal.Duration{Duration: time.Second},
+				Queries: []Query{{Query: " "}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
+
+func TestInitScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "query.sql")
+	require.NoError(t, ioutil.WriteFile(script, []byte("SELECT 1"), 0644))
+
+	plugin := &SQL{
+		Driver:  "postgres",
+		Dsn:     "postgres://localhost/db",
+		Timeout: internal.Duration{Duration: time.Second},
+		Queries: []Query{{Script: script}},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "pgx", plugin.driverName)
+	require.Equal(t, "SELECT 1", plugin.Queries[0].Query)
+	require.Equal(t, "sql", plugin.Queries[0].Measurement)
+	require.Equal(t, "unix", plugin.Queries[0].TimeFormat)
+}
+
+func TestQueryDue(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	q := Query{}
+	require.True(t, q.due(now))
+	q.lastRun = now
+	require.True(t, q.due(now))
+
+	q.Interval.Duration = time.Minute
+	require.False(t, q.due(now.Add(30*time.Second)))
+	require.True(t, q.due(now.Add(59*time.Second+900*time.Millisecond)))
+	require.True(t, q.due(now.Add(2*time.Minute)))
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		convert  func(interface{}) (interface{}, error)
+		input    interface{}
+		expected interface{}
+	}{
+		{"float from string", toFloat, "4.5", 4.5},
+		{"float from int", toFloat, int64(4), 4.0},
+		{"float from bool", toFloat, true, 1.0},
+		{"int from string", toInt, "-42", int64(-42)},
+		{"int from float", toInt, 42.9, int64(42)},
+		{"int from uint", toInt, uint64(42), int64(42)},
+		{"int from time", toInt, time.Unix(0, 42), int64(42)},
+		{"uint from string", toUint, "18446744073709551615", uint64(18446744073709551615)},
+		{"uint from int", toUint, int64(42), uint64(42)},
+		{"bool from string", toBool, "true", true},
+		{"bool from int", toBool, int64(0), false},
+		{"string from int", toString, int64(42), "42"},
+		{"string from float", toString, 4.25, "4.25"},
+		{"string from time", toString, time.Unix(0, 0).UTC(), "1970-01-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.convert(tt.input)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+
+	_, err := toInt(uint64(1 << 63))
+	require.Error(t, err)
+	_, err = toUint(int64(-1))
+	require.Error(t, err)
+	_, err = toFloat("abc")
+	require.Error(t, err)
+}
+
+func TestNormalize(t *testing.T) {
+	require.Nil(t, normalize(nil))
+	require.Equal(t, "abc", normalize([]byte("abc")))
+	require.Equal(t, int64(-8), normalize(int8(-8)))
+	require.Equal(t, uint64(32), normalize(uint32(32)))
+	require.Equal(t, float64(1.5), normalize(float32(1.5)))
+}
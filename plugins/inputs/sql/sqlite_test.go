@@ -0,0 +1,199 @@
+//go:build linux && (386 || amd64 || arm || arm64)
+// +build linux
+// +build 386 amd64 arm arm64
+
+package sql
+
+import (
+	dbsql "database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const schema = `
+CREATE TABLE scoreboard (
+	application TEXT,
+	player      TEXT,
+	score       INTEGER,
+	latency     REAL,
+	online      BOOLEAN,
+	ratio       TEXT,
+	updated     INTEGER
+);
+INSERT INTO scoreboard VALUES ('chess', 'alice', 1500, 12.5, 1, '0.75', 1600000000);
+INSERT INTO scoreboard VALUES ('chess', 'bob', 1400, NULL, 0, '0.5', 1600000060);
+INSERT INTO scoreboard VALUES ('go', 'carol', 2000, 20.25, 1, '0.25', 1600000120);
+`
+
+func newSQLite(t *testing.T, queries ...Query) (*SQL, func()) {
+	dir, err := ioutil.TempDir("", "sql")
+	require.NoError(t, err)
+
+	dsn := filepath.Join(dir, "test.db")
+	db, err := dbsql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	plugin := &SQL{
+		Driver:             "sqlite",
+		Dsn:                dsn,
+		Timeout:            internal.Duration{Duration: 5 * time.Second},
+		MaxIdleConnections: 2,
+		Queries:            queries,
+		Log:                testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(nil))
+
+	return plugin, func() {
+		plugin.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSQLiteQuery(t *testing.T) {
+	plugin, cleanup := newSQLite(t, Query{
+		Query:               "SELECT * FROM scoreboard ORDER BY player",
+		Measurement:         "scores",
+		TimeColumn:          "updated",
+		TagColumnsInclude:   []string{"application", "player"},
+		FieldColumnsExclude: []string{"online"},
+		FieldColumnsFloat:   []string{"ratio"},
+	})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("scores",
+			map[string]string{"application": "chess", "player": "alice"},
+			map[string]interface{}{"score": int64(1500), "latency": 12.5, "ratio": 0.75},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric("scores",
+			map[string]string{"application": "chess", "player": "bob"},
+			map[string]interface{}{"score": int64(1400), "ratio": 0.5},
+			time.Unix(1600000060, 0),
+		),
+		testutil.MustMetric("scores",
+			map[string]string{"application": "go", "player": "carol"},
+			map[string]interface{}{"score": int64(2000), "latency": 20.25, "ratio": 0.25},
+			time.Unix(1600000120, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestSQLiteMeasurementColumn(t *testing.T) {
+	plugin, cleanup := newSQLite(t, Query{
+		Query:             "SELECT application, COUNT(*) AS players, SUM(online) AS online FROM scoreboard GROUP BY application",
+		MeasurementColumn: "application",
+		FieldColumnsBool:  []string{"online"},
+	})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("chess",
+			map[string]string{},
+			map[string]interface{}{"players": int64(2), "online": true},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("go",
+			map[string]string{},
+			map[string]interface{}{"players": int64(1), "online": true},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestSQLiteMultipleQueries(t *testing.T) {
+	plugin, cleanup := newSQLite(t,
+		Query{
+			Query:       "SELECT COUNT(*) AS players FROM scoreboard",
+			Measurement: "players",
+		},
+		Query{
+			Query:       "SELECT MAX(score) AS highscore FROM scoreboard",
+			Measurement: "highscore",
+			Interval:    internal.Duration{Duration: time.Hour},
+		},
+	)
+	defer cleanup()
+
+	// The second query is only executed on the first gather.
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("highscore",
+			map[string]string{},
+			map[string]interface{}{"highscore": int64(2000)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("players",
+			map[string]string{},
+			map[string]interface{}{"players": int64(3)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("players",
+			map[string]string{},
+			map[string]interface{}{"players": int64(3)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestSQLiteQueryError(t *testing.T) {
+	plugin, cleanup := newSQLite(t, Query{
+		Query: "SELECT * FROM unknown",
+	})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestSQLiteTimeFormat(t *testing.T) {
+	plugin, cleanup := newSQLite(t, Query{
+		Query:             "SELECT player, '2020-09-13 12:26:40' AS time, score FROM scoreboard WHERE player = 'alice'",
+		TimeColumn:        "time",
+		TimeFormat:        "2006-01-02 15:04:05",
+		TagColumnsInclude: []string{"player"},
+	})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sql",
+			map[string]string{"player": "alice"},
+			map[string]interface{}{"score": int64(1500)},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -220,12 +221,39 @@ func (h *HTTP) write(reqBody []byte) error {
 	_, err = ioutil.ReadAll(resp.Body)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("when writing to [%s] received status code: %d", h.URL, resp.StatusCode)
+		return &statusError{url: h.URL, statusCode: resp.StatusCode}
 	}
 
 	return nil
 }
 
+// IsPermanentError reports client errors as permanent, the server will reject
+// the same request again.  Timeouts, rate limiting and authentication errors
+// are considered transient.
+func (h *HTTP) IsPermanentError(err error) bool {
+	var serr *statusError
+	if !errors.As(err, &serr) {
+		return false
+	}
+
+	switch serr.statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return serr.statusCode >= 400 && serr.statusCode < 500
+}
+
+// statusError is returned when the server responds with a non-2xx status.
+type statusError struct {
+	url        string
+	statusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("when writing to [%s] received status code: %d", e.url, e.statusCode)
+}
+
 func init() {
 	outputs.Add("http", func() telegraf.Output {
 		return &HTTP{
@@ -183,6 +183,64 @@ func TestStatusCode(t *testing.T) {
 	}
 }
 
+func TestPermanentError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s", ts.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		statusCode int
+		permanent  bool
+	}{
+		{
+			name:       "bad request is permanent",
+			statusCode: http.StatusBadRequest,
+			permanent:  true,
+		},
+		{
+			name:       "payload too large is permanent",
+			statusCode: http.StatusRequestEntityTooLarge,
+			permanent:  true,
+		},
+		{
+			name:       "rate limiting is transient",
+			statusCode: http.StatusTooManyRequests,
+		},
+		{
+			name:       "unauthorized is transient",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "server error is transient",
+			statusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			})
+
+			plugin := &HTTP{
+				URL: u.String(),
+			}
+			plugin.SetSerializer(influx.NewSerializer())
+			require.NoError(t, plugin.Connect())
+
+			err = plugin.Write([]telegraf.Metric{getMetric()})
+			require.Error(t, err)
+			require.Equal(t, tt.permanent, plugin.IsPermanentError(err))
+		})
+	}
+
+	plugin := &HTTP{}
+	require.False(t, plugin.IsPermanentError(fmt.Errorf("connection refused")))
+}
+
 func TestContentType(t *testing.T) {
 	ts := httptest.NewServer(http.NotFoundHandler())
 	defer ts.Close()
@@ -0,0 +1,429 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	defaultEndpoint      = "/loki/api/v1/push"
+	defaultClientTimeout = 5 * time.Second
+)
+
+var sampleConfig = `
+  ## The domain of Loki
+  domain = "https://loki.domain.tld"
+
+  ## Endpoint to write api
+  # endpoint = "/loki/api/v1/push"
+
+  ## Connection timeout, defaults to "5s" if not set.
+  # timeout = "5s"
+
+  ## Basic auth credential
+  # username = "loki"
+  # password = "pass"
+
+  ## Tenant ID sent in the X-Scope-OrgID header for multi-tenant setups.
+  # tenant_id = ""
+
+  ## Additional HTTP headers
+  # http_headers = {"X-Custom-Header" = "value"}
+
+  ## Format of the push request, either "protobuf" (snappy compressed) or
+  ## "json".
+  # format = "protobuf"
+
+  ## Compress JSON requests with gzip, protobuf requests are always compressed
+  ## with snappy.
+  # gzip_request = false
+
+  ## Label holding the name of the metric, set to an empty string to omit the
+  ## name.
+  # metric_name_label = "__name"
+
+  ## Field used as log line.  If not set or missing in a metric, the line is
+  ## the logfmt encoding of all fields of the metric.
+  # line_field = ""
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type Loki struct {
+	Domain          string            `toml:"domain"`
+	Endpoint        string            `toml:"endpoint"`
+	Timeout         internal.Duration `toml:"timeout"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	TenantID        string            `toml:"tenant_id"`
+	Headers         map[string]string `toml:"http_headers"`
+	Format          string            `toml:"format"`
+	GZipRequest     bool              `toml:"gzip_request"`
+	MetricNameLabel string            `toml:"metric_name_label"`
+	LineField       string            `toml:"line_field"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	url    string
+	client *http.Client
+}
+
+// entry is a log line of a stream.
+type entry struct {
+	timestamp time.Time
+	line      string
+}
+
+// stream holds the entries sharing the same set of labels.
+type stream struct {
+	labels  []telegraf.Tag
+	entries []entry
+}
+
+func (l *Loki) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Loki) Description() string {
+	return "Send logs to Loki"
+}
+
+func (l *Loki) Init() error {
+	if l.Domain == "" {
+		return errors.New("domain is required")
+	}
+
+	switch l.Format {
+	case "":
+		l.Format = "protobuf"
+	case "protobuf", "json":
+	default:
+		return fmt.Errorf("unknown format %q", l.Format)
+	}
+
+	if l.Endpoint == "" {
+		l.Endpoint = defaultEndpoint
+	}
+	l.url = strings.TrimRight(l.Domain, "/") + l.Endpoint
+
+	if l.Timeout.Duration == 0 {
+		l.Timeout.Duration = defaultClientTimeout
+	}
+
+	return nil
+}
+
+func (l *Loki) Connect() error {
+	tlsCfg, err := l.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	l.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: l.Timeout.Duration,
+	}
+	return nil
+}
+
+func (l *Loki) Close() error {
+	if l.client != nil {
+		l.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (l *Loki) Write(metrics []telegraf.Metric) error {
+	streams := l.streams(metrics)
+	if len(streams) == 0 {
+		return nil
+	}
+
+	var body []byte
+	var err error
+	switch l.Format {
+	case "json":
+		body, err = encodeJSON(streams)
+	default:
+		body, err = encodeProtobuf(streams)
+	}
+	if err != nil {
+		return err
+	}
+	return l.write(body)
+}
+
+// streams groups the metrics by their labels, the entries of each stream
+// are sorted by time as required by Loki.
+func (l *Loki) streams(metrics []telegraf.Metric) []*stream {
+	var streams []*stream
+	index := make(map[string]*stream)
+	for _, m := range metrics {
+		labels := l.labels(m)
+		key := labelString(labels)
+
+		s, ok := index[key]
+		if !ok {
+			s = &stream{labels: labels}
+			index[key] = s
+			streams = append(streams, s)
+		}
+
+		line, err := l.line(m)
+		if err != nil {
+			l.Log.Errorf("Encoding metric %q failed: %v", m.Name(), err)
+			continue
+		}
+		s.entries = append(s.entries, entry{timestamp: m.Time(), line: line})
+	}
+
+	for _, s := range streams {
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].timestamp.Before(s.entries[j].timestamp)
+		})
+	}
+	return streams
+}
+
+// labels returns the sorted labels of the metric, the tag keys are
+// sanitized to valid label names.
+func (l *Loki) labels(m telegraf.Metric) []telegraf.Tag {
+	labels := make([]telegraf.Tag, 0, len(m.TagList())+1)
+	if l.MetricNameLabel != "" {
+		labels = append(labels, telegraf.Tag{Key: sanitizeLabelName(l.MetricNameLabel), Value: m.Name()})
+	}
+	for _, tag := range m.TagList() {
+		labels = append(labels, telegraf.Tag{Key: sanitizeLabelName(tag.Key), Value: tag.Value})
+	}
+	sort.SliceStable(labels, func(i, j int) bool { return labels[i].Key < labels[j].Key })
+	return labels
+}
+
+// line returns the log line of the metric.
+func (l *Loki) line(m telegraf.Metric) (string, error) {
+	if l.LineField != "" {
+		if v, ok := m.GetField(l.LineField); ok {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := logfmt.NewEncoder(&buf)
+	fields := append([]*telegraf.Field(nil), m.FieldList()...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	for _, field := range fields {
+		if err := enc.EncodeKeyval(field.Key, field.Value); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// sanitizeLabelName replaces the characters not allowed in label names with
+// an underscore.
+func sanitizeLabelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			return r
+		case r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// labelString returns the labels in the Prometheus format used by the
+// protobuf push API, such as {host="a", job="b"}.
+func labelString(labels []telegraf.Tag) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(label.Key)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(label.Value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func encodeProtobuf(streams []*stream) ([]byte, error) {
+	req := newMessage("PushRequest")
+	for _, s := range streams {
+		sm := newMessage("StreamAdapter")
+		sm.SetFieldByName("labels", labelString(s.labels))
+		for _, e := range s.entries {
+			em := newMessage("EntryAdapter")
+			em.SetFieldByName("timestamp", &timestamp.Timestamp{
+				Seconds: e.timestamp.Unix(),
+				Nanos:   int32(e.timestamp.Nanosecond()),
+			})
+			em.SetFieldByName("line", e.line)
+			sm.AddRepeatedFieldByName("entries", em)
+		}
+		req.AddRepeatedFieldByName("streams", sm)
+	}
+
+	buf, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, buf), nil
+}
+
+type jsonRequest struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func encodeJSON(streams []*stream) ([]byte, error) {
+	req := jsonRequest{Streams: make([]jsonStream, 0, len(streams))}
+	for _, s := range streams {
+		js := jsonStream{
+			Stream: make(map[string]string, len(s.labels)),
+			Values: make([][2]string, 0, len(s.entries)),
+		}
+		for _, label := range s.labels {
+			js.Stream[label.Key] = label.Value
+		}
+		for _, e := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.timestamp.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, js)
+	}
+	return json.Marshal(req)
+}
+
+func (l *Loki) write(body []byte) error {
+	var reader io.Reader = bytes.NewReader(body)
+	if l.Format == "json" && l.GZipRequest {
+		rc, err := internal.CompressWithGzip(reader)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		reader = rc
+	}
+
+	req, err := http.NewRequest(http.MethodPost, l.url, reader)
+	if err != nil {
+		return err
+	}
+
+	if l.Username != "" || l.Password != "" {
+		req.SetBasicAuth(l.Username, l.Password)
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+	switch l.Format {
+	case "json":
+		req.Header.Set("Content-Type", "application/json")
+		if l.GZipRequest {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+	default:
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	}
+	if l.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.TenantID)
+	}
+	for k, v := range l.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{
+			url:        l.url,
+			statusCode: resp.StatusCode,
+			message:    strings.TrimSpace(string(msg)),
+		}
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+// IsPermanentError reports client errors as permanent, such as entries
+// rejected for being out of order or too old.  Timeouts, rate limiting and
+// authentication errors are considered transient.
+func (l *Loki) IsPermanentError(err error) bool {
+	var serr *statusError
+	if !errors.As(err, &serr) {
+		return false
+	}
+
+	switch serr.statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return serr.statusCode >= 400 && serr.statusCode < 500
+}
+
+// statusError is returned when the server responds with a non-2xx status.
+type statusError struct {
+	url        string
+	statusCode int
+	message    string
+}
+
+func (e *statusError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("when writing to [%s] received status code: %d", e.url, e.statusCode)
+	}
+	return fmt.Sprintf("when writing to [%s] received status code: %d: %s", e.url, e.statusCode, e.message)
+}
+
+func init() {
+	outputs.Add("loki", func() telegraf.Output {
+		return &Loki{
+			Timeout:         internal.Duration{Duration: defaultClientTimeout},
+			MetricNameLabel: "__name",
+		}
+	})
+}
@@ -0,0 +1,230 @@
+package loki
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/require"
+)
+
+func getMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("log",
+			map[string]string{"host": "a", "app.name": "web"},
+			map[string]interface{}{"message": "second", "level": "info"},
+			time.Unix(1600000002, 0),
+		),
+		testutil.MustMetric("log",
+			map[string]string{"host": "a", "app.name": "web"},
+			map[string]interface{}{"message": "first", "level": "info"},
+			time.Unix(1600000001, 0),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"usage_idle": 91.5, "running": true},
+			time.Unix(1600000000, 500),
+		),
+	}
+}
+
+func newLoki(url string) *Loki {
+	return &Loki{
+		Domain:          url,
+		MetricNameLabel: "__name",
+		Log:             testutil.Logger{},
+	}
+}
+
+func TestInit(t *testing.T) {
+	l := newLoki("")
+	require.Error(t, l.Init())
+
+	l = newLoki("http://localhost:3100/")
+	l.Format = "xml"
+	require.Error(t, l.Init())
+
+	l = newLoki("http://localhost:3100/")
+	require.NoError(t, l.Init())
+	require.Equal(t, "http://localhost:3100/loki/api/v1/push", l.url)
+	require.Equal(t, "protobuf", l.Format)
+	require.Equal(t, defaultClientTimeout, l.Timeout.Duration)
+}
+
+func TestStreams(t *testing.T) {
+	l := newLoki("http://localhost:3100")
+	l.LineField = "message"
+	require.NoError(t, l.Init())
+
+	streams := l.streams(getMetrics())
+	require.Len(t, streams, 2)
+
+	require.Equal(t, `{__name="log", app_name="web", host="a"}`, labelString(streams[0].labels))
+	require.Equal(t, []entry{
+		{timestamp: time.Unix(1600000001, 0), line: "first"},
+		{timestamp: time.Unix(1600000002, 0), line: "second"},
+	}, streams[0].entries)
+
+	// Metrics without the line field are encoded as logfmt.
+	require.Equal(t, `{__name="cpu", host="b"}`, labelString(streams[1].labels))
+	require.Equal(t, []entry{
+		{timestamp: time.Unix(1600000000, 500), line: "running=true usage_idle=91.5"},
+	}, streams[1].entries)
+}
+
+func TestStreamsWithoutName(t *testing.T) {
+	l := newLoki("http://localhost:3100")
+	l.MetricNameLabel = ""
+	require.NoError(t, l.Init())
+
+	streams := l.streams(getMetrics()[:1])
+	require.Len(t, streams, 1)
+	require.Equal(t, `{app_name="web", host="a"}`, labelString(streams[0].labels))
+	require.Equal(t, `level=info message=second`, streams[0].entries[0].line)
+}
+
+func TestWriteProtobuf(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	l := newLoki(ts.URL)
+	l.TenantID = "tenant"
+	l.Username = "user"
+	l.Password = "secret"
+	l.Headers = map[string]string{"X-Test": "value"}
+	require.NoError(t, l.Init())
+	require.NoError(t, l.Connect())
+	require.NoError(t, l.Write(getMetrics()))
+
+	require.Equal(t, "/loki/api/v1/push", received.URL.Path)
+	require.Equal(t, "application/x-protobuf", received.Header.Get("Content-Type"))
+	require.Equal(t, "tenant", received.Header.Get("X-Scope-OrgID"))
+	require.Equal(t, "value", received.Header.Get("X-Test"))
+	username, password, ok := received.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", username)
+	require.Equal(t, "secret", password)
+
+	buf, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+	req := newMessage("PushRequest")
+	require.NoError(t, req.Unmarshal(buf))
+
+	streams := req.GetFieldByName("streams").([]interface{})
+	require.Len(t, streams, 2)
+
+	type decoded struct {
+		labels string
+		time   time.Time
+		line   string
+	}
+	var actual []decoded
+	for _, s := range streams {
+		sm := s.(*dynamic.Message)
+		for _, e := range sm.GetFieldByName("entries").([]interface{}) {
+			em := e.(*dynamic.Message)
+			ts := em.GetFieldByName("timestamp").(*timestamp.Timestamp)
+			actual = append(actual, decoded{
+				labels: sm.GetFieldByName("labels").(string),
+				time:   time.Unix(ts.Seconds, int64(ts.Nanos)),
+				line:   em.GetFieldByName("line").(string),
+			})
+		}
+	}
+	require.Equal(t, []decoded{
+		{`{__name="log", app_name="web", host="a"}`, time.Unix(1600000001, 0), "level=info message=first"},
+		{`{__name="log", app_name="web", host="a"}`, time.Unix(1600000002, 0), "level=info message=second"},
+		{`{__name="cpu", host="b"}`, time.Unix(1600000000, 500), "running=true usage_idle=91.5"},
+	}, actual)
+}
+
+func TestWriteJSON(t *testing.T) {
+	var received *http.Request
+	var actual jsonRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(gz).Decode(&struct {
+			Streams *[]jsonStream `json:"streams"`
+		}{&actual.Streams}))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	l := newLoki(ts.URL)
+	l.Format = "json"
+	l.GZipRequest = true
+	l.LineField = "message"
+	require.NoError(t, l.Init())
+	require.NoError(t, l.Connect())
+	require.NoError(t, l.Write(getMetrics()))
+
+	require.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	require.Equal(t, "gzip", received.Header.Get("Content-Encoding"))
+	require.Empty(t, received.Header.Get("X-Scope-OrgID"))
+	require.Equal(t, []jsonStream{
+		{
+			Stream: map[string]string{"__name": "log", "app_name": "web", "host": "a"},
+			Values: [][2]string{
+				{"1600000001000000000", "first"},
+				{"1600000002000000000", "second"},
+			},
+		},
+		{
+			Stream: map[string]string{"__name": "cpu", "host": "b"},
+			Values: [][2]string{
+				{"1600000000000000500", "running=true usage_idle=91.5"},
+			},
+		},
+	}, actual.Streams)
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		permanent  bool
+	}{
+		{"bad request", http.StatusBadRequest, true},
+		{"rate limited", http.StatusTooManyRequests, false},
+		{"unauthorized", http.StatusUnauthorized, false},
+		{"server error", http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte("entry out of order"))
+			}))
+			defer ts.Close()
+
+			l := newLoki(ts.URL)
+			require.NoError(t, l.Init())
+			require.NoError(t, l.Connect())
+
+			err := l.Write(getMetrics())
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "entry out of order")
+			require.Equal(t, tt.permanent, l.IsPermanentError(err))
+		})
+	}
+}
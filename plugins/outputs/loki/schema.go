@@ -0,0 +1,59 @@
+package loki
+
+import (
+	"fmt"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// schema contains the parts of the Loki push API definitions required for
+// pushing log entries.
+var schema = map[string]string{
+	"logproto/push.proto": `
+syntax = "proto3";
+
+package logproto;
+
+import "google/protobuf/timestamp.proto";
+
+message PushRequest {
+  repeated StreamAdapter streams = 1;
+}
+
+message StreamAdapter {
+  string labels = 1;
+  repeated EntryAdapter entries = 2;
+}
+
+message EntryAdapter {
+  google.protobuf.Timestamp timestamp = 1;
+  string line = 2;
+}
+`,
+}
+
+var messageTypes = make(map[string]*desc.MessageDescriptor)
+
+func init() {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(schema),
+	}
+
+	fds, err := parser.ParseFiles("logproto/push.proto")
+	if err != nil {
+		panic(fmt.Sprintf("parsing Loki schema: %v", err))
+	}
+
+	for _, fd := range fds {
+		for _, md := range fd.GetMessageTypes() {
+			messageTypes[md.GetName()] = md
+		}
+	}
+}
+
+// newMessage creates an empty message of the Loki message type.
+func newMessage(name string) *dynamic.Message {
+	return dynamic.NewMessage(messageTypes[name])
+}
@@ -0,0 +1,155 @@
+package opentelemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/otlp"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const sampleConfig = `
+  ## Address and port of the OpenTelemetry gRPC receiver.
+  # service_address = "localhost:4317"
+
+  ## Timeout of an export request.
+  # timeout = "5s"
+
+  ## Compression of the requests, either "gzip" or "none".
+  # compression = "gzip"
+
+  ## Tags sent as resource attributes, the other tags are sent as attributes
+  ## of the data points.
+  # resource_tags = ["host"]
+
+  ## Additional gRPC request metadata
+  # [outputs.opentelemetry.headers]
+  #   key1 = "value1"
+
+  ## Optional TLS Config
+  ## Use TLS with the system certificate authorities, TLS is also enabled if
+  ## any of the other options are set.
+  # enable_tls = false
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type OpenTelemetry struct {
+	ServiceAddress string            `toml:"service_address"`
+	Timeout        internal.Duration `toml:"timeout"`
+	Compression    string            `toml:"compression"`
+	ResourceTags   []string          `toml:"resource_tags"`
+	Headers        map[string]string `toml:"headers"`
+	EnableTLS      bool              `toml:"enable_tls"`
+	tlsint.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	conn     *grpc.ClientConn
+	metadata metadata.MD
+	callOpts []grpc.CallOption
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Send OpenTelemetry metrics over gRPC"
+}
+
+func (o *OpenTelemetry) Init() error {
+	switch o.Compression {
+	case "", "none":
+	case "gzip":
+		o.callOpts = append(o.callOpts, grpc.UseCompressor(gzip.Name))
+	default:
+		return fmt.Errorf("unsupported compression %q", o.Compression)
+	}
+
+	o.metadata = metadata.New(o.Headers)
+	return nil
+}
+
+func (o *OpenTelemetry) Connect() error {
+	tlsConfig, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig == nil && o.EnableTLS {
+		tlsConfig = &tls.Config{}
+	}
+
+	var opts []grpc.DialOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	// The connection is established in the background and on demand.
+	o.conn, err = grpc.Dial(o.ServiceAddress, opts...)
+	return err
+}
+
+func (o *OpenTelemetry) Close() error {
+	if o.conn == nil {
+		return nil
+	}
+	return o.conn.Close()
+}
+
+func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
+	req, err := otlp.FromMetrics(metrics, o.ResourceTags)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.Timeout.Duration)
+	defer cancel()
+	if len(o.metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, o.metadata)
+	}
+
+	return o.conn.Invoke(ctx, otlp.ExportMethod, req, otlp.NewResponse(), o.callOpts...)
+}
+
+// IsPermanentError reports requests rejected as invalid by the receiver as
+// permanent, sending the same metrics again would fail again.
+func (o *OpenTelemetry) IsPermanentError(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch s.Code() {
+	case codes.InvalidArgument, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+func init() {
+	outputs.Add("opentelemetry", func() telegraf.Output {
+		return &OpenTelemetry{
+			ServiceAddress: "localhost:4317",
+			Timeout:        internal.Duration{Duration: 5 * time.Second},
+			Compression:    "gzip",
+		}
+	})
+}
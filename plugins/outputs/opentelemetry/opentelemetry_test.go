@@ -0,0 +1,173 @@
+package opentelemetry
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/otlp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// receiver is an OTLP metrics service recording the requests.
+type receiver struct {
+	listener net.Listener
+	server   *grpc.Server
+	err      error
+	requests []*dynamic.Message
+	metadata []metadata.MD
+}
+
+func newReceiver(t *testing.T) *receiver {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	r := &receiver{
+		listener: listener,
+		server:   grpc.NewServer(),
+	}
+	r.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: otlp.MetricsService.GetFullyQualifiedName(),
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Export",
+				Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+					req := otlp.NewRequest()
+					if err := dec(req); err != nil {
+						return nil, err
+					}
+					md, _ := metadata.FromIncomingContext(ctx)
+					r.requests = append(r.requests, req)
+					r.metadata = append(r.metadata, md)
+					if r.err != nil {
+						return nil, r.err
+					}
+					return otlp.NewResponse(), nil
+				},
+			},
+		},
+	}, r)
+	go r.server.Serve(listener)
+	return r
+}
+
+func (r *receiver) stop() {
+	r.server.Stop()
+}
+
+func newOpenTelemetry(t *testing.T, address string) *OpenTelemetry {
+	plugin := &OpenTelemetry{
+		ServiceAddress: address,
+		Timeout:        internal.Duration{Duration: 5 * time.Second},
+		Compression:    "gzip",
+		ResourceTags:   []string{"host"},
+		Headers:        map[string]string{"X-Tenant": "ops"},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	return plugin
+}
+
+func TestWrite(t *testing.T) {
+	r := newReceiver(t)
+	defer r.stop()
+
+	plugin := newOpenTelemetry(t, r.listener.Addr().String())
+	defer plugin.Close()
+
+	now := time.Unix(1600000000, 0)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5},
+			now,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"net",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"bytes_recv": int64(42)},
+			now,
+			telegraf.Counter,
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Len(t, r.requests, 1)
+	require.Equal(t, []string{"ops"}, r.metadata[0].Get("x-tenant"))
+
+	actual, err := otlp.ToMetrics(r.requests[0], "otlp", time.Unix(0, 0))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"cpu_usage_idle": 91.5},
+			now,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"otlp",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"net_bytes_recv": int64(42)},
+			now,
+			telegraf.Counter,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.SortMetrics())
+
+	// The host tag is sent as resource attribute.
+	rm := r.requests[0].GetFieldByName("resource_metrics").([]interface{})
+	require.Len(t, rm, 1)
+	resource := rm[0].(*dynamic.Message).GetFieldByName("resource").(*dynamic.Message)
+	attributes := resource.GetFieldByName("attributes").([]interface{})
+	require.Len(t, attributes, 1)
+	require.Equal(t, "host", attributes[0].(*dynamic.Message).GetFieldByName("key"))
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"invalid", status.Error(codes.InvalidArgument, "bad request"), true},
+		{"unavailable", status.Error(codes.Unavailable, "overloaded"), false},
+		{"exhausted", status.Error(codes.ResourceExhausted, "rate limited"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newReceiver(t)
+			defer r.stop()
+			r.err = tt.err
+
+			plugin := newOpenTelemetry(t, r.listener.Addr().String())
+			defer plugin.Close()
+
+			err := plugin.Write([]telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+			})
+			require.Error(t, err)
+			require.Equal(t, tt.permanent, plugin.IsPermanentError(err))
+		})
+	}
+}
+
+func TestInitInvalidCompression(t *testing.T) {
+	plugin := &OpenTelemetry{Compression: "zstd"}
+	require.Error(t, plugin.Init())
+}
@@ -1,12 +1,18 @@
 package prometheus
 
 import (
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +22,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/outputs/prometheus_client/v1"
 	"github.com/influxdata/telegraf/plugins/outputs/prometheus_client/v2"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -24,6 +31,7 @@ var (
 	defaultListen             = ":9273"
 	defaultPath               = "/metrics"
 	defaultExpirationInterval = internal.Duration{Duration: 60 * time.Second}
+	defaultExemplarField      = "exemplar"
 )
 
 var sampleConfig = `
@@ -60,7 +68,15 @@ var sampleConfig = `
   ## Unless set to false all string metrics will be sent as labels.
   # string_as_label = true
 
-  ## If set, enable TLS with the given certificate.
+  ## Tags holding exemplar labels, such as trace identifiers.  These tags are
+  ## not used as labels; instead the counters and histogram buckets of the
+  ## metric link to an exemplar with the tags and the value of exemplar_field.
+  ## Exemplars are only exposed in the OpenMetrics format and require
+  ## metric_version = 2.
+  # exemplar_tags = ["trace_id"]
+  # exemplar_field = "exemplar"
+
+  ## If set, enable TLS with the given certificate and serve metrics over HTTPS.
   # tls_cert = "/etc/ssl/telegraf.crt"
   # tls_key = "/etc/ssl/telegraf.key"
 
@@ -89,6 +105,8 @@ type PrometheusClient struct {
 	CollectorsExclude  []string          `toml:"collectors_exclude"`
 	StringAsLabel      bool              `toml:"string_as_label"`
 	ExportTimestamp    bool              `toml:"export_timestamp"`
+	ExemplarTags       []string          `toml:"exemplar_tags"`
+	ExemplarField      string            `toml:"exemplar_field"`
 	tlsint.ServerConfig
 
 	Log telegraf.Logger `toml:"-"`
@@ -96,7 +114,17 @@ type PrometheusClient struct {
 	server    *http.Server
 	url       *url.URL
 	collector Collector
-	wg        sync.WaitGroup
+	// defaults holds the collectors of the Go runtime and process, registry
+	// holds the collector of the Telegraf metrics.
+	defaults *prometheus.Registry
+	registry *prometheus.Registry
+	wg       sync.WaitGroup
+}
+
+// familyCollector is implemented by collectors that provide the additional
+// information of the OpenMetrics format.
+type familyCollector interface {
+	Families() []serializer.Family
 }
 
 func (p *PrometheusClient) Description() string {
@@ -116,31 +144,32 @@ func (p *PrometheusClient) Init() error {
 		delete(defaultCollectors, collector)
 	}
 
-	registry := prometheus.NewRegistry()
+	p.defaults = prometheus.NewRegistry()
 	for collector := range defaultCollectors {
 		switch collector {
 		case "gocollector":
-			registry.Register(prometheus.NewGoCollector())
+			p.defaults.Register(prometheus.NewGoCollector())
 		case "process":
-			registry.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+			p.defaults.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 		default:
 			return fmt.Errorf("unrecognized collector %s", collector)
 		}
 	}
 
+	p.registry = prometheus.NewRegistry()
 	switch p.MetricVersion {
 	default:
 		fallthrough
 	case 1:
 		p.Log.Warnf("Use of deprecated configuration: metric_version = 1; please update to metric_version = 2")
 		p.collector = v1.NewCollector(p.ExpirationInterval.Duration, p.StringAsLabel, p.Log)
-		err := registry.Register(p.collector)
+		err := p.registry.Register(p.collector)
 		if err != nil {
 			return err
 		}
 	case 2:
-		p.collector = v2.NewCollector(p.ExpirationInterval.Duration, p.StringAsLabel, p.ExportTimestamp)
-		err := registry.Register(p.collector)
+		p.collector = v2.NewCollector(p.ExpirationInterval.Duration, p.StringAsLabel, p.ExportTimestamp, p.ExemplarTags, p.ExemplarField)
+		err := p.registry.Register(p.collector)
 		if err != nil {
 			return err
 		}
@@ -158,13 +187,14 @@ func (p *PrometheusClient) Init() error {
 
 	authHandler := internal.AuthHandler(p.BasicUsername, p.BasicPassword, "prometheus", onAuthError)
 	rangeHandler := internal.IPRangeHandler(ipRange, onError)
-	promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+	promHandler := promhttp.HandlerFor(prometheus.Gatherers{p.defaults, p.registry},
+		promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
 
 	mux := http.NewServeMux()
 	if p.Path == "" {
 		p.Path = "/"
 	}
-	mux.Handle(p.Path, authHandler(rangeHandler(promHandler)))
+	mux.Handle(p.Path, authHandler(rangeHandler(p.negotiate(promHandler))))
 
 	tlsConfig, err := p.TLSConfig()
 	if err != nil {
@@ -219,6 +249,93 @@ func (p *PrometheusClient) Connect() error {
 	return nil
 }
 
+// negotiate serves the OpenMetrics format if accepted by the client and the
+// Prometheus text format otherwise.
+func (p *PrometheusClient) negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !acceptsOpenMetrics(req) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		families, err := p.gather()
+		if err != nil {
+			// Like the text format, serve the metrics gathered successfully.
+			p.Log.Errorf("Error gathering metrics: %v", err)
+		}
+
+		rw.Header().Set("Content-Type", serializer.OpenMetricsContentType)
+		var w io.Writer = rw
+		if acceptsGzip(req) {
+			rw.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(rw)
+			defer gz.Close()
+			w = gz
+		}
+
+		err = serializer.WriteOpenMetrics(w, families)
+		if err != nil {
+			p.Log.Errorf("Error writing metrics: %v", err)
+		}
+	})
+}
+
+// gather returns the metric families of all collectors sorted by name.
+func (p *PrometheusClient) gather() ([]serializer.Family, error) {
+	var families []serializer.Family
+	gathered, err := p.defaults.Gather()
+	for _, mf := range gathered {
+		families = append(families, serializer.Family{MetricFamily: mf})
+	}
+
+	if c, ok := p.collector.(familyCollector); ok {
+		families = append(families, c.Families()...)
+	} else {
+		gathered, gerr := p.registry.Gather()
+		for _, mf := range gathered {
+			families = append(families, serializer.Family{MetricFamily: mf})
+		}
+		if err == nil {
+			err = gerr
+		}
+	}
+
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+	return families, err
+}
+
+func acceptsOpenMetrics(req *http.Request) bool {
+	for _, header := range req.Header["Accept"] {
+		for _, part := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil || mediaType != "application/openmetrics-text" {
+				continue
+			}
+			if q, ok := params["q"]; ok {
+				if v, err := strconv.ParseFloat(q, 64); err != nil || v <= 0 {
+					continue
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsGzip(req *http.Request) bool {
+	for _, header := range req.Header["Accept-Encoding"] {
+		for _, part := range strings.Split(header, ",") {
+			part = strings.TrimSpace(part)
+			if part == "gzip" || strings.HasPrefix(part, "gzip;") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func onAuthError(_ http.ResponseWriter) {
 }
 
@@ -257,6 +374,7 @@ func init() {
 			Path:               defaultPath,
 			ExpirationInterval: defaultExpirationInterval,
 			StringAsLabel:      true,
+			ExemplarField:      defaultExemplarField,
 		}
 	})
 }
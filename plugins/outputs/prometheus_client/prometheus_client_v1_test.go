@@ -400,3 +400,75 @@ rpc_duration_seconds_count 2693
 		})
 	}
 }
+
+func TestHistogramStateMetricVersion1(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:            "127.0.0.1:0",
+		MetricVersion:     1,
+		CollectorsExclude: []string{"gocollector", "process"},
+		Path:              "/metrics",
+		Log:               testutil.Logger{Name: "outputs.prometheus_client"},
+	}
+	err := output.Init()
+	require.NoError(t, err)
+	err = output.Connect()
+	require.NoError(t, err)
+	defer func() {
+		err := output.Close()
+		require.NoError(t, err)
+	}()
+
+	err = output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"http_request_duration_seconds",
+			map[string]string{},
+			map[string]interface{}{
+				"sum":   10,
+				"0.5":   4,
+				"1":     6,
+				"+Inf":  8,
+				"count": 8,
+			},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+	})
+	require.NoError(t, err)
+
+	// A later update missing some of the buckets keeps the previous ones.
+	err = output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"http_request_duration_seconds",
+			map[string]string{},
+			map[string]interface{}{
+				"sum":   12,
+				"0.5":   5,
+				"+Inf":  9,
+				"count": 9,
+			},
+			time.Unix(10, 0),
+			telegraf.Histogram,
+		),
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", output.URL(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, `# HELP http_request_duration_seconds Telegraf collected metric
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{le="0.5"} 5
+http_request_duration_seconds_bucket{le="1"} 6
+http_request_duration_seconds_bucket{le="+Inf"} 9
+http_request_duration_seconds_count 9
+http_request_duration_seconds_sum 12
+# EOF
+`, string(body))
+}
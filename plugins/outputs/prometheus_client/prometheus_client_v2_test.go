@@ -1,6 +1,7 @@
 package prometheus
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	inputs "github.com/influxdata/telegraf/plugins/inputs/prometheus"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -461,3 +463,149 @@ rpc_duration_seconds_count 2693
 		})
 	}
 }
+
+func TestOpenMetricsMetricVersion2(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:            "127.0.0.1:0",
+		MetricVersion:     2,
+		CollectorsExclude: []string{"gocollector", "process"},
+		Path:              "/metrics",
+		ExemplarTags:      []string{"trace_id"},
+		ExemplarField:     "exemplar",
+		Log:               testutil.Logger{Name: "outputs.prometheus_client"},
+	}
+	err := output.Init()
+	require.NoError(t, err)
+	err = output.Connect()
+	require.NoError(t, err)
+	defer func() {
+		err := output.Close()
+		require.NoError(t, err)
+	}()
+
+	err = output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"http",
+			map[string]string{"host": "example.org", "trace_id": "abc"},
+			map[string]interface{}{"requests": 10.0, "exemplar": 1.0},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+	})
+	require.NoError(t, err)
+	err = output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"http",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{"requests": 12.0},
+			time.Unix(10, 0),
+			telegraf.Counter,
+		),
+	})
+	require.NoError(t, err)
+
+	get := func(accept, encoding string) *http.Response {
+		req, err := http.NewRequest("GET", output.URL(), nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", accept)
+		req.Header.Set("Accept-Encoding", encoding)
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp
+	}
+
+	expected := `# HELP http_requests Telegraf collected metric
+# TYPE http_requests counter
+http_requests_total{host="example.org"} 12 # {trace_id="abc"} 1 0
+http_requests_created{host="example.org"} 0
+# EOF
+`
+
+	t.Run("openmetrics", func(t *testing.T) {
+		resp := get("application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5", "")
+		defer resp.Body.Close()
+		require.Equal(t, "application/openmetrics-text; version=1.0.0; charset=utf-8", resp.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(body))
+	})
+
+	t.Run("openmetrics gzip", func(t *testing.T) {
+		resp := get("application/openmetrics-text", "gzip")
+		defer resp.Body.Close()
+		require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(gz)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(body))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		resp := get("text/plain;version=0.0.4,application/openmetrics-text;q=0", "")
+		defer resp.Body.Close()
+		require.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, `# HELP http_requests Telegraf collected metric
+# TYPE http_requests counter
+http_requests{host="example.org"} 12
+`, string(body))
+	})
+}
+
+func TestTLSMetricVersion2(t *testing.T) {
+	pki := testutil.NewPKI("../../../testutil/pki")
+	output := &PrometheusClient{
+		Listen:            "127.0.0.1:0",
+		MetricVersion:     2,
+		CollectorsExclude: []string{"gocollector", "process"},
+		Path:              "/metrics",
+		ServerConfig: tlsint.ServerConfig{
+			TLSCert:           pki.ServerCertPath(),
+			TLSKey:            pki.ServerKeyPath(),
+			TLSAllowedCACerts: []string{pki.CACertPath()},
+		},
+		Log: testutil.Logger{Name: "outputs.prometheus_client"},
+	}
+	err := output.Init()
+	require.NoError(t, err)
+	err = output.Connect()
+	require.NoError(t, err)
+	defer func() {
+		err := output.Close()
+		require.NoError(t, err)
+	}()
+	require.True(t, strings.HasPrefix(output.URL(), "https://"))
+
+	err = output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{"time_idle": 42.0},
+			time.Unix(0, 0),
+		),
+	})
+	require.NoError(t, err)
+
+	tlsConfig, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	resp, err := client.Get(output.URL())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `# HELP cpu_time_idle Telegraf collected metric
+# TYPE cpu_time_idle untyped
+cpu_time_idle{host="example.org"} 42
+`, string(body))
+
+	// Clients without a certificate are rejected.
+	_, err = http.Get(output.URL())
+	require.Error(t, err)
+}
@@ -153,6 +153,12 @@ func CreateSampleID(tags map[string]string) SampleID {
 }
 
 func addSample(fam *MetricFamily, sample *Sample, sampleID SampleID) {
+	if old, ok := fam.Samples[sampleID]; ok {
+		for k := range old.Labels {
+			fam.LabelSet[k]--
+		}
+		mergeSample(fam.TelegrafValueType, sample, old)
+	}
 
 	for k := range sample.Labels {
 		fam.LabelSet[k]++
@@ -161,6 +167,30 @@ func addSample(fam *MetricFamily, sample *Sample, sampleID SampleID) {
 	fam.Samples[sampleID] = sample
 }
 
+// mergeSample keeps the buckets and quantiles of the previous histogram or
+// summary sample that are missing in the new sample, unless the count
+// decreased indicating that the series was reset.
+func mergeSample(valueType telegraf.ValueType, sample *Sample, old *Sample) {
+	if sample.Count < old.Count {
+		return
+	}
+
+	switch valueType {
+	case telegraf.Histogram:
+		for bound, count := range old.HistogramValue {
+			if _, ok := sample.HistogramValue[bound]; !ok {
+				sample.HistogramValue[bound] = count
+			}
+		}
+	case telegraf.Summary:
+		for quantile, value := range old.SummaryValue {
+			if _, ok := sample.SummaryValue[quantile]; !ok {
+				sample.SummaryValue[quantile] = value
+			}
+		}
+	}
+}
+
 func (c *Collector) addMetricFamily(point telegraf.Metric, sample *Sample, mname string, sampleID SampleID) {
 	var fam *MetricFamily
 	var ok bool
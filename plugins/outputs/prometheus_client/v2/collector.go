@@ -43,8 +43,11 @@ type Collector struct {
 	coll           *serializer.Collection
 }
 
-func NewCollector(expire time.Duration, stringsAsLabel bool, exportTimestamp bool) *Collector {
-	config := serializer.FormatConfig{}
+func NewCollector(expire time.Duration, stringsAsLabel bool, exportTimestamp bool, exemplarTags []string, exemplarField string) *Collector {
+	config := serializer.FormatConfig{
+		ExemplarTags:  exemplarTags,
+		ExemplarField: exemplarField,
+	}
 	if stringsAsLabel {
 		config.StringHandling = serializer.StringAsLabel
 	}
@@ -83,6 +86,19 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	}
 }
 
+// Families returns the current metric families along with the information
+// only available in the OpenMetrics format.
+func (c *Collector) Families() []serializer.Family {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.expireDuration != 0 {
+		c.coll.Expire(time.Now(), c.expireDuration)
+	}
+
+	return c.coll.GetFamilies()
+}
+
 func (c *Collector) Add(metrics []telegraf.Metric) error {
 	c.Lock()
 	defer c.Unlock()
@@ -0,0 +1,144 @@
+package sql
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQL(driver string) *SQL {
+	return &SQL{
+		Driver:          driver,
+		TimestampColumn: "timestamp",
+		TagsColumn:      "tags",
+		TableTemplate:   "CREATE TABLE {TABLE}({COLUMNS})",
+		Log:             testutil.Logger{},
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	s := newSQL("oracle")
+	require.Error(t, s.Init())
+
+	s = newSQL("mysql")
+	s.UseCopy = true
+	require.Error(t, s.Init())
+}
+
+func TestCloseWithoutConnect(t *testing.T) {
+	s := newSQL("pgx")
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Close())
+}
+
+func TestConnectCopyTimeout(t *testing.T) {
+	// The server accepts connections but never answers the startup.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	s := newSQL("pgx")
+	s.DataSourceName = fmt.Sprintf("postgres://telegraf@%s/telegraf?sslmode=disable", listener.Addr())
+	s.Timeout = internal.Duration{Duration: 100 * time.Millisecond}
+	s.UseCopy = true
+	require.NoError(t, s.Init())
+
+	start := time.Now()
+	require.Error(t, s.Connect())
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	require.NoError(t, s.Close())
+}
+
+func TestConvertDefaults(t *testing.T) {
+	s := newSQL("mysql")
+	s.Convert.Real = "FLOAT"
+	require.NoError(t, s.Init())
+	require.Equal(t, "FLOAT", s.Convert.Real)
+	require.Equal(t, "BIGINT UNSIGNED", s.Convert.Unsigned)
+	require.Equal(t, "DATETIME(6)", s.Convert.Timestamp)
+}
+
+func TestQuoteIdent(t *testing.T) {
+	s := newSQL("pgx")
+	require.NoError(t, s.Init())
+	require.Equal(t, `"cpu ""usage"""`, s.quoteIdent(`cpu "usage"`))
+
+	s = newSQL("mysql")
+	require.NoError(t, s.Init())
+	require.Equal(t, "`cpu``s`", s.quoteIdent("cpu`s"))
+}
+
+func TestBatchColumns(t *testing.T) {
+	s := newSQL("pgx")
+	require.NoError(t, s.Init())
+
+	ts := time.Unix(1600000000, 0)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.0, "timestamp": int64(1)},
+			ts,
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 43.0, "count": uint64(1 << 63), "host": "x", "up": true},
+			ts,
+		),
+	}
+
+	b := s.newBatch("cpu")
+	for _, m := range metrics {
+		require.NoError(t, b.add(m))
+	}
+
+	require.Equal(t, []column{
+		{name: "timestamp", typ: "TIMESTAMP"},
+		{name: "host", typ: "TEXT"},
+		{name: "usage", typ: "DOUBLE PRECISION"},
+		{name: "cpu", typ: "TEXT"},
+		{name: "count", typ: "NUMERIC(20)"},
+		{name: "up", typ: "BOOLEAN"},
+	}, b.columns)
+	require.Equal(t, [][]interface{}{
+		{ts.UTC(), "a", 42.0, nil, nil, nil},
+		{ts.UTC(), "b", 43.0, "cpu0", "9223372036854775808", true},
+	}, b.paddedRows())
+}
+
+func TestBatchTagsAsJSON(t *testing.T) {
+	s := newSQL("pgx")
+	s.TagsAsJSON = true
+	require.NoError(t, s.Init())
+
+	ts := time.Unix(1600000000, 0)
+	b := s.newBatch("cpu")
+	require.NoError(t, b.add(testutil.MustMetric("cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage": int64(42)},
+		ts,
+	)))
+
+	require.Equal(t, []column{
+		{name: "timestamp", typ: "TIMESTAMP"},
+		{name: "tags", typ: "JSONB"},
+		{name: "usage", typ: "BIGINT"},
+	}, b.columns)
+	require.Equal(t, [][]interface{}{
+		{ts.UTC(), `{"cpu":"cpu0","host":"a"}`, int64(42)},
+	}, b.paddedRows())
+}
@@ -0,0 +1,201 @@
+// +build linux
+// +build 386 amd64 arm arm64
+
+package sql
+
+import (
+	gosql "database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLite(t *testing.T) (*SQL, func()) {
+	dir, err := ioutil.TempDir("", "sql-output")
+	require.NoError(t, err)
+
+	s := newSQL("sqlite")
+	s.DataSourceName = filepath.Join(dir, "metrics.db")
+	s.Timeout.Duration = 5 * time.Second
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// dump returns the content of the table ordered by the rowid as strings.
+func dump(t *testing.T, db *gosql.DB, table string) ([]string, [][]string) {
+	rows, err := db.Query(fmt.Sprintf(`SELECT * FROM "%s" ORDER BY rowid`, table))
+	require.NoError(t, err)
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+
+	var result [][]string
+	for rows.Next() {
+		values := make([]gosql.NullString, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(ptrs...))
+
+		row := make([]string, 0, len(columns))
+		for _, v := range values {
+			if v.Valid {
+				row = append(row, v.String)
+			} else {
+				row = append(row, "NULL")
+			}
+		}
+		result = append(result, row)
+	}
+	require.NoError(t, rows.Err())
+	return columns, result
+}
+
+func TestSQLiteWrite(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	ts := time.Unix(1600000000, 500000000)
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3)},
+			ts,
+		),
+		testutil.MustMetric("mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"used": uint64(1024), "ok": true, "state": "fine"},
+			ts,
+		),
+	}))
+
+	columns, rows := dump(t, s.db, "cpu")
+	require.Equal(t, []string{"timestamp", "host", "count", "usage"}, columns)
+	require.Equal(t, [][]string{{"2020-09-13 12:26:40.5", "a", "3", "42.5"}}, rows)
+
+	columns, rows = dump(t, s.db, "mem")
+	require.Equal(t, []string{"timestamp", "host", "ok", "state", "used"}, columns)
+	require.Equal(t, [][]string{{"2020-09-13 12:26:40.5", "a", "1", "fine", "1024"}}, rows)
+}
+
+func TestSQLiteSchemaEvolution(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	ts := time.Unix(1600000000, 0)
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 1.0},
+			ts,
+		),
+	}))
+
+	// New tags and fields are added as columns to the existing table.
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 2.0, "idle": 98.0},
+			ts,
+		),
+	}))
+
+	// The schema is read from the database after a restart.
+	s.Close()
+	require.NoError(t, s.Connect())
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "c", "region": "eu"},
+			map[string]interface{}{"usage": 3.0},
+			ts,
+		),
+	}))
+
+	columns, rows := dump(t, s.db, "cpu")
+	require.Equal(t, []string{"timestamp", "host", "usage", "cpu", "idle", "region"}, columns)
+	require.Equal(t, [][]string{
+		{"2020-09-13 12:26:40", "a", "1", "NULL", "NULL", "NULL"},
+		{"2020-09-13 12:26:40", "b", "2", "cpu0", "98", "NULL"},
+		{"2020-09-13 12:26:40", "c", "3", "NULL", "NULL", "eu"},
+	}, rows)
+}
+
+func TestSQLiteTagsAsJSON(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+	s.TagsAsJSON = true
+
+	ts := time.Unix(1600000000, 0)
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 1.0},
+			ts,
+		),
+	}))
+
+	columns, rows := dump(t, s.db, "cpu")
+	require.Equal(t, []string{"timestamp", "tags", "usage"}, columns)
+	require.Equal(t, [][]string{{"2020-09-13 12:26:40", `{"cpu":"cpu0","host":"a"}`, "1"}}, rows)
+}
+
+func TestSQLiteBatches(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+
+	// More rows than fit in a single statement.
+	ts := time.Unix(1600000000, 0)
+	metrics := make([]telegraf.Metric, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(i)},
+			ts.Add(time.Duration(i)*time.Second),
+		))
+	}
+	require.NoError(t, s.Write(metrics))
+
+	var count, sum int64
+	require.NoError(t, s.db.QueryRow(`SELECT COUNT(*), SUM("value") FROM "cpu"`).Scan(&count, &sum))
+	require.Equal(t, int64(1000), count)
+	require.Equal(t, int64(999*1000/2), sum)
+}
+
+func TestSQLiteTableTemplate(t *testing.T) {
+	s, cleanup := newSQLite(t)
+	defer cleanup()
+	s.TableTemplate = "CREATE TABLE {TABLE}({COLUMNS}, PRIMARY KEY ({TIMESTAMP}, host))"
+
+	ts := time.Unix(1600000000, 0)
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage": 1.0},
+		ts,
+	)
+	require.NoError(t, s.Write([]telegraf.Metric{m}))
+
+	// Duplicate primary key, the whole write is rolled back.
+	err := s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"usage": 2.0}, ts),
+		m,
+	})
+	require.Error(t, err)
+
+	var count int64
+	require.NoError(t, s.db.QueryRow(`SELECT COUNT(*) FROM "cpu"`).Scan(&count))
+	require.Equal(t, int64(1), count)
+}
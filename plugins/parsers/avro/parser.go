@@ -0,0 +1,141 @@
+package avro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/record"
+)
+
+type Config struct {
+	record.Config
+
+	// SchemaFile is the Avro schema of the messages.
+	SchemaFile string
+	// ConfluentWireFormat enables messages prefixed by a schema ID.
+	ConfluentWireFormat bool
+	// SchemaDirectory contains the schemas of the schema IDs of messages in
+	// the confluent wire format.
+	SchemaDirectory string
+}
+
+// Parser decodes Avro binary encoded records into metrics.
+type Parser struct {
+	Now func() time.Time
+
+	mapper    *record.Mapper
+	confluent bool
+	schema    *schema
+	schemas   map[int]*schema
+}
+
+// New creates a parser, loading all schemas from disk.
+func New(config *Config) (*Parser, error) {
+	mapper, err := record.NewMapper(&config.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Parser{
+		Now:       time.Now,
+		mapper:    mapper,
+		confluent: config.ConfluentWireFormat,
+		schemas:   make(map[int]*schema),
+	}
+
+	if config.SchemaFile != "" {
+		p.schema, err = loadSchema(config.SchemaFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.SchemaDirectory != "" {
+		if !p.confluent {
+			return nil, fmt.Errorf("schema directory requires the confluent wire format")
+		}
+		files, err := record.SchemaFiles(config.SchemaDirectory, ".avsc")
+		if err != nil {
+			return nil, err
+		}
+		for id, file := range files {
+			p.schemas[id], err = loadSchema(file)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if p.schema == nil && len(p.schemas) == 0 {
+		return nil, fmt.Errorf("no schema file or schema directory with schemas configured")
+	}
+	return p, nil
+}
+
+func loadSchema(file string) (*schema, error) {
+	spec, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSchema(string(spec))
+	if err != nil {
+		return nil, fmt.Errorf("loading schema %s: %v", file, err)
+	}
+	return s, nil
+}
+
+// Parse decodes the records in buf into metrics, one for each record.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	s := p.schema
+	if p.confluent {
+		id, payload, err := record.SplitConfluent(buf)
+		if err != nil {
+			return nil, err
+		}
+		if schema, ok := p.schemas[id]; ok {
+			s = schema
+		} else if s == nil {
+			return nil, fmt.Errorf("unknown schema ID %d", id)
+		}
+		buf = payload
+	}
+
+	t := p.Now()
+	metrics := make([]telegraf.Metric, 0)
+	for len(buf) > 0 {
+		v, rest, err := s.decode(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = rest
+
+		rec, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected record but got %T", v)
+		}
+		m, err := p.mapper.Metric(rec, t)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("can not parse the line: %s, for data format: avro", line)
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.mapper.SetDefaultTags(tags)
+}
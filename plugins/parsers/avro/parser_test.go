@@ -0,0 +1,172 @@
+package avro
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/record"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, file string, datums ...interface{}) []byte {
+	t.Helper()
+
+	spec, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	codec, err := goavro.NewCodec(string(spec))
+	require.NoError(t, err)
+
+	var buf []byte
+	for _, datum := range datums {
+		buf, err = codec.BinaryFromNative(buf, datum)
+		require.NoError(t, err)
+	}
+	return buf
+}
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		Config: record.Config{
+			MetricName:   "avro",
+			TagKeys:      []string{"host", "location_region"},
+			NameKey:      "name",
+			StringFields: []string{"status"},
+			TimeKey:      "time",
+		},
+		SchemaFile: "testdata/measurement.avsc",
+	})
+	require.NoError(t, err)
+
+	buf := encode(t, "testdata/measurement.avsc",
+		map[string]interface{}{
+			"name":   "disk",
+			"host":   goavro.Union("string", "server01"),
+			"value":  42.5,
+			"count":  goavro.Union("long", int64(7)),
+			"status": "FAILED",
+			"time":   time.Unix(1600000000, 0),
+			"location": goavro.Union("example.Location", map[string]interface{}{
+				"region": "eu-west",
+				"rack":   goavro.Union("int", int32(4)),
+			}),
+			"previous": nil,
+			"extra": map[string]interface{}{
+				"load": goavro.Union("double", 0.5),
+				"none": nil,
+			},
+			"samples": []interface{}{int32(1), int32(2)},
+		},
+		map[string]interface{}{
+			"name":     "cpu",
+			"host":     nil,
+			"value":    1.0,
+			"count":    nil,
+			"status":   "OK",
+			"time":     time.Unix(1600000001, 0),
+			"location": nil,
+			"previous": goavro.Union("example.Location", map[string]interface{}{
+				"region": "us-east",
+				"rack":   nil,
+			}),
+			"extra":   map[string]interface{}{},
+			"samples": []interface{}{},
+		},
+	)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"disk",
+			map[string]string{
+				"host":            "server01",
+				"location_region": "eu-west",
+			},
+			map[string]interface{}{
+				"value":         42.5,
+				"count":         int64(7),
+				"status":        "FAILED",
+				"location_rack": int64(4),
+				"extra_load":    0.5,
+				"samples_0":     int64(1),
+				"samples_1":     int64(2),
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value":  1.0,
+				"status": "OK",
+			},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseConfluent(t *testing.T) {
+	parser, err := New(&Config{
+		Config: record.Config{
+			MetricName: "avro",
+			TagKeys:    []string{"name"},
+		},
+		ConfluentWireFormat: true,
+		SchemaDirectory:     "testdata/registry",
+	})
+	require.NoError(t, err)
+	parser.SetDefaultTags(map[string]string{"source": "kafka"})
+	parser.Now = func() time.Time { return time.Unix(0, 0) }
+
+	buf := encode(t, "testdata/registry/3.avsc", map[string]interface{}{
+		"name":  "started",
+		"value": goavro.Union("float", float32(1.5)),
+	})
+
+	m, err := parser.ParseLine(string(append([]byte{0, 0, 0, 0, 3}, buf...)))
+	require.NoError(t, err)
+	expected := testutil.MustMetric(
+		"avro",
+		map[string]string{"name": "started", "source": "kafka"},
+		map[string]interface{}{"value": 1.5},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{m})
+
+	_, err = parser.Parse(append([]byte{0, 0, 0, 0, 4}, buf...))
+	require.Error(t, err)
+	_, err = parser.Parse(append([]byte{1, 0, 0, 0, 3}, buf...))
+	require.Error(t, err)
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "no schema",
+			config: &Config{},
+		},
+		{
+			name:   "missing schema file",
+			config: &Config{SchemaFile: "testdata/missing.avsc"},
+		},
+		{
+			name:   "directory without confluent wire format",
+			config: &Config{SchemaDirectory: "testdata/registry"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config)
+			require.Error(t, err)
+		})
+	}
+}
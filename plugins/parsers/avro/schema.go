@@ -0,0 +1,199 @@
+package avro
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// schema decodes Avro binary data into native values.
+type schema struct {
+	codec *goavro.Codec
+	root  interface{}
+	// named are the schemas of named types by full name
+	named map[string]interface{}
+}
+
+func newSchema(spec string) (*schema, error) {
+	codec, err := goavro.NewCodec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var root interface{}
+	if err := json.Unmarshal([]byte(spec), &root); err != nil {
+		return nil, err
+	}
+
+	s := &schema{
+		codec: codec,
+		root:  root,
+		named: make(map[string]interface{}),
+	}
+	s.register(root, "")
+	return s, nil
+}
+
+// decode decodes one datum from buf and returns the value and the remaining
+// bytes.
+func (s *schema) decode(buf []byte) (interface{}, []byte, error) {
+	v, rest, err := s.codec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.unwrap(s.root, "", v), rest, nil
+}
+
+// register records the named types defined in the schema.
+func (s *schema) register(t interface{}, namespace string) {
+	switch t := t.(type) {
+	case []interface{}:
+		for _, member := range t {
+			s.register(member, namespace)
+		}
+	case map[string]interface{}:
+		switch t["type"] {
+		case "record", "error", "enum", "fixed":
+			name, ns := fullName(t, namespace)
+			s.named[name] = t
+			if fields, ok := t["fields"].([]interface{}); ok {
+				for _, field := range fields {
+					if field, ok := field.(map[string]interface{}); ok {
+						s.register(field["type"], ns)
+					}
+				}
+			}
+		case "array":
+			s.register(t["items"], namespace)
+		case "map":
+			s.register(t["values"], namespace)
+		default:
+			s.register(t["type"], namespace)
+		}
+	}
+}
+
+// unwrap replaces the unions in v, which goavro decodes as maps of the type
+// name to the value, by their value.
+func (s *schema) unwrap(t interface{}, namespace string, v interface{}) interface{} {
+	switch t := t.(type) {
+	case string:
+		if named, ok := s.named[s.resolve(t, namespace)]; ok {
+			_, ns := fullName(named.(map[string]interface{}), namespace)
+			return s.unwrap(named, ns, v)
+		}
+		return v
+	case []interface{}:
+		union, ok := v.(map[string]interface{})
+		if !ok || len(union) != 1 {
+			return v
+		}
+		for name, value := range union {
+			for _, member := range t {
+				if s.typeName(member, namespace) == name {
+					return s.unwrap(member, namespace, value)
+				}
+			}
+			return value
+		}
+	case map[string]interface{}:
+		switch t["type"] {
+		case "record", "error":
+			rec, ok := v.(map[string]interface{})
+			if !ok {
+				return v
+			}
+			_, ns := fullName(t, namespace)
+			fields, _ := t["fields"].([]interface{})
+			for _, field := range fields {
+				field, ok := field.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := field["name"].(string)
+				if value, ok := rec[name]; ok {
+					rec[name] = s.unwrap(field["type"], ns, value)
+				}
+			}
+			return rec
+		case "array":
+			items, ok := v.([]interface{})
+			if !ok {
+				return v
+			}
+			for i, item := range items {
+				items[i] = s.unwrap(t["items"], namespace, item)
+			}
+			return items
+		case "map":
+			values, ok := v.(map[string]interface{})
+			if !ok {
+				return v
+			}
+			for k, value := range values {
+				values[k] = s.unwrap(t["values"], namespace, value)
+			}
+			return values
+		case "enum", "fixed":
+			return v
+		default:
+			return s.unwrap(t["type"], namespace, v)
+		}
+	}
+	return v
+}
+
+// typeName returns the name goavro uses for the member of a union.
+func (s *schema) typeName(t interface{}, namespace string) string {
+	switch t := t.(type) {
+	case string:
+		if isPrimitive(t) {
+			return t
+		}
+		return s.resolve(t, namespace)
+	case map[string]interface{}:
+		typ, _ := t["type"].(string)
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			name, _ := fullName(t, namespace)
+			return name
+		}
+		if logicalType, ok := t["logicalType"].(string); ok {
+			return typ + "." + logicalType
+		}
+		return typ
+	}
+	return ""
+}
+
+// resolve returns the full name of a reference to a named type.
+func (s *schema) resolve(name string, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// fullName returns the full name and namespace of a named type.
+func fullName(t map[string]interface{}, namespace string) (string, string) {
+	name, _ := t["name"].(string)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name, name[:i]
+	}
+	if ns, ok := t["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name, ""
+	}
+	return namespace + "." + name, namespace
+}
+
+func isPrimitive(name string) bool {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package cbor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	cborserializer "github.com/influxdata/telegraf/plugins/serializers/cbor"
+)
+
+// Parser decodes metrics encoded by the cbor serializer.
+type Parser struct {
+	DefaultTags map[string]string
+}
+
+// Parse decodes the CBOR sequence of maps in buf.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	dec := cbor.NewDecoder(bytes.NewReader(buf))
+	for {
+		var e cborserializer.Metric
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			if dec.NumBytesRead() < len(buf) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return metrics, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name, tags, fields, tm, tp, err := e.Values()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range p.DefaultTags {
+			if _, ok := tags[k]; !ok {
+				tags[k] = v
+			}
+		}
+
+		m, err := metric.New(name, tags, fields, tm, tp)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+}
+
+// ParseLine decodes a single metric.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(metrics) {
+	case 0:
+		return nil, nil
+	case 1:
+		return metrics[0], nil
+	default:
+		return nil, fmt.Errorf("cannot parse line with multiple (%d) metrics", len(metrics))
+	}
+}
+
+// SetDefaultTags adds tags to the metrics outputs of Parse and ParseLine.
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,108 @@
+package cbor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/influxdata/telegraf"
+	cborserializer "github.com/influxdata/telegraf/plugins/serializers/cbor"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "localhost", "cpu": "cpu0"},
+			map[string]interface{}{
+				"int":      int64(-42),
+				"pos_int":  int64(math.MaxInt64),
+				"uint":     uint64(0),
+				"max_uint": uint64(math.MaxUint64),
+				"float":    1.5,
+				"bool":     false,
+				"string":   "ok",
+			},
+			time.Unix(1600000000, 123456789),
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": 0.0},
+			time.Unix(-1, 5),
+		),
+	}
+
+	s := cborserializer.NewSerializer()
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	p := &Parser{}
+	actual, err := p.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, metrics, actual)
+	for i := range metrics {
+		require.Equal(t, metrics[i].Type(), actual[i].Type())
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+
+	buf, err := cborserializer.NewSerializer().Serialize(m)
+	require.NoError(t, err)
+
+	p := &Parser{}
+	p.SetDefaultTags(map[string]string{"host": "default", "region": "eu"})
+	actual, err := p.ParseLine(string(buf))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "localhost", "region": "eu"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{actual})
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+	}{
+		{
+			name:   "int overflow",
+			fields: map[string]interface{}{"value": uint64(math.MaxUint64)},
+		},
+		{
+			name: "bignum overflow",
+			fields: map[string]interface{}{
+				"value": cbor.Tag{Number: cborserializer.BignumTag, Content: []byte{1, 0, 0, 0, 0, 0, 0, 0, 0}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := cbor.Marshal(&cborserializer.Metric{Name: "cpu", Fields: tt.fields})
+			require.NoError(t, err)
+
+			p := &Parser{}
+			_, err = p.Parse(buf)
+			require.Error(t, err)
+		})
+	}
+
+	p := &Parser{}
+	_, err := p.Parse([]byte{0xa1})
+	require.Error(t, err)
+}
@@ -0,0 +1,349 @@
+package json_v2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/tidwall/gjson"
+)
+
+var ErrInvalidJSON = errors.New("invalid JSON")
+
+// Config selects objects from a JSON document and creates a metric for each
+// of them.  Paths use the GJSON path syntax and, except for the selection
+// path, are relative to the selected object.
+type Config struct {
+	// Path selects the object or array of objects to create metrics from,
+	// by default the document root is selected.
+	Path string `toml:"path"`
+
+	MeasurementName     string `toml:"measurement_name"`
+	MeasurementNamePath string `toml:"measurement_name_path"`
+
+	TimestampPath     string `toml:"timestamp_path"`
+	TimestampFormat   string `toml:"timestamp_format"`
+	TimestampTimezone string `toml:"timestamp_timezone"`
+
+	Tags   []DataSet `toml:"tag"`
+	Fields []DataSet `toml:"field"`
+
+	// Objects are selected relative to each object selected by Path.  If
+	// set, the metrics of the nested objects are created instead of the
+	// metric of the object; they inherit the name, timestamp, tags and
+	// fields of the object.
+	Objects []Config `toml:"object"`
+}
+
+// DataSet selects a tag or field.
+type DataSet struct {
+	Path   string `toml:"path"`
+	Rename string `toml:"rename"`
+	// Type of the field, one of int, uint, float, string or bool.  By
+	// default the type is derived from the JSON value.
+	Type string `toml:"type"`
+}
+
+// Parser decodes JSON documents into metrics.
+type Parser struct {
+	MetricName  string
+	Configs     []Config
+	DefaultTags map[string]string
+	Now         func() time.Time
+}
+
+// object holds the values of a selected object inherited by nested objects.
+type object struct {
+	name   string
+	time   time.Time
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// New creates a parser and validates its configs.
+func New(metricName string, configs []Config, defaultTags map[string]string) (*Parser, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("no json_v2 selection configured")
+	}
+	for i := range configs {
+		if err := validate(&configs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Parser{
+		MetricName:  metricName,
+		Configs:     configs,
+		DefaultTags: defaultTags,
+		Now:         time.Now,
+	}, nil
+}
+
+func validate(config *Config) error {
+	if config.TimestampPath != "" && config.TimestampFormat == "" {
+		return fmt.Errorf("timestamp_path %q requires timestamp_format", config.TimestampPath)
+	}
+	for _, field := range config.Fields {
+		switch field.Type {
+		case "", "int", "uint", "float", "string", "bool":
+		default:
+			return fmt.Errorf("invalid type %q of field %q", field.Type, field.Path)
+		}
+	}
+	for i := range config.Objects {
+		if err := validate(&config.Objects[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse converts a JSON document to metrics.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if !gjson.ValidBytes(buf) {
+		return nil, ErrInvalidJSON
+	}
+	doc := gjson.ParseBytes(buf)
+
+	root := &object{
+		name:   p.MetricName,
+		time:   p.Now(),
+		tags:   make(map[string]string),
+		fields: make(map[string]interface{}),
+	}
+	for k, v := range p.DefaultTags {
+		root.tags[k] = v
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	for i := range p.Configs {
+		m, err := p.parseObjects(&p.Configs[i], doc, root)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a single JSON document, the document must result in
+// exactly one metric.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(metrics) {
+	case 0:
+		return nil, nil
+	case 1:
+		return metrics[0], nil
+	default:
+		return nil, fmt.Errorf("cannot parse line with multiple (%d) metrics", len(metrics))
+	}
+}
+
+// SetDefaultTags adds tags to the metrics outputs of Parse and ParseLine.
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// parseObjects creates the metrics of the objects selected by the config
+// relative to result.
+func (p *Parser) parseObjects(config *Config, result gjson.Result, parent *object) ([]telegraf.Metric, error) {
+	selected := result
+	if config.Path != "" {
+		selected = result.Get(config.Path)
+		if !selected.Exists() {
+			return nil, nil
+		}
+	}
+
+	elements := []gjson.Result{selected}
+	if selected.IsArray() {
+		elements = selected.Array()
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	for _, element := range elements {
+		obj, err := p.parseObject(config, element, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(config.Objects) == 0 {
+			if len(obj.fields) == 0 {
+				continue
+			}
+			m, err := metric.New(obj.name, obj.tags, obj.fields, obj.time)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+			continue
+		}
+
+		for i := range config.Objects {
+			m, err := p.parseObjects(&config.Objects[i], element, obj)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m...)
+		}
+	}
+	return metrics, nil
+}
+
+// parseObject returns the values of the object, starting with the values of
+// the parent.
+func (p *Parser) parseObject(config *Config, result gjson.Result, parent *object) (*object, error) {
+	obj := &object{
+		name:   parent.name,
+		time:   parent.time,
+		tags:   make(map[string]string, len(parent.tags)),
+		fields: make(map[string]interface{}, len(parent.fields)),
+	}
+	for k, v := range parent.tags {
+		obj.tags[k] = v
+	}
+	for k, v := range parent.fields {
+		obj.fields[k] = v
+	}
+
+	if config.MeasurementName != "" {
+		obj.name = config.MeasurementName
+	}
+	if config.MeasurementNamePath != "" {
+		if name := result.Get(config.MeasurementNamePath); name.Exists() {
+			obj.name = name.String()
+		}
+	}
+
+	if config.TimestampPath != "" {
+		ts := result.Get(config.TimestampPath)
+		if !ts.Exists() {
+			return nil, fmt.Errorf("timestamp_path %q not found", config.TimestampPath)
+		}
+		var err error
+		obj.time, err = internal.ParseTimestamp(config.TimestampFormat, ts.String(), config.TimestampTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp %q: %v", config.TimestampPath, err)
+		}
+	}
+
+	for _, tag := range config.Tags {
+		v := result.Get(tag.Path)
+		if !v.Exists() {
+			continue
+		}
+		obj.tags[tag.name()] = v.String()
+	}
+
+	for _, field := range config.Fields {
+		v := result.Get(field.Path)
+		if !v.Exists() {
+			continue
+		}
+		if err := addField(obj.fields, field.name(), field.Type, v); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func (d *DataSet) name() string {
+	if d.Rename != "" {
+		return d.Rename
+	}
+	return d.Path
+}
+
+// addField adds the value as field; objects and arrays are flattened into a
+// field for each of their values, joining the keys with an underscore.
+func addField(fields map[string]interface{}, name string, typ string, v gjson.Result) error {
+	if v.IsObject() || v.IsArray() {
+		var err error
+		i := 0
+		v.ForEach(func(key, value gjson.Result) bool {
+			k := key.String()
+			if v.IsArray() {
+				k = strconv.Itoa(i)
+				i++
+			}
+			err = addField(fields, name+"_"+k, typ, value)
+			return err == nil
+		})
+		return err
+	}
+
+	value, err := convert(typ, v)
+	if err != nil {
+		return fmt.Errorf("field %q: %v", name, err)
+	}
+	if value != nil {
+		fields[name] = value
+	}
+	return nil
+}
+
+// convert returns the value in the type, nulls are returned as nil.
+func convert(typ string, v gjson.Result) (interface{}, error) {
+	if v.Type == gjson.Null {
+		return nil, nil
+	}
+
+	switch typ {
+	case "":
+		return v.Value(), nil
+	case "string":
+		return v.String(), nil
+	case "float":
+		if v.Type == gjson.String {
+			return strconv.ParseFloat(v.Str, 64)
+		}
+		return v.Float(), nil
+	case "int":
+		if v.Type == gjson.String {
+			if i, err := strconv.ParseInt(v.Str, 10, 64); err == nil {
+				return i, nil
+			}
+			f, err := strconv.ParseFloat(v.Str, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to int", v.Str)
+			}
+			return int64(f), nil
+		}
+		return v.Int(), nil
+	case "uint":
+		if v.Type == gjson.String {
+			if u, err := strconv.ParseUint(v.Str, 10, 64); err == nil {
+				return u, nil
+			}
+			f, err := strconv.ParseFloat(v.Str, 64)
+			if err != nil || f < 0 {
+				return nil, fmt.Errorf("cannot convert %q to uint", v.Str)
+			}
+			return uint64(f), nil
+		}
+		if v.Type == gjson.Number && v.Num < 0 {
+			return nil, fmt.Errorf("cannot convert %s to uint", v.Raw)
+		}
+		return v.Uint(), nil
+	case "bool":
+		if v.Type == gjson.String {
+			b, err := strconv.ParseBool(v.Str)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to bool", v.Str)
+			}
+			return b, nil
+		}
+		return v.Bool(), nil
+	default:
+		return nil, fmt.Errorf("invalid type %q", typ)
+	}
+}
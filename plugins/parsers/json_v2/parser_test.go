@@ -0,0 +1,293 @@
+package json_v2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const weatherDoc = `
+{
+  "station": {
+    "id": "ST-01",
+    "location": {"lat": 52.52, "lon": 13.40},
+    "elevation": "34"
+  },
+  "time": 1600000000,
+  "samples": [
+    {"time": 1600000010, "sensor": "temp", "value": 21.5, "ok": true},
+    {"time": 1600000020, "sensor": "temp", "value": 22, "ok": "false"}
+  ],
+  "errors": [
+    {"code": 3, "counts": [1, 2]}
+  ],
+  "empty": null
+}
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []Config
+		want    []telegraf.Metric
+	}{
+		{
+			name: "document root",
+			configs: []Config{
+				{
+					MeasurementName: "station",
+					TimestampPath:   "time",
+					TimestampFormat: "unix",
+					Tags: []DataSet{
+						{Path: "station.id", Rename: "station"},
+					},
+					Fields: []DataSet{
+						{Path: "station.elevation", Rename: "elevation", Type: "int"},
+						{Path: "station.location"},
+						{Path: "empty"},
+						{Path: "missing"},
+					},
+				},
+			},
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"station",
+					map[string]string{"station": "ST-01"},
+					map[string]interface{}{
+						"elevation":            int64(34),
+						"station.location_lat": 52.52,
+						"station.location_lon": 13.40,
+					},
+					time.Unix(1600000000, 0),
+				),
+			},
+		},
+		{
+			name: "array of objects",
+			configs: []Config{
+				{
+					Path:                "samples",
+					MeasurementNamePath: "sensor",
+					TimestampPath:       "time",
+					TimestampFormat:     "unix",
+					Fields: []DataSet{
+						{Path: "value", Type: "float"},
+						{Path: "ok", Type: "bool"},
+					},
+				},
+			},
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"temp",
+					map[string]string{},
+					map[string]interface{}{"value": 21.5, "ok": true},
+					time.Unix(1600000010, 0),
+				),
+				testutil.MustMetric(
+					"temp",
+					map[string]string{},
+					map[string]interface{}{"value": 22.0, "ok": false},
+					time.Unix(1600000020, 0),
+				),
+			},
+		},
+		{
+			name: "inherit from parent",
+			configs: []Config{
+				{
+					MeasurementName: "weather",
+					TimestampPath:   "time",
+					TimestampFormat: "unix",
+					Tags: []DataSet{
+						{Path: "station.id", Rename: "station"},
+					},
+					Fields: []DataSet{
+						{Path: "station.elevation", Rename: "elevation", Type: "uint"},
+					},
+					Objects: []Config{
+						{
+							Path: "samples",
+							Tags: []DataSet{
+								{Path: "sensor"},
+							},
+							Fields: []DataSet{
+								{Path: "value", Type: "int"},
+							},
+						},
+						{
+							Path:            "errors",
+							MeasurementName: "weather_errors",
+							Fields: []DataSet{
+								{Path: "code", Type: "string"},
+								{Path: "counts", Type: "int"},
+							},
+						},
+					},
+				},
+			},
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"weather",
+					map[string]string{"station": "ST-01", "sensor": "temp"},
+					map[string]interface{}{"elevation": uint64(34), "value": int64(21)},
+					time.Unix(1600000000, 0),
+				),
+				testutil.MustMetric(
+					"weather",
+					map[string]string{"station": "ST-01", "sensor": "temp"},
+					map[string]interface{}{"elevation": uint64(34), "value": int64(22)},
+					time.Unix(1600000000, 0),
+				),
+				testutil.MustMetric(
+					"weather_errors",
+					map[string]string{"station": "ST-01"},
+					map[string]interface{}{
+						"elevation": uint64(34),
+						"code":      "3",
+						"counts_0":  int64(1),
+						"counts_1":  int64(2),
+					},
+					time.Unix(1600000000, 0),
+				),
+			},
+		},
+		{
+			name: "multiple selections",
+			configs: []Config{
+				{
+					MeasurementName: "station",
+					Fields: []DataSet{
+						{Path: "station.location.lat", Rename: "lat"},
+					},
+				},
+				{
+					Path:            "samples.#(sensor==\"temp\")#",
+					MeasurementName: "temp",
+					Fields: []DataSet{
+						{Path: "value"},
+					},
+				},
+			},
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"station",
+					map[string]string{},
+					map[string]interface{}{"lat": 52.52},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"temp",
+					map[string]string{},
+					map[string]interface{}{"value": 21.5},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"temp",
+					map[string]string{},
+					map[string]interface{}{"value": 22.0},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "missing selection",
+			configs: []Config{
+				{
+					Path:   "missing",
+					Fields: []DataSet{{Path: "value"}},
+				},
+			},
+			want: []telegraf.Metric{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New("json_v2", tt.configs, nil)
+			require.NoError(t, err)
+			parser.Now = func() time.Time { return time.Unix(0, 0) }
+
+			actual, err := parser.Parse([]byte(weatherDoc))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, tt.want, actual)
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	parser, err := New("json_v2", []Config{
+		{
+			TimestampPath:     "time",
+			TimestampFormat:   "2006-01-02 15:04:05",
+			TimestampTimezone: "Europe/Berlin",
+			Fields:            []DataSet{{Path: "value"}},
+		},
+	}, map[string]string{"host": "localhost"})
+	require.NoError(t, err)
+
+	m, err := parser.ParseLine(`{"time": "2020-06-01 12:00:00", "value": 1}`)
+	require.NoError(t, err)
+	expected := testutil.MustMetric(
+		"json_v2",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{m})
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		input  string
+	}{
+		{
+			name:   "invalid json",
+			config: Config{Fields: []DataSet{{Path: "value"}}},
+			input:  `{"value": `,
+		},
+		{
+			name:   "invalid conversion",
+			config: Config{Fields: []DataSet{{Path: "value", Type: "int"}}},
+			input:  `{"value": "high"}`,
+		},
+		{
+			name: "missing timestamp",
+			config: Config{
+				TimestampPath:   "time",
+				TimestampFormat: "unix",
+				Fields:          []DataSet{{Path: "value"}},
+			},
+			input: `{"value": 1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New("json_v2", []Config{tt.config}, nil)
+			require.NoError(t, err)
+
+			_, err = parser.Parse([]byte(tt.input))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New("json_v2", nil, nil)
+	require.Error(t, err)
+
+	_, err = New("json_v2", []Config{{TimestampPath: "time"}}, nil)
+	require.Error(t, err)
+
+	_, err = New("json_v2", []Config{
+		{
+			Objects: []Config{
+				{Fields: []DataSet{{Path: "value", Type: "integer"}}},
+			},
+		},
+	}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package msgpack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Parser decodes metrics encoded by the msgpack serializer.
+type Parser struct {
+	DefaultTags map[string]string
+}
+
+// Parse decodes the sequence of MessagePack maps in buf.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for len(buf) > 0 {
+		var m telegraf.Metric
+		var err error
+		m, buf, err = p.readMetric(buf)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// ParseLine decodes a single metric.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(metrics) {
+	case 0:
+		return nil, nil
+	case 1:
+		return metrics[0], nil
+	default:
+		return nil, fmt.Errorf("cannot parse line with multiple (%d) metrics", len(metrics))
+	}
+}
+
+// SetDefaultTags adds tags to the metrics outputs of Parse and ParseLine.
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// readMetric decodes the map at the start of b and returns the remaining
+// bytes.  Unknown keys are skipped.
+func (p *Parser) readMetric(b []byte) (telegraf.Metric, []byte, error) {
+	size, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var name string
+	var tm time.Time
+	tp := telegraf.Untyped
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	for i := uint32(0); i < size; i++ {
+		var key string
+		key, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch key {
+		case "name":
+			name, b, err = msgp.ReadStringBytes(b)
+		case "time":
+			tm, b, err = readTime(b)
+		case "tags":
+			b, err = readTags(b, tags)
+		case "fields":
+			b, err = readFields(b, fields)
+		case "type":
+			var s string
+			s, b, err = msgp.ReadStringBytes(b)
+			tp = msgpack.ParseType(s)
+		default:
+			b, err = msgp.Skip(b)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %q: %v", key, err)
+		}
+	}
+
+	for k, v := range p.DefaultTags {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
+		}
+	}
+
+	m, err := metric.New(name, tags, fields, tm, tp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, b, nil
+}
+
+// readTime reads a timestamp extension or an integer of nanoseconds since
+// the epoch.
+func readTime(b []byte) (time.Time, []byte, error) {
+	if msgp.NextType(b) == msgp.ExtensionType {
+		var ts msgpack.Timestamp
+		b, err := msgp.ReadExtensionBytes(b, &ts)
+		return ts.Time, b, err
+	}
+
+	ns, b, err := msgp.ReadInt64Bytes(b)
+	return time.Unix(0, ns), b, err
+}
+
+func readTags(b []byte, tags map[string]string) ([]byte, error) {
+	size, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < size; i++ {
+		var k, v string
+		k, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		v, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		tags[k] = v
+	}
+	return b, nil
+}
+
+// readFields reads the field map, keeping the distinction of signed and
+// unsigned integers of the encoding.  Fields of other types are skipped.
+func readFields(b []byte, fields map[string]interface{}) ([]byte, error) {
+	size, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < size; i++ {
+		var k string
+		k, b, err = msgp.ReadStringBytes(b)
+		if err != nil {
+			return nil, err
+		}
+
+		switch msgp.NextType(b) {
+		case msgp.IntType:
+			fields[k], b, err = msgp.ReadInt64Bytes(b)
+		case msgp.UintType:
+			fields[k], b, err = msgp.ReadUint64Bytes(b)
+		case msgp.Float64Type:
+			fields[k], b, err = msgp.ReadFloat64Bytes(b)
+		case msgp.Float32Type:
+			var f float32
+			f, b, err = msgp.ReadFloat32Bytes(b)
+			fields[k] = float64(f)
+		case msgp.BoolType:
+			fields[k], b, err = msgp.ReadBoolBytes(b)
+		case msgp.StrType:
+			fields[k], b, err = msgp.ReadStringBytes(b)
+		default:
+			b, err = msgp.Skip(b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", k, err)
+		}
+	}
+	return b, nil
+}
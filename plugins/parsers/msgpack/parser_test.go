@@ -0,0 +1,124 @@
+package msgpack
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "localhost", "cpu": "cpu0"},
+			map[string]interface{}{
+				"int":       int64(-42),
+				"small_int": int64(1),
+				"uint":      uint64(7),
+				"max_uint":  uint64(math.MaxUint64),
+				"float":     1.5,
+				"bool":      true,
+				"string":    "ok",
+			},
+			time.Unix(1600000000, 123456789),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"used": uint64(0)},
+			time.Unix(-1, 5),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"disk",
+			map[string]string{},
+			map[string]interface{}{"free": 0.0},
+			time.Unix(0, 0),
+		),
+	}
+
+	s := msgpack.NewSerializer()
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	p := &Parser{}
+	actual, err := p.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, metrics, actual)
+	for i := range metrics {
+		require.Equal(t, metrics[i].Type(), actual[i].Type())
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+
+	buf, err := msgpack.NewSerializer().Serialize(m)
+	require.NoError(t, err)
+
+	p := &Parser{}
+	p.SetDefaultTags(map[string]string{"host": "default", "region": "eu"})
+	actual, err := p.ParseLine(string(buf))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "localhost", "region": "eu"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{actual})
+}
+
+func TestParseForeignEncoding(t *testing.T) {
+	// Written by another encoder: integer time, float32 value and an
+	// unknown key.
+	var buf []byte
+	buf = msgp.AppendMapHeader(buf, 4)
+	buf = msgp.AppendString(buf, "name")
+	buf = msgp.AppendString(buf, "cpu")
+	buf = msgp.AppendString(buf, "time")
+	buf = msgp.AppendInt64(buf, 1600000000000000000)
+	buf = msgp.AppendString(buf, "fields")
+	buf = msgp.AppendMapHeader(buf, 2)
+	buf = msgp.AppendString(buf, "value")
+	buf = msgp.AppendFloat32(buf, 0.5)
+	buf = msgp.AppendString(buf, "list")
+	buf = msgp.AppendArrayHeader(buf, 1)
+	buf = msgp.AppendInt(buf, 1)
+	buf = msgp.AppendString(buf, "unknown")
+	buf = msgp.AppendNil(buf)
+
+	p := &Parser{}
+	actual, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 0.5},
+		time.Unix(1600000000, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, actual)
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := &Parser{}
+	_, err := p.Parse([]byte{0x81, 0xa4, 'n', 'a'})
+	require.Error(t, err)
+
+	_, err = p.Parse([]byte{0x01})
+	require.Error(t, err)
+}
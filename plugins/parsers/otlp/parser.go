@@ -0,0 +1,78 @@
+package otlp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/otlp"
+)
+
+// Parser decodes OTLP ExportMetricsServiceRequests into metrics.
+type Parser struct {
+	MetricName  string
+	DefaultTags map[string]string
+	Now         func() time.Time
+
+	format string
+}
+
+// NewParser creates a parser for the format, either "protobuf" or "json".
+func NewParser(metricName string, format string, defaultTags map[string]string) (*Parser, error) {
+	switch format {
+	case "":
+		format = "protobuf"
+	case "protobuf", "json":
+	default:
+		return nil, fmt.Errorf("invalid otlp_format %q", format)
+	}
+
+	return &Parser{
+		MetricName:  metricName,
+		DefaultTags: defaultTags,
+		Now:         time.Now,
+		format:      format,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	req, err := otlp.Unmarshal(buf, p.format)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := otlp.ToMetrics(req, p.MetricName, p.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range metrics {
+		for k, v := range p.DefaultTags {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// ParseLine decodes a request containing a single data point.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(metrics) {
+	case 0:
+		return nil, nil
+	case 1:
+		return metrics[0], nil
+	default:
+		return nil, fmt.Errorf("cannot parse line with multiple (%d) metrics", len(metrics))
+	}
+}
+
+// SetDefaultTags adds tags to the metrics outputs of Parse and ParseLine.
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
@@ -0,0 +1,59 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const request = `{
+  "resourceMetrics": [{
+    "resource": {
+      "attributes": [{"key": "host", "value": {"stringValue": "localhost"}}]
+    },
+    "scopeMetrics": [{
+      "metrics": [{
+        "name": "temperature",
+        "gauge": {
+          "dataPoints": [{"asDouble": 21.5}]
+        }
+      }]
+    }]
+  }]
+}`
+
+func TestParseLine(t *testing.T) {
+	p, err := NewParser("otlp", "json", map[string]string{"host": "default", "region": "eu"})
+	require.NoError(t, err)
+	p.Now = func() time.Time { return time.Unix(42, 0) }
+
+	actual, err := p.ParseLine(request)
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"otlp",
+		map[string]string{"host": "localhost", "region": "eu"},
+		map[string]interface{}{"temperature": 21.5},
+		time.Unix(42, 0),
+		telegraf.Gauge,
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{actual})
+}
+
+func TestParseInvalid(t *testing.T) {
+	p, err := NewParser("otlp", "protobuf", nil)
+	require.NoError(t, err)
+	_, err = p.Parse([]byte{0xff, 0xff})
+	require.Error(t, err)
+
+	p, err = NewParser("otlp", "json", nil)
+	require.NoError(t, err)
+	_, err = p.Parse([]byte(`{"resourceMetrics": 1}`))
+	require.Error(t, err)
+
+	_, err = NewParser("otlp", "xml", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/record"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+type Config struct {
+	record.Config
+
+	// SchemaFile is the .proto file defining the message type.
+	SchemaFile string
+	// ImportPaths are searched for imports of the schema files.
+	ImportPaths []string
+	// MessageType is the fully qualified name of the message type in the
+	// schema file, e.g. example.Measurement.
+	MessageType string
+	// ConfluentWireFormat enables messages prefixed by a schema ID and the
+	// indexes of the message type.
+	ConfluentWireFormat bool
+	// SchemaDirectory contains the .proto files of the schema IDs of
+	// messages in the confluent wire format.
+	SchemaDirectory string
+}
+
+// Parser decodes Protocol Buffers messages into metrics.
+type Parser struct {
+	Now func() time.Time
+
+	mapper      *record.Mapper
+	confluent   bool
+	messageType *desc.MessageDescriptor
+	files       map[int]*desc.FileDescriptor
+}
+
+// New creates a parser, loading all schemas from disk.
+func New(config *Config) (*Parser, error) {
+	mapper, err := record.NewMapper(&config.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Parser{
+		Now:       time.Now,
+		mapper:    mapper,
+		confluent: config.ConfluentWireFormat,
+		files:     make(map[int]*desc.FileDescriptor),
+	}
+
+	if config.SchemaFile != "" {
+		if config.MessageType == "" {
+			return nil, fmt.Errorf("schema file requires a message type")
+		}
+		fd, err := loadSchema(config.SchemaFile, config.ImportPaths)
+		if err != nil {
+			return nil, err
+		}
+		p.messageType = fd.FindMessage(config.MessageType)
+		if p.messageType == nil {
+			return nil, fmt.Errorf("message type %q not found in %s", config.MessageType, config.SchemaFile)
+		}
+	}
+
+	if config.SchemaDirectory != "" {
+		if !p.confluent {
+			return nil, fmt.Errorf("schema directory requires the confluent wire format")
+		}
+		files, err := record.SchemaFiles(config.SchemaDirectory, ".proto")
+		if err != nil {
+			return nil, err
+		}
+		importPaths := append([]string{config.SchemaDirectory}, config.ImportPaths...)
+		for id, file := range files {
+			p.files[id], err = loadSchema(file, importPaths)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if p.messageType == nil && len(p.files) == 0 {
+		return nil, fmt.Errorf("no schema file or schema directory with schemas configured")
+	}
+	return p, nil
+}
+
+func loadSchema(file string, importPaths []string) (*desc.FileDescriptor, error) {
+	parser := protoparse.Parser{
+		ImportPaths: append([]string{filepath.Dir(file)}, importPaths...),
+	}
+	fds, err := parser.ParseFiles(filepath.Base(file))
+	if err != nil {
+		return nil, fmt.Errorf("loading schema %s: %v", file, err)
+	}
+	return fds[0], nil
+}
+
+// Parse decodes the message in buf into a metric.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	md := p.messageType
+	if p.confluent {
+		id, payload, err := record.SplitConfluent(buf)
+		if err != nil {
+			return nil, err
+		}
+		indexes, payload, err := messageIndexes(payload)
+		if err != nil {
+			return nil, err
+		}
+		if fd, ok := p.files[id]; ok {
+			md, err = findMessage(fd, indexes)
+			if err != nil {
+				return nil, fmt.Errorf("schema ID %d: %v", id, err)
+			}
+		} else if md == nil {
+			return nil, fmt.Errorf("unknown schema ID %d", id)
+		}
+		buf = payload
+	}
+
+	msg := dynamic.NewMessage(md)
+	if err := msg.Unmarshal(buf); err != nil {
+		return nil, err
+	}
+
+	m, err := p.mapper.Metric(toRecord(msg), p.Now())
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.mapper.SetDefaultTags(tags)
+}
+
+// messageIndexes reads the indexes of the message type following the schema
+// ID in the confluent wire format.  The indexes are an array of zig-zag
+// encoded varints, where an empty array stands for the first message type.
+func messageIndexes(buf []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(buf)
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("invalid message indexes")
+	}
+	buf = buf[n:]
+	if count == 0 {
+		return []int{0}, buf, nil
+	}
+	// Every index takes at least one byte.
+	if count < 0 || count > int64(len(buf)) {
+		return nil, nil, fmt.Errorf("invalid message index count %d", count)
+	}
+
+	indexes := make([]int, 0, count)
+	for i := int64(0); i < count; i++ {
+		index, n := binary.Varint(buf)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("invalid message indexes")
+		}
+		indexes = append(indexes, int(index))
+		buf = buf[n:]
+	}
+	return indexes, buf, nil
+}
+
+// findMessage returns the message type at the indexes, the first index
+// selects the message type in the file and the following nested types.
+func findMessage(fd *desc.FileDescriptor, indexes []int) (*desc.MessageDescriptor, error) {
+	types := fd.GetMessageTypes()
+	var md *desc.MessageDescriptor
+	for _, i := range indexes {
+		if i < 0 || i >= len(types) {
+			return nil, fmt.Errorf("message index %v out of range", indexes)
+		}
+		md = types[i]
+		types = md.GetNestedMessageTypes()
+	}
+	return md, nil
+}
+
+// toRecord converts the message into a record.  Enums are converted into
+// their names and google.protobuf.Timestamp messages into times.
+func toRecord(msg *dynamic.Message) map[string]interface{} {
+	rec := make(map[string]interface{})
+	for _, fd := range msg.GetMessageDescriptor().GetFields() {
+		if fd.GetOneOf() != nil || (fd.GetMessageType() != nil && !fd.IsRepeated()) {
+			// Skip unset members of oneofs and messages, scalars are always
+			// set to include zero values in proto3.
+			if !msg.HasField(fd) {
+				continue
+			}
+		}
+
+		v := msg.GetField(fd)
+		switch {
+		case fd.IsMap():
+			values := make(map[string]interface{})
+			for k, item := range v.(map[interface{}]interface{}) {
+				values[fmt.Sprint(k)] = toValue(fd.GetMapValueType(), item)
+			}
+			rec[fd.GetName()] = values
+		case fd.IsRepeated():
+			items := v.([]interface{})
+			values := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				values = append(values, toValue(fd, item))
+			}
+			rec[fd.GetName()] = values
+		default:
+			rec[fd.GetName()] = toValue(fd, v)
+		}
+	}
+	return rec
+}
+
+func toValue(fd *desc.FieldDescriptor, v interface{}) interface{} {
+	if pm, ok := v.(proto.Message); ok {
+		// Well-known types are decoded into their generated types.
+		if msg, err := dynamic.AsDynamicMessage(pm); err == nil {
+			v = msg
+		}
+	}
+
+	switch v := v.(type) {
+	case *dynamic.Message:
+		if v.GetMessageDescriptor().GetFullyQualifiedName() == "google.protobuf.Timestamp" {
+			seconds, _ := v.GetFieldByName("seconds").(int64)
+			nanos, _ := v.GetFieldByName("nanos").(int32)
+			return time.Unix(seconds, int64(nanos)).UTC()
+		}
+		return toRecord(v)
+	case int32:
+		if enum := fd.GetEnumType(); enum != nil {
+			if value := enum.FindValueByNumber(v); value != nil {
+				return value.GetName()
+			}
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,253 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/record"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/require"
+)
+
+func measurement(t *testing.T, md *desc.MessageDescriptor) []byte {
+	t.Helper()
+
+	ts := dynamic.NewMessage(md.FindFieldByName("time").GetMessageType())
+	ts.SetFieldByName("seconds", int64(1600000000))
+	ts.SetFieldByName("nanos", int32(500))
+
+	location := dynamic.NewMessage(md.FindFieldByName("location").GetMessageType())
+	location.SetFieldByName("region", "eu-west")
+	location.SetFieldByName("rack", uint32(4))
+
+	msg := dynamic.NewMessage(md)
+	msg.SetFieldByName("name", "disk")
+	msg.SetFieldByName("host", "server01")
+	msg.SetFieldByName("value", 42.5)
+	msg.SetFieldByName("status", int32(2))
+	msg.SetFieldByName("time", ts)
+	msg.SetFieldByName("location", location)
+	msg.SetFieldByName("extra", map[interface{}]interface{}{"load": 0.5})
+	msg.SetFieldByName("samples", []interface{}{int32(1), int32(2)})
+
+	buf, err := msg.Marshal()
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		Config: record.Config{
+			MetricName:   "protobuf",
+			TagKeys:      []string{"host", "location_region"},
+			NameKey:      "name",
+			StringFields: []string{"status"},
+			TimeKey:      "time",
+		},
+		SchemaFile:  "testdata/measurement.proto",
+		MessageType: "example.Measurement",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(measurement(t, parser.messageType))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"disk",
+			map[string]string{
+				"host":            "server01",
+				"location_region": "eu-west",
+			},
+			map[string]interface{}{
+				"value":         42.5,
+				"count":         int64(0),
+				"status":        "FAILED",
+				"location_rack": uint64(4),
+				"extra_load":    0.5,
+				"samples_0":     int64(1),
+				"samples_1":     int64(2),
+			},
+			time.Unix(1600000000, 500),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	parser, err := New(&Config{
+		Config: record.Config{
+			MetricName: "protobuf",
+		},
+		SchemaFile:  "testdata/measurement.proto",
+		MessageType: "example.Measurement",
+	})
+	require.NoError(t, err)
+	parser.SetDefaultTags(map[string]string{"source": "kafka"})
+	parser.Now = func() time.Time { return time.Unix(0, 0) }
+
+	msg := dynamic.NewMessage(parser.messageType)
+	msg.SetFieldByName("value", 1.0)
+	buf, err := msg.Marshal()
+	require.NoError(t, err)
+
+	m, err := parser.ParseLine(string(buf))
+	require.NoError(t, err)
+	expected := testutil.MustMetric(
+		"protobuf",
+		map[string]string{"source": "kafka"},
+		map[string]interface{}{"value": 1.0, "count": int64(0)},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{m})
+}
+
+func TestParseConfluent(t *testing.T) {
+	parser, err := New(&Config{
+		Config: record.Config{
+			MetricName:   "protobuf",
+			StringFields: []string{"*"},
+		},
+		ConfluentWireFormat: true,
+		SchemaDirectory:     "testdata/registry",
+	})
+	require.NoError(t, err)
+	parser.Now = func() time.Time { return time.Unix(0, 0) }
+
+	fd := parser.files[7]
+	require.NotNil(t, fd)
+
+	tests := []struct {
+		name     string
+		header   []byte
+		msg      *dynamic.Message
+		expected map[string]interface{}
+	}{
+		{
+			name:   "first message type",
+			header: []byte{0, 0, 0, 0, 7, 0},
+			msg: func() *dynamic.Message {
+				msg := dynamic.NewMessage(fd.FindMessage("example.Event"))
+				msg.SetFieldByName("name", "started")
+				return msg
+			}(),
+			expected: map[string]interface{}{"name": "started"},
+		},
+		{
+			name: "nested message type",
+			// two indexes, 1 and 0, zig-zag encoded
+			header: []byte{0, 0, 0, 0, 7, 4, 2, 0},
+			msg: func() *dynamic.Message {
+				msg := dynamic.NewMessage(fd.FindMessage("example.Reading.Sensor"))
+				msg.SetFieldByName("id", "s1")
+				msg.SetFieldByName("temperature", float32(21.5))
+				return msg
+			}(),
+			expected: map[string]interface{}{"id": "s1", "temperature": 21.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := tt.msg.Marshal()
+			require.NoError(t, err)
+
+			metrics, err := parser.Parse(append(tt.header, buf...))
+			require.NoError(t, err)
+			expected := testutil.MustMetric("protobuf", map[string]string{}, tt.expected, time.Unix(0, 0))
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, metrics)
+		})
+	}
+
+	_, err = parser.Parse([]byte{0, 0, 0, 0, 8, 0})
+	require.Error(t, err)
+	_, err = parser.Parse([]byte{0, 0, 0, 0, 7, 2, 10})
+	require.Error(t, err)
+}
+
+func TestParseConfluentInvalidHeader(t *testing.T) {
+	parser, err := New(&Config{
+		ConfluentWireFormat: true,
+		SchemaDirectory:     "testdata/registry",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{
+			name:   "truncated schema ID",
+			header: []byte{0, 0, 0},
+		},
+		{
+			name:   "missing message indexes",
+			header: []byte{0, 0, 0, 0, 7},
+		},
+		{
+			name:   "truncated index count",
+			header: []byte{0, 0, 0, 0, 7, 0x80},
+		},
+		{
+			name: "negative index count",
+			// -1 zig-zag encoded
+			header: []byte{0, 0, 0, 0, 7, 1},
+		},
+		{
+			name: "huge negative index count",
+			// math.MinInt64 zig-zag encoded
+			header: []byte{0, 0, 0, 0, 7, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		},
+		{
+			name: "huge index count",
+			// math.MaxInt64 zig-zag encoded
+			header: []byte{0, 0, 0, 0, 7, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		},
+		{
+			name: "index count beyond message",
+			// three indexes, one given
+			header: []byte{0, 0, 0, 0, 7, 6, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.Parse(tt.header)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "no schema",
+			config: &Config{},
+		},
+		{
+			name:   "missing message type",
+			config: &Config{SchemaFile: "testdata/measurement.proto"},
+		},
+		{
+			name: "unknown message type",
+			config: &Config{
+				SchemaFile:  "testdata/measurement.proto",
+				MessageType: "example.Unknown",
+			},
+		},
+		{
+			name:   "directory without confluent wire format",
+			config: &Config{SchemaDirectory: "testdata/registry"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config)
+			require.Error(t, err)
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/record"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
+	"github.com/influxdata/telegraf/plugins/parsers/cbor"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
@@ -12,11 +15,16 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/grok"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	"github.com/influxdata/telegraf/plugins/parsers/msgpack"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/otlp"
 	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
 )
 
 type ParserFunc func() (Parser, error)
@@ -80,6 +88,9 @@ type Config struct {
 	// holds a gjson path for json parser
 	JSONQuery string `toml:"json_query"`
 
+	// JSONV2Config holds the object selections of the json_v2 parser
+	JSONV2Config []json_v2.Config `toml:"json_v2"`
+
 	// key of time
 	JSONTimeKey string `toml:"json_time_key"`
 
@@ -150,6 +161,34 @@ type Config struct {
 
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
+
+	// XML configuration, one for each metric selection
+	XMLConfig []xml.Config `toml:"xml"`
+
+	// Protobuf configuration
+	ProtobufSchemaFile          string   `toml:"protobuf_schema_file"`
+	ProtobufImportPaths         []string `toml:"protobuf_import_paths"`
+	ProtobufMessageType         string   `toml:"protobuf_message_type"`
+	ProtobufConfluentWireFormat bool     `toml:"protobuf_confluent_wire_format"`
+	ProtobufSchemaDirectory     string   `toml:"protobuf_schema_directory"`
+	ProtobufNameKey             string   `toml:"protobuf_name_key"`
+	ProtobufStringFields        []string `toml:"protobuf_string_fields"`
+	ProtobufTimeKey             string   `toml:"protobuf_time_key"`
+	ProtobufTimeFormat          string   `toml:"protobuf_time_format"`
+	ProtobufTimezone            string   `toml:"protobuf_timezone"`
+
+	// Avro configuration
+	AvroSchemaFile          string   `toml:"avro_schema_file"`
+	AvroConfluentWireFormat bool     `toml:"avro_confluent_wire_format"`
+	AvroSchemaDirectory     string   `toml:"avro_schema_directory"`
+	AvroNameKey             string   `toml:"avro_name_key"`
+	AvroStringFields        []string `toml:"avro_string_fields"`
+	AvroTimeKey             string   `toml:"avro_time_key"`
+	AvroTimeFormat          string   `toml:"avro_time_format"`
+	AvroTimezone            string   `toml:"avro_timezone"`
+
+	// OTLP encoding, either protobuf or json
+	OTLPFormat string `toml:"otlp_format"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -172,6 +211,8 @@ func NewParser(config *Config) (Parser, error) {
 				Strict:       config.JSONStrict,
 			},
 		)
+	case "json_v2":
+		parser, err = json_v2.New(config.MetricName, config.JSONV2Config, config.DefaultTags)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
 			config.DataType, config.DefaultTags)
@@ -237,6 +278,48 @@ func NewParser(config *Config) (Parser, error) {
 		)
 	case "prometheus":
 		parser, err = NewPrometheusParser(config.DefaultTags)
+	case "xml":
+		parser, err = xml.New(config.MetricName, config.XMLConfig, config.DefaultTags)
+	case "protobuf":
+		parser, err = protobuf.New(&protobuf.Config{
+			Config: record.Config{
+				MetricName:   config.MetricName,
+				TagKeys:      config.TagKeys,
+				NameKey:      config.ProtobufNameKey,
+				StringFields: config.ProtobufStringFields,
+				TimeKey:      config.ProtobufTimeKey,
+				TimeFormat:   config.ProtobufTimeFormat,
+				Timezone:     config.ProtobufTimezone,
+				DefaultTags:  config.DefaultTags,
+			},
+			SchemaFile:          config.ProtobufSchemaFile,
+			ImportPaths:         config.ProtobufImportPaths,
+			MessageType:         config.ProtobufMessageType,
+			ConfluentWireFormat: config.ProtobufConfluentWireFormat,
+			SchemaDirectory:     config.ProtobufSchemaDirectory,
+		})
+	case "avro":
+		parser, err = avro.New(&avro.Config{
+			Config: record.Config{
+				MetricName:   config.MetricName,
+				TagKeys:      config.TagKeys,
+				NameKey:      config.AvroNameKey,
+				StringFields: config.AvroStringFields,
+				TimeKey:      config.AvroTimeKey,
+				TimeFormat:   config.AvroTimeFormat,
+				Timezone:     config.AvroTimezone,
+				DefaultTags:  config.DefaultTags,
+			},
+			SchemaFile:          config.AvroSchemaFile,
+			ConfluentWireFormat: config.AvroConfluentWireFormat,
+			SchemaDirectory:     config.AvroSchemaDirectory,
+		})
+	case "msgpack":
+		parser = &msgpack.Parser{DefaultTags: config.DefaultTags}
+	case "cbor":
+		parser = &cbor.Parser{DefaultTags: config.DefaultTags}
+	case "otlp":
+		parser, err = otlp.NewParser(config.MetricName, config.OTLPFormat, config.DefaultTags)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -0,0 +1,271 @@
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Config selects metrics from a XML document.  All queries except the
+// selection are evaluated relative to the selected node.
+type Config struct {
+	// Selection selects the nodes to create a metric from, by default the
+	// document root is used.
+	Selection string `toml:"metric_selection"`
+	// MetricQuery is the query of the metric name, by default the name of
+	// the parser is used.
+	MetricQuery string `toml:"metric_name"`
+
+	Timestamp    string `toml:"timestamp"`
+	TimestampFmt string `toml:"timestamp_format"`
+
+	Tags      map[string]string `toml:"tags"`
+	Fields    map[string]string `toml:"fields"`
+	FieldsInt map[string]string `toml:"fields_int"`
+}
+
+// Parser decodes XML documents into metrics using XPath queries.
+type Parser struct {
+	MetricName  string
+	Configs     []Config
+	DefaultTags map[string]string
+	Now         func() time.Time
+
+	queries map[string]*xpath.Expr
+}
+
+// New creates a parser and compiles the queries of its configs.
+func New(metricName string, configs []Config, defaultTags map[string]string) (*Parser, error) {
+	p := &Parser{
+		MetricName:  metricName,
+		Configs:     configs,
+		DefaultTags: defaultTags,
+		Now:         time.Now,
+		queries:     make(map[string]*xpath.Expr),
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no metric selection configured")
+	}
+	for i, config := range configs {
+		queries := []string{config.Selection, config.MetricQuery, config.Timestamp}
+		for _, m := range []map[string]string{config.Tags, config.Fields, config.FieldsInt} {
+			for _, query := range m {
+				queries = append(queries, query)
+			}
+		}
+		for _, query := range queries {
+			if err := p.compile(query); err != nil {
+				return nil, fmt.Errorf("config %d: %v", i+1, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *Parser) compile(query string) error {
+	if query == "" {
+		return nil
+	}
+	if _, ok := p.queries[query]; ok {
+		return nil
+	}
+	expr, err := xpath.Compile(query)
+	if err != nil {
+		return fmt.Errorf("invalid query %q: %v", query, err)
+	}
+	p.queries[query] = expr
+	return nil
+}
+
+// Parse converts a XML document to metrics, one for each node selected by
+// each config.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.Now()
+	metrics := make([]telegraf.Metric, 0)
+	for i, config := range p.Configs {
+		selected := []*xmlquery.Node{doc}
+		if config.Selection != "" {
+			selected = xmlquery.QuerySelectorAll(doc, p.queries[config.Selection])
+		}
+
+		for _, node := range selected {
+			m, err := p.parseNode(doc, node, &config, t)
+			if err != nil {
+				return nil, fmt.Errorf("config %d: %v", i+1, err)
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a single XML document, the document must result in
+// exactly one metric.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(metrics) {
+	case 0:
+		return nil, nil
+	case 1:
+		return metrics[0], nil
+	default:
+		return nil, fmt.Errorf("cannot parse line with multiple (%d) metrics", len(metrics))
+	}
+}
+
+// SetDefaultTags adds tags to the metrics outputs of Parse and ParseLine.
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) parseNode(doc, node *xmlquery.Node, config *Config, t time.Time) (telegraf.Metric, error) {
+	nav := navigator(doc, node)
+
+	name := p.MetricName
+	if config.MetricQuery != "" {
+		v, ok := p.query(nav, config.MetricQuery)
+		if ok {
+			name = toString(v)
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("empty metric name")
+	}
+
+	if config.Timestamp != "" {
+		v, ok := p.query(nav, config.Timestamp)
+		if !ok {
+			return nil, fmt.Errorf("timestamp %q not found", config.Timestamp)
+		}
+		format := config.TimestampFmt
+		if format == "" {
+			format = "unix"
+		}
+		var err error
+		t, err = internal.ParseTimestamp(format, toString(v), "")
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp: %v", err)
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for key, query := range config.Tags {
+		if v, ok := p.query(nav, query); ok {
+			tags[key] = toString(v)
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for key, query := range config.Fields {
+		if v, ok := p.query(nav, query); ok {
+			fields[key] = v
+		}
+	}
+	for key, query := range config.FieldsInt {
+		v, ok := p.query(nav, query)
+		if !ok {
+			continue
+		}
+		iv, err := toInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+		fields[key] = iv
+	}
+
+	return metric.New(name, tags, fields, t)
+}
+
+// navigator returns a navigator of the document positioned at node, so
+// absolute queries still refer to the document root.
+func navigator(doc, node *xmlquery.Node) *xmlquery.NodeNavigator {
+	var path []*xmlquery.Node
+	for n := node; n != nil && n != doc; n = n.Parent {
+		path = append(path, n)
+	}
+
+	nav := xmlquery.CreateXPathNavigator(doc)
+	for i := len(path) - 1; i >= 0; i-- {
+		nav.MoveToChild()
+		for nav.Current() != path[i] && nav.MoveToNext() {
+		}
+	}
+	return nav
+}
+
+// query evaluates the query relative to the current node of nav.  Numbers,
+// booleans and strings resulting from XPath functions are returned as is, for
+// node sets the text of the first node is returned.  The result is false if
+// the query selects no nodes.
+func (p *Parser) query(nav *xmlquery.NodeNavigator, query string) (interface{}, bool) {
+	v := p.queries[query].Evaluate(nav.Copy())
+	switch v := v.(type) {
+	case *xpath.NodeIterator:
+		if !v.MoveNext() {
+			return nil, false
+		}
+		nav := v.Current().(*xmlquery.NodeNavigator)
+		return strings.TrimSpace(nav.Value()), true
+	case float64, bool, string:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		if iv, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return iv, nil
+		}
+		fv, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %q to integer", v)
+		}
+		return int64(fv), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %v to integer", v)
+	}
+}
@@ -0,0 +1,307 @@
+package xml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const busDoc = `<?xml version="1.0"?>
+<Gateway>
+  <Name>Main Gateway</Name>
+  <Timestamp>1577923199</Timestamp>
+  <Sequence>12</Sequence>
+  <Status>
+    <ok>true</ok>
+  </Status>
+  <Bus>
+    <Sensor name="Sensor Facility A">
+      <Variable temperature="20.0"/>
+      <Variable power="123.4"/>
+      <Variable frequency="49.78"/>
+      <Variable consumers="3"/>
+      <Mode>busy</Mode>
+    </Sensor>
+    <Sensor name="Sensor Facility B">
+      <Variable temperature="23.1"/>
+      <Variable power="14.3"/>
+      <Variable frequency="49.78"/>
+      <Variable consumers="1"/>
+      <Mode>standby</Mode>
+    </Sensor>
+  </Bus>
+</Gateway>
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []Config
+		input   string
+		want    []telegraf.Metric
+	}{
+		{
+			name: "document root",
+			configs: []Config{
+				{
+					Tags: map[string]string{
+						"gateway": "/Gateway/Name",
+					},
+					Fields: map[string]string{
+						"ok":       "/Gateway/Status/ok = 'true'",
+						"sequence": "number(/Gateway/Sequence)",
+						"name":     "/Gateway/Name",
+						"missing":  "/Gateway/Missing",
+					},
+					FieldsInt: map[string]string{
+						"sequence_int": "/Gateway/Sequence",
+					},
+				},
+			},
+			input: busDoc,
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"xml",
+					map[string]string{
+						"gateway": "Main Gateway",
+					},
+					map[string]interface{}{
+						"ok":           true,
+						"sequence":     12.0,
+						"name":         "Main Gateway",
+						"sequence_int": int64(12),
+					},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "metric selection",
+			configs: []Config{
+				{
+					Selection:   "/Gateway/Bus/Sensor",
+					MetricQuery: "'sensor'",
+					Timestamp:   "/Gateway/Timestamp",
+					Tags: map[string]string{
+						"name": "@name",
+						"mode": "Mode",
+					},
+					Fields: map[string]string{
+						"temperature": "number(Variable/@temperature)",
+						"power":       "number(Variable/@power)",
+					},
+					FieldsInt: map[string]string{
+						"consumers": "Variable/@consumers",
+					},
+				},
+			},
+			input: busDoc,
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"sensor",
+					map[string]string{
+						"name": "Sensor Facility A",
+						"mode": "busy",
+					},
+					map[string]interface{}{
+						"temperature": 20.0,
+						"power":       123.4,
+						"consumers":   int64(3),
+					},
+					time.Unix(1577923199, 0),
+				),
+				testutil.MustMetric(
+					"sensor",
+					map[string]string{
+						"name": "Sensor Facility B",
+						"mode": "standby",
+					},
+					map[string]interface{}{
+						"temperature": 23.1,
+						"power":       14.3,
+						"consumers":   int64(1),
+					},
+					time.Unix(1577923199, 0),
+				),
+			},
+		},
+		{
+			name: "multiple selections",
+			configs: []Config{
+				{
+					Selection:   "/Gateway",
+					MetricQuery: "'gateway'",
+					FieldsInt: map[string]string{
+						"sequence": "Sequence",
+					},
+				},
+				{
+					Selection:   "//Sensor",
+					MetricQuery: "concat('sensor_', Mode)",
+					Fields: map[string]string{
+						"frequency": "number(Variable/@frequency)",
+					},
+				},
+			},
+			input: busDoc,
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"gateway",
+					map[string]string{},
+					map[string]interface{}{
+						"sequence": int64(12),
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"sensor_busy",
+					map[string]string{},
+					map[string]interface{}{
+						"frequency": 49.78,
+					},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric(
+					"sensor_standby",
+					map[string]string{},
+					map[string]interface{}{
+						"frequency": 49.78,
+					},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "timestamp format",
+			configs: []Config{
+				{
+					Selection:    "/Data",
+					Timestamp:    "@time",
+					TimestampFmt: "2006-01-02T15:04:05Z07:00",
+					Fields: map[string]string{
+						"value": "number(.)",
+					},
+				},
+			},
+			input: `<Data time="2020-01-02T00:00:00Z">42</Data>`,
+			want: []telegraf.Metric{
+				testutil.MustMetric(
+					"xml",
+					map[string]string{},
+					map[string]interface{}{
+						"value": 42.0,
+					},
+					time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				),
+			},
+		},
+		{
+			name: "no nodes selected",
+			configs: []Config{
+				{
+					Selection: "/Gateway/Missing",
+					Fields: map[string]string{
+						"value": "number(.)",
+					},
+				},
+			},
+			input: busDoc,
+			want:  []telegraf.Metric{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New("xml", tt.configs, nil)
+			require.NoError(t, err)
+			parser.Now = func() time.Time { return time.Unix(0, 0) }
+
+			actual, err := parser.Parse([]byte(tt.input))
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, tt.want, actual)
+		})
+	}
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	parser, err := New("xml", []Config{
+		{
+			Selection: "/Data",
+			Fields: map[string]string{
+				"value": "number(.)",
+			},
+		},
+	}, map[string]string{"host": "localhost"})
+	require.NoError(t, err)
+	parser.Now = func() time.Time { return time.Unix(0, 0) }
+
+	actual, err := parser.ParseLine(`<Data>42</Data>`)
+	require.NoError(t, err)
+	expected := testutil.MustMetric(
+		"xml",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{actual})
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []Config
+		input   string
+	}{
+		{
+			name: "invalid integer",
+			configs: []Config{
+				{
+					FieldsInt: map[string]string{"name": "/Gateway/Name"},
+				},
+			},
+			input: busDoc,
+		},
+		{
+			name: "missing timestamp",
+			configs: []Config{
+				{
+					Timestamp: "/Gateway/Missing",
+					Fields:    map[string]string{"name": "/Gateway/Name"},
+				},
+			},
+			input: busDoc,
+		},
+		{
+			name: "invalid document",
+			configs: []Config{
+				{
+					Fields: map[string]string{"name": "/Gateway/Name"},
+				},
+			},
+			input: `<Gateway><Name>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New("xml", tt.configs, nil)
+			require.NoError(t, err)
+
+			_, err = parser.Parse([]byte(tt.input))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestInvalidQuery(t *testing.T) {
+	_, err := New("xml", []Config{
+		{
+			Selection: "/Gateway[",
+		},
+	}, nil)
+	require.Error(t, err)
+
+	_, err = New("xml", nil, nil)
+	require.Error(t, err)
+}
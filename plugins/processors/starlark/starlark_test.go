@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -24,60 +25,74 @@ func TestInitError(t *testing.T) {
 		{
 			name: "source must define apply",
 			plugin: &Starlark{
-				Source: "",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Source: "",
+					Log:    testutil.Logger{},
+				},
 			},
 		},
 		{
 			name: "apply must be a function",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 apply = 42
 `,
-				Log: testutil.Logger{},
+					Log: testutil.Logger{},
+				},
 			},
 		},
 		{
 			name: "apply function must take one arg",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 def apply():
 	pass
 `,
-				Log: testutil.Logger{},
+					Log: testutil.Logger{},
+				},
 			},
 		},
 		{
 			name: "package scope must have valid syntax",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 for
 `,
-				Log: testutil.Logger{},
+					Log: testutil.Logger{},
+				},
 			},
 		},
 		{
 			name: "no source no script",
 			plugin: &Starlark{
-				Log: testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Log: testutil.Logger{},
+				},
 			},
 		},
 		{
 			name: "source and script",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 def apply():
 	pass
 `,
-				Script: "testdata/ratio.star",
-				Log:    testutil.Logger{},
+					Script: "testdata/ratio.star",
+					Log:    testutil.Logger{},
+				},
 			},
 		},
 		{
 			name: "script file not found",
 			plugin: &Starlark{
-				Script: "testdata/file_not_found.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/file_not_found.star",
+					Log:    testutil.Logger{},
+				},
 			},
 		},
 	}
@@ -217,8 +232,10 @@ def apply(metric):
 	for _, tt := range applyTests {
 		t.Run(tt.name, func(t *testing.T) {
 			plugin := &Starlark{
-				Source: tt.source,
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Source: tt.source,
+					Log:    testutil.Logger{},
+				},
 			}
 			err := plugin.Init()
 			require.NoError(t, err)
@@ -2423,8 +2440,10 @@ def process(metric):
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			plugin := &Starlark{
-				Source: tt.source,
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Source: tt.source,
+					Log:    testutil.Logger{},
+				},
 			}
 			err := plugin.Init()
 			require.NoError(t, err)
@@ -2462,8 +2481,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "rename",
 			plugin: &Starlark{
-				Script: "testdata/rename.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/rename.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("cpu",
@@ -2489,8 +2510,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "drop fields by type",
 			plugin: &Starlark{
-				Script: "testdata/drop_string_fields.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/drop_string_fields.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("device",
@@ -2520,8 +2543,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "drop fields with unexpected type",
 			plugin: &Starlark{
-				Script: "testdata/drop_fields_with_unexpected_type.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/drop_fields_with_unexpected_type.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("device",
@@ -2554,8 +2579,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "scale",
 			plugin: &Starlark{
-				Script: "testdata/scale.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/scale.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("cpu",
@@ -2575,8 +2602,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "ratio",
 			plugin: &Starlark{
-				Script: "testdata/ratio.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/ratio.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("mem",
@@ -2603,8 +2632,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "logging",
 			plugin: &Starlark{
-				Script: "testdata/logging.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/logging.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("log",
@@ -2628,8 +2659,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "multiple_metrics",
 			plugin: &Starlark{
-				Script: "testdata/multiple_metrics.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/multiple_metrics.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("mm",
@@ -2660,8 +2693,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "multiple_metrics_with_json",
 			plugin: &Starlark{
-				Script: "testdata/multiple_metrics_with_json.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/multiple_metrics_with_json.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("json",
@@ -2692,8 +2727,10 @@ func TestScript(t *testing.T) {
 		{
 			name: "fail",
 			plugin: &Starlark{
-				Script: "testdata/fail.star",
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Script: "testdata/fail.star",
+					Log:    testutil.Logger{},
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("fail",
@@ -2981,8 +3018,10 @@ def apply(metric):
 	for _, tt := range tests {
 		b.Run(tt.name, func(b *testing.B) {
 			plugin := &Starlark{
-				Source: tt.source,
-				Log:    testutil.Logger{},
+				StarlarkCommon: common.StarlarkCommon{
+					Source: tt.source,
+					Log:    testutil.Logger{},
+				},
 			}
 
 			err := plugin.Init()
@@ -3026,8 +3065,10 @@ func TestAllScriptTestData(t *testing.T) {
 					outputMetrics = parseMetricsFrom(t, lines, "Example Output:")
 				}
 				plugin := &Starlark{
-					Script: fn,
-					Log:    testutil.Logger{},
+					StarlarkCommon: common.StarlarkCommon{
+						Script: fn,
+						Log:    testutil.Logger{},
+					},
 				}
 				require.NoError(t, plugin.Init())
 
@@ -3100,3 +3141,102 @@ func parseErrorMessage(t *testing.T, lines []string, header string) string {
 	require.True(t, startIdx < len(lines), fmt.Sprintf("Expected to find the error message after %q, but found none", header))
 	return strings.TrimLeft(lines[startIdx], "# ")
 }
+
+func TestStatePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	source := `
+state.setdefault("count", 0)
+
+def apply(metric):
+	state["count"] += 1
+	state["last"] = deepcopy(metric)
+	metric.fields["count"] = state["count"]
+	return metric
+`
+	statefile := filepath.Join(dir, "state.json")
+
+	run := func(m telegraf.Metric) []telegraf.Metric {
+		plugin := &Starlark{
+			StarlarkCommon: common.StarlarkCommon{
+				Source:    source,
+				StateFile: statefile,
+				Log:       testutil.Logger{},
+			},
+		}
+		require.NoError(t, plugin.Init())
+
+		acc := &testutil.Accumulator{}
+		require.NoError(t, plugin.Start(acc))
+		require.NoError(t, plugin.Add(m, acc))
+		require.NoError(t, plugin.Stop())
+		return acc.GetTelegrafMetrics()
+	}
+
+	run(testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0)))
+	actual := run(testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 43}, time.Unix(0, 0)))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 43, "count": 2},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// The restored state contains the metric stored by the previous run.
+	plugin := &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Source: `
+def apply(metric):
+	return [deepcopy(state["last"]), metric]
+`,
+			StateFile: statefile,
+			Log:       testutil.Logger{},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	require.NoError(t, plugin.Add(testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)), acc))
+	require.NoError(t, plugin.Stop())
+
+	expected = []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 43},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("mem",
+			map[string]string{},
+			map[string]interface{}{"value": 1},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestStateUnsupportedType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plugin := &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Source: `
+state["fn"] = lambda x: x
+
+def apply(metric):
+	return metric
+`,
+			StateFile: filepath.Join(dir, "state.json"),
+			Log:       testutil.Logger{},
+		},
+	}
+	require.NoError(t, plugin.Init())
+	require.Error(t, plugin.Stop())
+}
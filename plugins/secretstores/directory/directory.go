@@ -0,0 +1,99 @@
+package directory
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+var sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as @{id:key}.
+  id = "secrets"
+
+  ## Directory holding one file per secret, the file name is the key of the
+  ## secret.  Trailing newlines of the files are ignored.
+  path = "/run/secrets"
+`
+
+// Directory is a secret store reading each secret from a file in a
+// directory, as used for Docker and Kubernetes secrets.
+type Directory struct {
+	Path string `toml:"path"`
+}
+
+func (d *Directory) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Directory) Description() string {
+	return "Read secrets from the files of a directory"
+}
+
+func (d *Directory) Init() error {
+	if d.Path == "" {
+		return errors.New("path is required")
+	}
+	return nil
+}
+
+func (d *Directory) Get(key string) (string, error) {
+	path, err := d.keyPath(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("secret %q not found", key)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func (d *Directory) Set(key, value string) error {
+	path, err := d.keyPath(key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(value), 0600)
+}
+
+func (d *Directory) List() ([]string, error) {
+	files, err := ioutil.ReadDir(d.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, file := range files {
+		// Hidden files include the data directories of Kubernetes mounts.
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		keys = append(keys, file.Name())
+	}
+	return keys, nil
+}
+
+// keyPath returns the path of the file of a secret, keys must not refer to
+// files outside of the directory.
+func (d *Directory) keyPath(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(d.Path, key), nil
+}
+
+func init() {
+	secretstores.Add("directory", func() telegraf.SecretStore {
+		return &Directory{}
+	})
+}
@@ -0,0 +1,52 @@
+package directory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".hidden"), []byte("hidden"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0700))
+
+	d := &Directory{Path: dir}
+	require.NoError(t, d.Init())
+
+	value, err := d.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "secret", value)
+
+	_, err = d.Get("missing")
+	require.EqualError(t, err, `secret "missing" not found`)
+
+	require.NoError(t, d.Set("token", "abc"))
+	value, err = d.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "abc", value)
+
+	keys, err := d.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"password", "token"}, keys)
+}
+
+func TestDirectory_InvalidKey(t *testing.T) {
+	d := &Directory{Path: t.TempDir()}
+	require.NoError(t, d.Init())
+
+	for _, key := range []string{"", "..", "../password", "a/b", `a\b`, ".hidden"} {
+		_, err := d.Get(key)
+		require.Error(t, err, key)
+		require.Error(t, d.Set(key, "value"), key)
+	}
+}
+
+func TestDirectory_MissingPath(t *testing.T) {
+	d := &Directory{}
+	require.EqualError(t, d.Init(), "path is required")
+}
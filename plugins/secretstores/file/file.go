@@ -0,0 +1,238 @@
+package file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	fileVersion = 1
+
+	// scrypt parameters recommended for interactive logins.
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	keyLength    = 32
+	saltLength   = 16
+	fileMode     = 0600
+	tempFileName = ".telegraf-secrets-*"
+)
+
+var sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as @{id:key}.
+  id = "secrets"
+
+  ## File holding the encrypted secrets, it is created when the first secret
+  ## is stored with "telegraf secrets set".
+  path = "/etc/telegraf/secrets.enc"
+
+  ## Password the encryption key is derived from.  Take it from the
+  ## environment to avoid storing it next to the file.
+  password = "${TELEGRAF_SECRETS_PASSWORD}"
+`
+
+// encryptedFile is the content of the secrets file.  The secrets are
+// encrypted with AES-256-GCM using a key derived from the password with
+// scrypt.
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// File is a secret store keeping the secrets in an encrypted file.
+type File struct {
+	Path     string `toml:"path"`
+	Password string `toml:"password"`
+
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from an encrypted file"
+}
+
+func (f *File) Init() error {
+	if f.Path == "" {
+		return errors.New("path is required")
+	}
+	if f.Password == "" {
+		return errors.New("password is required")
+	}
+
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	f.secrets = secrets
+	return nil
+}
+
+func (f *File) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, ok := f.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found", key)
+	}
+	return value, nil
+}
+
+func (f *File) Set(key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets := make(map[string]string, len(f.secrets)+1)
+	for k, v := range f.secrets {
+		secrets[k] = v
+	}
+	secrets[key] = value
+
+	err := f.write(secrets)
+	if err != nil {
+		return err
+	}
+	f.secrets = secrets
+	return nil
+}
+
+func (f *File) List() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.secrets))
+	for key := range f.secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// read decrypts the secrets of the file, a missing file holds no secrets.
+func (f *File) read() (map[string]string, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file encryptedFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", f.Path, err)
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported version %d of %s", file.Version, f.Path)
+	}
+
+	aead, err := f.cipher(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in %s", f.Path)
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: wrong password or corrupted file", f.Path)
+	}
+
+	secrets := make(map[string]string)
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, fmt.Errorf("parsing secrets of %s: %w", f.Path, err)
+	}
+	return secrets, nil
+}
+
+// write encrypts the secrets with a new salt and nonce and replaces the file.
+func (f *File) write(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{
+		Version: fileVersion,
+		Salt:    make([]byte, saltLength),
+	}
+	if _, err := io.ReadFull(rand.Reader, file.Salt); err != nil {
+		return err
+	}
+	aead, err := f.cipher(file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, file.Nonce); err != nil {
+		return err
+	}
+	file.Data = aead.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so the secrets are not lost if
+	// writing fails.
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), tempFileName)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(fileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func (f *File) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(f.Password), salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
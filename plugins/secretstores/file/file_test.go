@@ -0,0 +1,67 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	f := &File{Path: path, Password: "password"}
+	require.NoError(t, f.Init())
+
+	keys, err := f.List()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	require.NoError(t, f.Set("token", "abc"))
+	require.NoError(t, f.Set("password", "secret"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.False(t, strings.Contains(string(data), "secret"))
+
+	// Read the secrets back from the file.
+	f = &File{Path: path, Password: "password"}
+	require.NoError(t, f.Init())
+
+	value, err := f.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "secret", value)
+
+	_, err = f.Get("missing")
+	require.EqualError(t, err, `secret "missing" not found`)
+
+	keys, err = f.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"password", "token"}, keys)
+}
+
+func TestFile_WrongPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	f := &File{Path: path, Password: "password"}
+	require.NoError(t, f.Init())
+	require.NoError(t, f.Set("token", "abc"))
+
+	f = &File{Path: path, Password: "wrong"}
+	require.EqualError(t, f.Init(), "decrypting "+path+": wrong password or corrupted file")
+}
+
+func TestFile_MissingSettings(t *testing.T) {
+	f := &File{Password: "password"}
+	require.EqualError(t, f.Init(), "path is required")
+
+	f = &File{Path: "secrets.enc"}
+	require.EqualError(t, f.Init(), "password is required")
+}
@@ -0,0 +1,70 @@
+package keyring
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+var sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as @{id:key}.
+  id = "secrets"
+
+  ## Service the secrets are stored for in the keyring.
+  # service = "telegraf"
+`
+
+// Keyring is the keyring of the operating system.
+type Keyring interface {
+	Get(service, key string) (string, error)
+	Set(service, key, value string) error
+	Keys(service string) ([]string, error)
+}
+
+// OSKeyring is a secret store keeping the secrets in the keyring of the
+// operating system.
+type OSKeyring struct {
+	Service string `toml:"service"`
+
+	keyring Keyring
+}
+
+func (k *OSKeyring) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *OSKeyring) Description() string {
+	return "Read secrets from the keyring of the operating system"
+}
+
+func (k *OSKeyring) Init() error {
+	if k.Service == "" {
+		k.Service = "telegraf"
+	}
+
+	if k.keyring == nil {
+		keyring, err := newKeyring()
+		if err != nil {
+			return err
+		}
+		k.keyring = keyring
+	}
+	return nil
+}
+
+func (k *OSKeyring) Get(key string) (string, error) {
+	return k.keyring.Get(k.Service, key)
+}
+
+func (k *OSKeyring) Set(key, value string) error {
+	return k.keyring.Set(k.Service, key, value)
+}
+
+func (k *OSKeyring) List() ([]string, error) {
+	return k.keyring.Keys(k.Service)
+}
+
+func init() {
+	secretstores.Add("keyring", func() telegraf.SecretStore {
+		return &OSKeyring{}
+	})
+}
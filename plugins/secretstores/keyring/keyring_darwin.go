@@ -0,0 +1,46 @@
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychain accesses the macOS keychain with the security command.
+type keychain struct{}
+
+func newKeyring() (Keyring, error) {
+	return &keychain{}, nil
+}
+
+func (k *keychain) Get(service, key string) (string, error) {
+	out, err := run("find-generic-password", "-s", service, "-a", key, "-w")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func (k *keychain) Set(service, key, value string) error {
+	_, err := run("add-generic-password", "-U", "-s", service, "-a", key, "-w", value)
+	return err
+}
+
+func (k *keychain) Keys(service string) ([]string, error) {
+	return nil, errors.New("listing the secrets of the keychain is not supported")
+}
+
+func run(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("security", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("security %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,75 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// secretTool accesses the Secret Service keyring, e.g. GNOME Keyring or
+// KWallet, with the secret-tool command of libsecret.
+type secretTool struct {
+	path string
+}
+
+func newKeyring() (Keyring, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, fmt.Errorf("secret-tool of libsecret is required: %w", err)
+	}
+	return &secretTool{path: path}, nil
+}
+
+func (s *secretTool) Get(service, key string) (string, error) {
+	out, err := s.run("", "lookup", "service", service, "key", key)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "", fmt.Errorf("secret %q not found", key)
+	}
+	return out, nil
+}
+
+func (s *secretTool) Set(service, key, value string) error {
+	label := fmt.Sprintf("--label=%s %s", service, key)
+	_, err := s.run(value, "store", label, "service", service, "key", key)
+	return err
+}
+
+func (s *secretTool) Keys(service string) ([]string, error) {
+	out, err := s.run("", "search", "--all", "service", service)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "attribute.key = ") {
+			keys = append(keys, strings.TrimPrefix(line, "attribute.key = "))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// run runs secret-tool passing the secret on stdin, it is not visible in the
+// process list.
+func (s *secretTool) run(stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("secret-tool %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
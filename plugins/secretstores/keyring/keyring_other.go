@@ -0,0 +1,12 @@
+// +build !linux,!darwin
+
+package keyring
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newKeyring() (Keyring, error) {
+	return nil, fmt.Errorf("keyring is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,56 @@
+package keyring
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockKeyring map[string]string
+
+func (m mockKeyring) Get(service, key string) (string, error) {
+	value, ok := m[service+"/"+key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found", key)
+	}
+	return value, nil
+}
+
+func (m mockKeyring) Set(service, key, value string) error {
+	m[service+"/"+key] = value
+	return nil
+}
+
+func (m mockKeyring) Keys(service string) ([]string, error) {
+	var keys []string
+	for k := range m {
+		if len(k) > len(service) && k[:len(service)+1] == service+"/" {
+			keys = append(keys, k[len(service)+1:])
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func TestOSKeyring(t *testing.T) {
+	keyring := mockKeyring{"other/token": "xyz"}
+	k := &OSKeyring{keyring: keyring}
+	require.NoError(t, k.Init())
+	require.Equal(t, "telegraf", k.Service)
+
+	require.NoError(t, k.Set("token", "abc"))
+	require.Equal(t, "abc", keyring["telegraf/token"])
+
+	value, err := k.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "abc", value)
+
+	_, err = k.Get("missing")
+	require.Error(t, err)
+
+	keys, err := k.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"token"}, keys)
+}
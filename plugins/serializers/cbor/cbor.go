@@ -0,0 +1,35 @@
+package cbor
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/influxdata/telegraf"
+)
+
+// Serializer encodes metrics as CBOR maps.
+type Serializer struct{}
+
+func NewSerializer() *Serializer {
+	return &Serializer{}
+}
+
+// Serialize encodes the metric as a single CBOR map.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	e, err := FromMetric(metric)
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(e)
+}
+
+// SerializeBatch encodes the metrics as a CBOR sequence of maps.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, m := range metrics {
+		b, err := s.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
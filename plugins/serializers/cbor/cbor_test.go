@@ -0,0 +1,58 @@
+package cbor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialize(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{
+			"int":  int64(-1),
+			"uint": uint64(1),
+		},
+		time.Unix(1, 5),
+		telegraf.Gauge,
+	)
+
+	s := NewSerializer()
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	var actual map[string]interface{}
+	require.NoError(t, cbor.Unmarshal(buf, &actual))
+	require.Equal(t, map[string]interface{}{
+		"name": "cpu",
+		"time": uint64(1000000005),
+		"tags": map[interface{}]interface{}{"host": "localhost"},
+		"fields": map[interface{}]interface{}{
+			"int":  int64(-1),
+			"uint": cbor.Tag{Number: BignumTag, Content: []byte{0x01}},
+		},
+		"type": "gauge",
+	}, actual)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+	}
+
+	s := NewSerializer()
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	one, err := s.Serialize(metrics[0])
+	require.NoError(t, err)
+	two, err := s.Serialize(metrics[1])
+	require.NoError(t, err)
+	require.Equal(t, append(one, two...), buf)
+}
@@ -0,0 +1,100 @@
+package cbor
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+)
+
+// BignumTag is the CBOR tag of positive bignums, unsigned integer fields are
+// encoded as bignum so they can be told apart from signed integers.
+const BignumTag = 2
+
+// Metric is the CBOR encoding of a metric.
+type Metric struct {
+	Name   string                 `cbor:"name"`
+	Time   int64                  `cbor:"time"`
+	Tags   map[string]string      `cbor:"tags"`
+	Fields map[string]interface{} `cbor:"fields"`
+	Type   string                 `cbor:"type,omitempty"`
+}
+
+// FromMetric converts the metric to its encoding.
+func FromMetric(m telegraf.Metric) (*Metric, error) {
+	e := &Metric{
+		Name:   m.Name(),
+		Time:   m.Time().UnixNano(),
+		Tags:   m.Tags(),
+		Fields: make(map[string]interface{}, len(m.FieldList())),
+	}
+	if m.Type() != telegraf.Untyped {
+		e.Type = msgpack.TypeName(m.Type())
+	}
+
+	for _, field := range m.FieldList() {
+		switch v := field.Value.(type) {
+		case uint64:
+			e.Fields[field.Key] = cbor.Tag{
+				Number:  BignumTag,
+				Content: new(big.Int).SetUint64(v).Bytes(),
+			}
+		case int64, float64, bool, string:
+			e.Fields[field.Key] = v
+		default:
+			return nil, fmt.Errorf("unsupported type %T of field %q", v, field.Key)
+		}
+	}
+	return e, nil
+}
+
+// Values returns the arguments of metric.New to create the encoded metric.
+func (e *Metric) Values() (string, map[string]string, map[string]interface{}, time.Time, telegraf.ValueType, error) {
+	fields := make(map[string]interface{}, len(e.Fields))
+	for k, v := range e.Fields {
+		value, err := fieldValue(v)
+		if err != nil {
+			return "", nil, nil, time.Time{}, 0, fmt.Errorf("field %q: %v", k, err)
+		}
+		if value != nil {
+			fields[k] = value
+		}
+	}
+
+	tags := e.Tags
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	return e.Name, tags, fields, time.Unix(0, e.Time), msgpack.ParseType(e.Type), nil
+}
+
+// fieldValue converts a decoded value to a field value, values of other
+// types are returned as nil.
+func fieldValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case uint64:
+		// Positive integers without bignum tag are signed integers.
+		if v > 1<<63-1 {
+			return nil, errors.New("integer overflows int64")
+		}
+		return int64(v), nil
+	case cbor.Tag:
+		b, ok := v.Content.([]byte)
+		if v.Number != BignumTag || !ok {
+			return nil, nil
+		}
+		u := new(big.Int).SetBytes(b)
+		if !u.IsUint64() {
+			return nil, errors.New("bignum overflows uint64")
+		}
+		return u.Uint64(), nil
+	case int64, float64, bool, string:
+		return v, nil
+	default:
+		return nil, nil
+	}
+}
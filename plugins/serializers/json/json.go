@@ -1,8 +1,11 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"math"
+	"text/template"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -10,6 +13,9 @@ import (
 
 type serializer struct {
 	TimestampUnits time.Duration
+
+	// template transforms the batch object before it is written.
+	template *template.Template
 }
 
 func NewSerializer(timestampUnits time.Duration) (*serializer, error) {
@@ -19,7 +25,33 @@ func NewSerializer(timestampUnits time.Duration) (*serializer, error) {
 	return s, nil
 }
 
+// NewTemplateSerializer creates a JSON serializer whose output is the result
+// of executing the Go template tmpl with the batch object.  Without a
+// template it is the same as NewSerializer.
+func NewTemplateSerializer(timestampUnits time.Duration, tmpl string) (*serializer, error) {
+	s, err := NewSerializer(timestampUnits)
+	if err != nil || tmpl == "" {
+		return s, err
+	}
+
+	s.template, err = template.New("json").Funcs(template.FuncMap{
+		"json": toJSON,
+	}).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("parsing json_template: %v", err)
+	}
+	return s, nil
+}
+
 func (s *serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if s.template != nil {
+		serialized, err := s.SerializeBatch([]telegraf.Metric{metric})
+		if err != nil {
+			return []byte{}, err
+		}
+		return append(serialized, '\n'), nil
+	}
+
 	m := s.createObject(metric)
 	serialized, err := json.Marshal(m)
 	if err != nil {
@@ -41,6 +73,10 @@ func (s *serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 		"metrics": objects,
 	}
 
+	if s.template != nil {
+		return s.transform(obj)
+	}
+
 	serialized, err := json.Marshal(obj)
 	if err != nil {
 		return []byte{}, err
@@ -75,6 +111,30 @@ func (s *serializer) createObject(metric telegraf.Metric) map[string]interface{}
 	return m
 }
 
+// transform executes the template with the batch object, the result must be
+// valid JSON.
+func (s *serializer) transform(obj map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.template.Execute(&buf, obj); err != nil {
+		return []byte{}, err
+	}
+
+	serialized := bytes.TrimSpace(buf.Bytes())
+	if !json.Valid(serialized) {
+		return []byte{}, fmt.Errorf("json_template output is not valid JSON: %q", serialized)
+	}
+	return serialized, nil
+}
+
+// toJSON encodes the value as JSON for use in templates.
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 func truncateDuration(units time.Duration) time.Duration {
 	// Default precision is 1s
 	if units <= 0 {
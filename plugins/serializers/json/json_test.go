@@ -3,6 +3,7 @@ package json
 import (
 	"fmt"
 	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -233,3 +234,54 @@ func TestSerializeBatchSkipInfAllFields(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte(`{"metrics":[{"fields":{},"name":"cpu","tags":{},"timestamp":0}]}`), buf)
 }
+
+func TestSerializeTemplate(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "b \"quoted\""},
+			map[string]interface{}{"used": int64(7)},
+			time.Unix(1600000010, 0),
+		),
+	}
+
+	tmpl := `{"series": [
+{{- range $i, $m := .metrics}}{{if $i}},{{end}}
+  {"metric": {{json $m.name}}, "points": [[{{$m.timestamp}}, {{json $m.fields}}]], "host": {{json $m.tags.host}}}
+{{- end}}]}`
+
+	s, err := NewTemplateSerializer(time.Second, tmpl)
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"series": [
+		{"metric": "cpu", "points": [[1600000000, {"usage": 42.5}]], "host": "a"},
+		{"metric": "mem", "points": [[1600000010, {"used": 7}]], "host": "b \"quoted\""}
+	]}`, string(buf))
+
+	buf, err = s.Serialize(metrics[0])
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(buf), "\n"))
+	require.JSONEq(t, `{"series": [
+		{"metric": "cpu", "points": [[1600000000, {"usage": 42.5}]], "host": "a"}
+	]}`, string(buf))
+}
+
+func TestSerializeTemplateErrors(t *testing.T) {
+	_, err := NewTemplateSerializer(0, `{{.metrics`)
+	require.Error(t, err)
+
+	s, err := NewTemplateSerializer(0, `{"count": {{len .metrics}}`)
+	require.NoError(t, err)
+	_, err = s.SerializeBatch([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	})
+	require.Error(t, err)
+}
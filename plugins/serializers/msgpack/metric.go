@@ -0,0 +1,170 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// TimestampExtension is the MessagePack extension type of timestamps.
+const TimestampExtension = -1
+
+// Timestamp implements the MessagePack timestamp extension type, it holds
+// the time with nanosecond precision.
+type Timestamp struct {
+	time.Time
+}
+
+// ExtensionType implements msgp.Extension.
+func (t *Timestamp) ExtensionType() int8 {
+	return TimestampExtension
+}
+
+// Len implements msgp.Extension, the smallest of the 32, 64 and 96 bit
+// formats able to hold the time is used.
+func (t *Timestamp) Len() int {
+	sec := t.Unix()
+	nsec := t.Nanosecond()
+	switch {
+	case sec>>34 != 0:
+		return 12
+	case sec>>32 != 0 || nsec != 0:
+		return 8
+	default:
+		return 4
+	}
+}
+
+// MarshalBinaryTo implements msgp.Extension.
+func (t *Timestamp) MarshalBinaryTo(b []byte) error {
+	sec := t.Unix()
+	nsec := uint64(t.Nanosecond())
+	switch len(b) {
+	case 4:
+		binary.BigEndian.PutUint32(b, uint32(sec))
+	case 8:
+		binary.BigEndian.PutUint64(b, nsec<<34|uint64(sec))
+	case 12:
+		binary.BigEndian.PutUint32(b, uint32(nsec))
+		binary.BigEndian.PutUint64(b[4:], uint64(sec))
+	default:
+		return fmt.Errorf("invalid timestamp length %d", len(b))
+	}
+	return nil
+}
+
+// UnmarshalBinary implements msgp.Extension.
+func (t *Timestamp) UnmarshalBinary(b []byte) error {
+	switch len(b) {
+	case 4:
+		t.Time = time.Unix(int64(binary.BigEndian.Uint32(b)), 0)
+	case 8:
+		v := binary.BigEndian.Uint64(b)
+		t.Time = time.Unix(int64(v&(1<<34-1)), int64(v>>34))
+	case 12:
+		nsec := binary.BigEndian.Uint32(b)
+		sec := binary.BigEndian.Uint64(b[4:])
+		t.Time = time.Unix(int64(sec), int64(nsec))
+	default:
+		return fmt.Errorf("invalid timestamp length %d", len(b))
+	}
+	return nil
+}
+
+// AppendMetric appends the metric as MessagePack map to b.
+func AppendMetric(b []byte, m telegraf.Metric) ([]byte, error) {
+	size := uint32(4)
+	if m.Type() != telegraf.Untyped {
+		size++
+	}
+	b = msgp.AppendMapHeader(b, size)
+
+	b = msgp.AppendString(b, "name")
+	b = msgp.AppendString(b, m.Name())
+
+	var err error
+	b = msgp.AppendString(b, "time")
+	b, err = msgp.AppendExtension(b, &Timestamp{m.Time()})
+	if err != nil {
+		return nil, err
+	}
+
+	b = msgp.AppendString(b, "tags")
+	b = msgp.AppendMapHeader(b, uint32(len(m.TagList())))
+	for _, tag := range m.TagList() {
+		b = msgp.AppendString(b, tag.Key)
+		b = msgp.AppendString(b, tag.Value)
+	}
+
+	b = msgp.AppendString(b, "fields")
+	b = msgp.AppendMapHeader(b, uint32(len(m.FieldList())))
+	for _, field := range m.FieldList() {
+		b = msgp.AppendString(b, field.Key)
+		switch v := field.Value.(type) {
+		case int64:
+			b = msgp.AppendInt64(b, v)
+		case uint64:
+			b = appendUint64(b, v)
+		case float64:
+			b = msgp.AppendFloat64(b, v)
+		case bool:
+			b = msgp.AppendBool(b, v)
+		case string:
+			b = msgp.AppendString(b, v)
+		default:
+			return nil, fmt.Errorf("unsupported type %T of field %q", v, field.Key)
+		}
+	}
+
+	if m.Type() != telegraf.Untyped {
+		b = msgp.AppendString(b, "type")
+		b = msgp.AppendString(b, TypeName(m.Type()))
+	}
+	return b, nil
+}
+
+// appendUint64 appends u using an unsigned integer format.  Unlike
+// msgp.AppendUint64 small values are not written as positive fixint, which
+// is decoded as signed integer.
+func appendUint64(b []byte, u uint64) []byte {
+	if u <= math.MaxUint8 {
+		return append(b, 0xcc, byte(u))
+	}
+	return msgp.AppendUint64(b, u)
+}
+
+// TypeName returns the name of the metric type used in the encoding.
+func TypeName(tp telegraf.ValueType) string {
+	switch tp {
+	case telegraf.Counter:
+		return "counter"
+	case telegraf.Gauge:
+		return "gauge"
+	case telegraf.Summary:
+		return "summary"
+	case telegraf.Histogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// ParseType returns the metric type of the name, unknown names are untyped.
+func ParseType(name string) telegraf.ValueType {
+	switch name {
+	case "counter":
+		return telegraf.Counter
+	case "gauge":
+		return telegraf.Gauge
+	case "summary":
+		return telegraf.Summary
+	case "histogram":
+		return telegraf.Histogram
+	default:
+		return telegraf.Untyped
+	}
+}
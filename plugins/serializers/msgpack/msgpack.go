@@ -0,0 +1,30 @@
+package msgpack
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// Serializer encodes metrics as MessagePack maps.
+type Serializer struct{}
+
+func NewSerializer() *Serializer {
+	return &Serializer{}
+}
+
+// Serialize encodes the metric as a single MessagePack map.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return AppendMetric(nil, metric)
+}
+
+// SerializeBatch encodes the metrics as a sequence of MessagePack maps.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, m := range metrics {
+		var err error
+		buf, err = AppendMetric(buf, m)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
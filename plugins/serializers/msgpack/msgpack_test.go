@@ -0,0 +1,104 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestSerialize(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"count": uint64(1)},
+		time.Unix(1, 0),
+		telegraf.Counter,
+	)
+
+	s := NewSerializer()
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	expected := []byte{0x85,
+		0xa4, 'n', 'a', 'm', 'e', 0xa3, 'c', 'p', 'u',
+		0xa4, 't', 'i', 'm', 'e', 0xd6, 0xff, 0x00, 0x00, 0x00, 0x01,
+		0xa4, 't', 'a', 'g', 's', 0x81,
+		0xa4, 'h', 'o', 's', 't', 0xa9, 'l', 'o', 'c', 'a', 'l', 'h', 'o', 's', 't',
+		0xa6, 'f', 'i', 'e', 'l', 'd', 's', 0x81,
+		0xa5, 'c', 'o', 'u', 'n', 't', 0xcc, 0x01,
+		0xa4, 't', 'y', 'p', 'e', 0xa7, 'c', 'o', 'u', 'n', 't', 'e', 'r',
+	}
+	require.Equal(t, expected, buf)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+	}
+
+	s := NewSerializer()
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	var names []string
+	for len(buf) > 0 {
+		var obj interface{}
+		obj, buf, err = msgp.ReadIntfBytes(buf)
+		require.NoError(t, err)
+		names = append(names, obj.(map[string]interface{})["name"].(string))
+	}
+	require.Equal(t, []string{"cpu", "mem"}, names)
+}
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+		len  int
+	}{
+		{
+			name: "seconds",
+			time: time.Unix(1600000000, 0),
+			len:  4,
+		},
+		{
+			name: "nanoseconds",
+			time: time.Unix(1600000000, 123456789),
+			len:  8,
+		},
+		{
+			name: "after 2106",
+			time: time.Unix(1<<33, 1),
+			len:  8,
+		},
+		{
+			name: "before epoch",
+			time: time.Unix(-1, 999999999),
+			len:  12,
+		},
+		{
+			name: "far future",
+			time: time.Unix(1<<35, 5),
+			len:  12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := &Timestamp{tt.time}
+			require.Equal(t, tt.len, ts.Len())
+
+			buf, err := msgp.AppendExtension(nil, ts)
+			require.NoError(t, err)
+
+			var actual Timestamp
+			_, err = msgp.ReadExtensionBytes(buf, &actual)
+			require.NoError(t, err)
+			require.True(t, tt.time.Equal(actual.Time), "expected %v, got %v", tt.time, actual.Time)
+		})
+	}
+}
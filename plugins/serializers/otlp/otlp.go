@@ -0,0 +1,43 @@
+package otlp
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/otlp"
+)
+
+// Serializer encodes metrics as OTLP ExportMetricsServiceRequest.
+type Serializer struct {
+	format       string
+	resourceTags []string
+}
+
+// NewSerializer creates a serializer for the format, either "protobuf" or
+// "json".  The tags listed in resourceTags become resource attributes.
+func NewSerializer(format string, resourceTags []string) (*Serializer, error) {
+	switch format {
+	case "":
+		format = "protobuf"
+	case "protobuf", "json":
+	default:
+		return nil, fmt.Errorf("invalid otlp_format %q", format)
+	}
+
+	return &Serializer{
+		format:       format,
+		resourceTags: resourceTags,
+	}, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	req, err := otlp.FromMetrics(metrics, s.resourceTags)
+	if err != nil {
+		return nil, err
+	}
+	return otlp.Marshal(req, s.format)
+}
@@ -0,0 +1,84 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/otlp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialize(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5},
+		time.Unix(1600000000, 0),
+		telegraf.Gauge,
+	)
+
+	for _, format := range []string{"protobuf", "json"} {
+		t.Run(format, func(t *testing.T) {
+			s, err := NewSerializer(format, []string{"host"})
+			require.NoError(t, err)
+
+			buf, err := s.Serialize(m)
+			require.NoError(t, err)
+
+			req, err := otlp.Unmarshal(buf, format)
+			require.NoError(t, err)
+			actual, err := otlp.ToMetrics(req, "otlp", time.Unix(0, 0))
+			require.NoError(t, err)
+
+			expected := testutil.MustMetric(
+				"otlp",
+				map[string]string{"host": "localhost", "cpu": "cpu0"},
+				map[string]interface{}{"cpu_usage_idle": 91.5},
+				time.Unix(1600000000, 0),
+				telegraf.Gauge,
+			)
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, actual)
+		})
+	}
+}
+
+func TestSerializeJSON(t *testing.T) {
+	m := testutil.MustMetric(
+		"net",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"bytes_recv": int64(42)},
+		time.Unix(1, 0),
+		telegraf.Counter,
+	)
+
+	s, err := NewSerializer("json", []string{"host"})
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch([]telegraf.Metric{m})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "resourceMetrics": [{
+    "resource": {
+      "attributes": [{"key": "host", "value": {"stringValue": "localhost"}}]
+    },
+    "scopeMetrics": [{
+      "scope": {"name": "telegraf"},
+      "metrics": [{
+        "name": "net_bytes_recv",
+        "sum": {
+          "dataPoints": [{"timeUnixNano": "1000000000", "asInt": "42"}],
+          "aggregationTemporality": 2,
+          "isMonotonic": true
+        }
+      }]
+    }]
+  }]
+}`, string(buf))
+}
+
+func TestInvalidFormat(t *testing.T) {
+	_, err := NewSerializer("xml", nil)
+	require.Error(t, err)
+}
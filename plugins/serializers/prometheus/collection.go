@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/influxdata/telegraf"
 	dto "github.com/prometheus/client_model/go"
 )
@@ -22,9 +23,11 @@ type MetricFamily struct {
 }
 
 type Metric struct {
-	Labels    []LabelPair
-	Time      time.Time
-	AddTime   time.Time
+	Labels  []LabelPair
+	Time    time.Time
+	AddTime time.Time
+	// Created is the time the series was first seen or last reset.
+	Created   time.Time
 	Scaler    *Scaler
 	Histogram *Histogram
 	Summary   *Summary
@@ -36,12 +39,22 @@ type LabelPair struct {
 }
 
 type Scaler struct {
-	Value float64
+	Value    float64
+	Exemplar *Exemplar
+}
+
+// Exemplar references data outside of the metric set, such as the trace that
+// contributed to a counter or histogram bucket.
+type Exemplar struct {
+	Labels []LabelPair
+	Value  float64
+	Time   time.Time
 }
 
 type Bucket struct {
-	Bound float64
-	Count uint64
+	Bound    float64
+	Count    uint64
+	Exemplar *Exemplar
 }
 
 type Quantile struct {
@@ -55,14 +68,21 @@ type Histogram struct {
 	Sum     float64
 }
 
-func (h *Histogram) merge(b Bucket) {
+// merge updates the bucket with the same bound, reporting false if the count
+// of the bucket decreased.
+func (h *Histogram) merge(b Bucket) bool {
 	for i := range h.Buckets {
 		if h.Buckets[i].Bound == b.Bound {
+			reset := b.Count < h.Buckets[i].Count
 			h.Buckets[i].Count = b.Count
-			return
+			if b.Exemplar != nil || reset {
+				h.Buckets[i].Exemplar = b.Exemplar
+			}
+			return !reset
 		}
 	}
 	h.Buckets = append(h.Buckets, b)
+	return true
 }
 
 type Summary struct {
@@ -136,6 +156,11 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 			}
 		}
 
+		// Exemplar tags identify a single observation, not the series.
+		if c.isExemplarTag(tag.Key) {
+			continue
+		}
+
 		name, ok := SanitizeLabelName(tag.Key)
 		if !ok {
 			continue
@@ -151,7 +176,7 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 	addedFieldLabel := false
 	for _, field := range metric.FieldList() {
 		value, ok := field.Value.(string)
-		if !ok {
+		if !ok || c.isExemplarField(field.Key) {
 			continue
 		}
 
@@ -180,9 +205,67 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 	return labels
 }
 
+func (c *Collection) isExemplarTag(key string) bool {
+	for _, tag := range c.config.ExemplarTags {
+		if key == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Collection) isExemplarField(key string) bool {
+	return len(c.config.ExemplarTags) != 0 && key == c.config.ExemplarField
+}
+
+// createExemplar returns the exemplar of the metric, or nil if the metric has
+// no exemplar tags or no exemplar value.
+func (c *Collection) createExemplar(metric telegraf.Metric) *Exemplar {
+	if len(c.config.ExemplarTags) == 0 {
+		return nil
+	}
+
+	fieldValue, ok := metric.GetField(c.config.ExemplarField)
+	if !ok {
+		return nil
+	}
+	value, ok := SampleValue(fieldValue)
+	if !ok {
+		return nil
+	}
+
+	labels := make([]LabelPair, 0, len(c.config.ExemplarTags))
+	for _, tag := range metric.TagList() {
+		if !c.isExemplarTag(tag.Key) {
+			continue
+		}
+
+		name, ok := SanitizeLabelName(tag.Key)
+		if !ok {
+			continue
+		}
+
+		labels = append(labels, LabelPair{Name: name, Value: tag.Value})
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	return &Exemplar{
+		Labels: labels,
+		Value:  value,
+		Time:   metric.Time(),
+	}
+}
+
 func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 	labels := c.createLabels(metric)
+	exemplar := c.createExemplar(metric)
 	for _, field := range metric.FieldList() {
+		if c.isExemplarField(field.Key) {
+			continue
+		}
+
 		metricName := MetricName(metric.Name(), field.Key, metric.Type())
 		metricName, ok := SanitizeMetricName(metricName)
 		if !ok {
@@ -227,11 +310,25 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 				continue
 			}
 
+			scaler := &Scaler{Value: value}
+			created := metric.Time()
+			if metric.Type() == telegraf.Counter {
+				scaler.Exemplar = exemplar
+				// Keep the state of the series unless the counter was reset.
+				if m != nil && value >= m.Scaler.Value {
+					created = m.Created
+					if scaler.Exemplar == nil {
+						scaler.Exemplar = m.Scaler.Exemplar
+					}
+				}
+			}
+
 			m = &Metric{
 				Labels:  labels,
 				Time:    metric.Time(),
 				AddTime: now,
-				Scaler:  &Scaler{Value: value},
+				Created: created,
+				Scaler:  scaler,
 			}
 
 			entry.Metrics[metricKey] = m
@@ -239,11 +336,13 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 			if m == nil {
 				m = &Metric{
 					Labels:    labels,
-					Time:      metric.Time(),
-					AddTime:   now,
+					Created:   metric.Time(),
 					Histogram: &Histogram{},
 				}
 			}
+			m.Time = metric.Time()
+			m.AddTime = now
+
 			switch {
 			case strings.HasSuffix(field.Key, "_bucket"):
 				le, ok := metric.GetTag("le")
@@ -260,10 +359,13 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 					continue
 				}
 
-				m.Histogram.merge(Bucket{
-					Bound: bound,
-					Count: count,
-				})
+				if !m.Histogram.merge(Bucket{
+					Bound:    bound,
+					Count:    count,
+					Exemplar: exemplar,
+				}) {
+					m.Created = metric.Time()
+				}
 			case strings.HasSuffix(field.Key, "_sum"):
 				sum, ok := SampleSum(field.Value)
 				if !ok {
@@ -277,6 +379,9 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 					continue
 				}
 
+				if count < m.Histogram.Count {
+					m.Created = metric.Time()
+				}
 				m.Histogram.Count = count
 			default:
 				continue
@@ -287,11 +392,13 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 			if m == nil {
 				m = &Metric{
 					Labels:  labels,
-					Time:    metric.Time(),
-					AddTime: now,
+					Created: metric.Time(),
 					Summary: &Summary{},
 				}
 			}
+			m.Time = metric.Time()
+			m.AddTime = now
+
 			switch {
 			case strings.HasSuffix(field.Key, "_sum"):
 				sum, ok := SampleSum(field.Value)
@@ -306,6 +413,9 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 					continue
 				}
 
+				if count < m.Summary.Count {
+					m.Created = metric.Time()
+				}
 				m.Summary.Count = count
 			default:
 				quantileTag, ok := metric.GetTag("quantile")
@@ -403,8 +513,26 @@ func (c *Collection) GetMetrics(entry Entry, order MetricSortOrder) []*Metric {
 	return metrics
 }
 
+// Family is a Prometheus metric family along with the creation time of each
+// of its metrics, which has no representation in the protobuf format.
+type Family struct {
+	*dto.MetricFamily
+	// Created holds the creation time of each metric in MetricFamily.Metric,
+	// a zero time if unknown.
+	Created []time.Time
+}
+
 func (c *Collection) GetProto() []*dto.MetricFamily {
-	result := make([]*dto.MetricFamily, 0, len(c.Entries))
+	families := c.GetFamilies()
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		result = append(result, family.MetricFamily)
+	}
+	return result
+}
+
+func (c *Collection) GetFamilies() []Family {
+	result := make([]Family, 0, len(c.Entries))
 
 	for _, entry := range c.GetEntries(c.config.MetricSortOrder) {
 		mf := &dto.MetricFamily{
@@ -412,18 +540,11 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 			Help: proto.String(helpString),
 			Type: MetricType(entry.Family.Type),
 		}
+		var created []time.Time
 
 		for _, metric := range c.GetMetrics(entry, c.config.MetricSortOrder) {
-			l := make([]*dto.LabelPair, 0, len(metric.Labels))
-			for _, label := range metric.Labels {
-				l = append(l, &dto.LabelPair{
-					Name:  proto.String(label.Name),
-					Value: proto.String(label.Value),
-				})
-			}
-
 			m := &dto.Metric{
-				Label: l,
+				Label: makeLabels(metric.Labels),
 			}
 
 			if c.config.TimestampExport == ExportTimestamp {
@@ -434,7 +555,10 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 			case telegraf.Gauge:
 				m.Gauge = &dto.Gauge{Value: proto.Float64(metric.Scaler.Value)}
 			case telegraf.Counter:
-				m.Counter = &dto.Counter{Value: proto.Float64(metric.Scaler.Value)}
+				m.Counter = &dto.Counter{
+					Value:    proto.Float64(metric.Scaler.Value),
+					Exemplar: makeExemplar(metric.Scaler.Exemplar),
+				}
 			case telegraf.Untyped:
 				m.Untyped = &dto.Untyped{Value: proto.Float64(metric.Scaler.Value)}
 			case telegraf.Histogram:
@@ -443,8 +567,12 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 					buckets = append(buckets, &dto.Bucket{
 						UpperBound:      proto.Float64(bucket.Bound),
 						CumulativeCount: proto.Uint64(bucket.Count),
+						Exemplar:        makeExemplar(bucket.Exemplar),
 					})
 				}
+				sort.Slice(buckets, func(i, j int) bool {
+					return buckets[i].GetUpperBound() < buckets[j].GetUpperBound()
+				})
 
 				m.Histogram = &dto.Histogram{
 					Bucket:      buckets,
@@ -459,6 +587,9 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 						Value:    proto.Float64(quantile.Value),
 					})
 				}
+				sort.Slice(quantiles, func(i, j int) bool {
+					return quantiles[i].GetQuantile() < quantiles[j].GetQuantile()
+				})
 
 				m.Summary = &dto.Summary{
 					Quantile:    quantiles,
@@ -470,12 +601,41 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 			}
 
 			mf.Metric = append(mf.Metric, m)
+			created = append(created, metric.Created)
 		}
 
 		if len(mf.Metric) != 0 {
-			result = append(result, mf)
+			result = append(result, Family{MetricFamily: mf, Created: created})
 		}
 	}
 
 	return result
 }
+
+func makeLabels(labels []LabelPair) []*dto.LabelPair {
+	result := make([]*dto.LabelPair, 0, len(labels))
+	for _, label := range labels {
+		result = append(result, &dto.LabelPair{
+			Name:  proto.String(label.Name),
+			Value: proto.String(label.Value),
+		})
+	}
+	return result
+}
+
+func makeExemplar(exemplar *Exemplar) *dto.Exemplar {
+	if exemplar == nil {
+		return nil
+	}
+
+	ts, err := ptypes.TimestampProto(exemplar.Time)
+	if err != nil {
+		ts = nil
+	}
+
+	return &dto.Exemplar{
+		Label:     makeLabels(exemplar.Labels),
+		Value:     proto.Float64(exemplar.Value),
+		Timestamp: ts,
+	}
+}
@@ -2,10 +2,12 @@ package prometheus
 
 import (
 	"math"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 	dto "github.com/prometheus/client_model/go"
@@ -425,3 +427,389 @@ func TestCollectionExpire(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectionState(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		age      time.Duration
+		input    []Input
+		expected []Family
+	}{
+		{
+			name: "histogram keeps state across updates",
+			now:  time.Unix(20, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{"le": "+Inf"},
+						map[string]interface{}{"http_request_duration_seconds_bucket": 2.0},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				},
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{"le": "0.5"},
+						map[string]interface{}{"http_request_duration_seconds_bucket": 1.0},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				},
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{},
+						map[string]interface{}{
+							"http_request_duration_seconds_sum":   1.5,
+							"http_request_duration_seconds_count": 2.0,
+						},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				},
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{"le": "0.5"},
+						map[string]interface{}{"http_request_duration_seconds_bucket": 3.0},
+						time.Unix(15, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(15, 0),
+				},
+			},
+			expected: []Family{
+				{
+					MetricFamily: &dto.MetricFamily{
+						Name: proto.String("http_request_duration_seconds"),
+						Help: proto.String(helpString),
+						Type: dto.MetricType_HISTOGRAM.Enum(),
+						Metric: []*dto.Metric{
+							{
+								Label: []*dto.LabelPair{},
+								Histogram: &dto.Histogram{
+									SampleCount: proto.Uint64(2),
+									SampleSum:   proto.Float64(1.5),
+									Bucket: []*dto.Bucket{
+										{
+											UpperBound:      proto.Float64(0.5),
+											CumulativeCount: proto.Uint64(3),
+										},
+										{
+											UpperBound:      proto.Float64(math.Inf(1)),
+											CumulativeCount: proto.Uint64(2),
+										},
+									},
+								},
+							},
+						},
+					},
+					Created: []time.Time{time.Unix(0, 0)},
+				},
+			},
+		},
+		{
+			name: "histogram reset",
+			now:  time.Unix(20, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{},
+						map[string]interface{}{
+							"http_request_duration_seconds_sum":   5.0,
+							"http_request_duration_seconds_count": 10.0,
+						},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				},
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{},
+						map[string]interface{}{
+							"http_request_duration_seconds_sum":   1.0,
+							"http_request_duration_seconds_count": 2.0,
+						},
+						time.Unix(12, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(12, 0),
+				},
+			},
+			expected: []Family{
+				{
+					MetricFamily: &dto.MetricFamily{
+						Name: proto.String("http_request_duration_seconds"),
+						Help: proto.String(helpString),
+						Type: dto.MetricType_HISTOGRAM.Enum(),
+						Metric: []*dto.Metric{
+							{
+								Label: []*dto.LabelPair{},
+								Histogram: &dto.Histogram{
+									SampleCount: proto.Uint64(2),
+									SampleSum:   proto.Float64(1.0),
+									Bucket:      []*dto.Bucket{},
+								},
+							},
+						},
+					},
+					Created: []time.Time{time.Unix(12, 0)},
+				},
+			},
+		},
+		{
+			name: "summary quantiles sorted",
+			now:  time.Unix(0, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{"quantile": "0.9"},
+						map[string]interface{}{"rpc_duration_seconds": 9.0},
+						time.Unix(0, 0),
+						telegraf.Summary,
+					),
+					addtime: time.Unix(0, 0),
+				},
+				{
+					metric: testutil.MustMetric(
+						"prometheus",
+						map[string]string{"quantile": "0.5"},
+						map[string]interface{}{"rpc_duration_seconds": 5.0},
+						time.Unix(0, 0),
+						telegraf.Summary,
+					),
+					addtime: time.Unix(0, 0),
+				},
+			},
+			expected: []Family{
+				{
+					MetricFamily: &dto.MetricFamily{
+						Name: proto.String("rpc_duration_seconds"),
+						Help: proto.String(helpString),
+						Type: dto.MetricType_SUMMARY.Enum(),
+						Metric: []*dto.Metric{
+							{
+								Label: []*dto.LabelPair{},
+								Summary: &dto.Summary{
+									SampleCount: proto.Uint64(0),
+									SampleSum:   proto.Float64(0),
+									Quantile: []*dto.Quantile{
+										{
+											Quantile: proto.Float64(0.5),
+											Value:    proto.Float64(5.0),
+										},
+										{
+											Quantile: proto.Float64(0.9),
+											Value:    proto.Float64(9.0),
+										},
+									},
+								},
+							},
+						},
+					},
+					Created: []time.Time{time.Unix(0, 0)},
+				},
+			},
+		},
+		{
+			name: "counter keeps created time",
+			now:  time.Unix(20, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"http",
+						map[string]string{},
+						map[string]interface{}{"requests": 10.0},
+						time.Unix(0, 0),
+						telegraf.Counter,
+					),
+					addtime: time.Unix(0, 0),
+				},
+				{
+					metric: testutil.MustMetric(
+						"http",
+						map[string]string{},
+						map[string]interface{}{"requests": 20.0},
+						time.Unix(15, 0),
+						telegraf.Counter,
+					),
+					addtime: time.Unix(15, 0),
+				},
+			},
+			expected: []Family{
+				{
+					MetricFamily: &dto.MetricFamily{
+						Name: proto.String("http_requests"),
+						Help: proto.String(helpString),
+						Type: dto.MetricType_COUNTER.Enum(),
+						Metric: []*dto.Metric{
+							{
+								Label:   []*dto.LabelPair{},
+								Counter: &dto.Counter{Value: proto.Float64(20.0)},
+							},
+						},
+					},
+					Created: []time.Time{time.Unix(0, 0)},
+				},
+			},
+		},
+		{
+			name: "counter reset",
+			now:  time.Unix(20, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"http",
+						map[string]string{},
+						map[string]interface{}{"requests": 10.0},
+						time.Unix(0, 0),
+						telegraf.Counter,
+					),
+					addtime: time.Unix(0, 0),
+				},
+				{
+					metric: testutil.MustMetric(
+						"http",
+						map[string]string{},
+						map[string]interface{}{"requests": 3.0},
+						time.Unix(15, 0),
+						telegraf.Counter,
+					),
+					addtime: time.Unix(15, 0),
+				},
+			},
+			expected: []Family{
+				{
+					MetricFamily: &dto.MetricFamily{
+						Name: proto.String("http_requests"),
+						Help: proto.String(helpString),
+						Type: dto.MetricType_COUNTER.Enum(),
+						Metric: []*dto.Metric{
+							{
+								Label:   []*dto.LabelPair{},
+								Counter: &dto.Counter{Value: proto.Float64(3.0)},
+							},
+						},
+					},
+					Created: []time.Time{time.Unix(15, 0)},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCollection(FormatConfig{})
+			for _, item := range tt.input {
+				c.Add(item.metric, item.addtime)
+			}
+			c.Expire(tt.now, tt.age)
+
+			actual := c.GetFamilies()
+
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCollectionExemplar(t *testing.T) {
+	c := NewCollection(FormatConfig{
+		ExemplarTags:  []string{"trace_id"},
+		ExemplarField: "exemplar",
+	})
+
+	c.Add(testutil.MustMetric(
+		"http",
+		map[string]string{"host": "example.org", "trace_id": "abc"},
+		map[string]interface{}{"requests": 10.0, "exemplar": 1.0},
+		time.Unix(5, 0),
+		telegraf.Counter,
+	), time.Unix(5, 0))
+	// Samples without exemplar keep the last exemplar of the series.
+	c.Add(testutil.MustMetric(
+		"http",
+		map[string]string{"host": "example.org"},
+		map[string]interface{}{"requests": 12.0},
+		time.Unix(10, 0),
+		telegraf.Counter,
+	), time.Unix(10, 0))
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{"le": "0.5", "trace_id": "def"},
+		map[string]interface{}{"latency_seconds_bucket": 4.0, "exemplar": 0.3},
+		time.Unix(10, 0),
+		telegraf.Histogram,
+	), time.Unix(10, 0))
+
+	exemplar := func(traceID string, value float64, ts time.Time) *dto.Exemplar {
+		timestamp, err := ptypes.TimestampProto(ts)
+		require.NoError(t, err)
+		return &dto.Exemplar{
+			Label: []*dto.LabelPair{
+				{Name: proto.String("trace_id"), Value: proto.String(traceID)},
+			},
+			Value:     proto.Float64(value),
+			Timestamp: timestamp,
+		}
+	}
+
+	expected := []*dto.MetricFamily{
+		{
+			Name: proto.String("http_requests"),
+			Help: proto.String(helpString),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{
+						{Name: proto.String("host"), Value: proto.String("example.org")},
+					},
+					Counter: &dto.Counter{
+						Value:    proto.Float64(12.0),
+						Exemplar: exemplar("abc", 1.0, time.Unix(5, 0)),
+					},
+				},
+			},
+		},
+		{
+			Name: proto.String("latency_seconds"),
+			Help: proto.String(helpString),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{},
+					Histogram: &dto.Histogram{
+						SampleCount: proto.Uint64(0),
+						SampleSum:   proto.Float64(0),
+						Bucket: []*dto.Bucket{
+							{
+								UpperBound:      proto.Float64(0.5),
+								CumulativeCount: proto.Uint64(4),
+								Exemplar:        exemplar("def", 0.3, time.Unix(10, 0)),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	actual := c.GetProto()
+	sort.Slice(actual, func(i, j int) bool {
+		return actual[i].GetName() < actual[j].GetName()
+	})
+	require.Equal(t, expected, actual)
+}
@@ -0,0 +1,201 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// OpenMetricsContentType is the content type of the OpenMetrics text format.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// WriteOpenMetrics writes the families in the OpenMetrics text format,
+// including the exemplars and creation times of the metrics.
+func WriteOpenMetrics(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+	for _, family := range families {
+		writeOpenMetricsFamily(bw, family)
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+func writeOpenMetricsFamily(w *bufio.Writer, family Family) {
+	name := family.GetName()
+
+	var typ string
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		// The family of a counter is named without the _total suffix of
+		// its samples.
+		name = strings.TrimSuffix(name, "_total")
+		typ = "counter"
+	case dto.MetricType_GAUGE:
+		typ = "gauge"
+	case dto.MetricType_HISTOGRAM:
+		typ = "histogram"
+	case dto.MetricType_SUMMARY:
+		typ = "summary"
+	default:
+		typ = "unknown"
+	}
+
+	if family.Help != nil {
+		w.WriteString("# HELP " + name + " " + escapeOpenMetrics(family.GetHelp()) + "\n")
+	}
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+
+	for i, m := range family.Metric {
+		var timestamp string
+		if m.TimestampMs != nil {
+			timestamp = formatTimestamp(time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond)))
+		}
+
+		var created time.Time
+		if i < len(family.Created) {
+			created = family.Created[i]
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			writeOpenMetricsSample(w, name+"_total", m.Label, "", "",
+				formatFloat(m.Counter.GetValue()), timestamp, m.Counter.GetExemplar())
+		case dto.MetricType_GAUGE:
+			writeOpenMetricsSample(w, name, m.Label, "", "",
+				formatFloat(m.Gauge.GetValue()), timestamp, nil)
+		case dto.MetricType_HISTOGRAM:
+			var inf bool
+			for _, bucket := range m.Histogram.Bucket {
+				inf = math.IsInf(bucket.GetUpperBound(), 1)
+				writeOpenMetricsSample(w, name+"_bucket", m.Label, "le", formatFloat(bucket.GetUpperBound()),
+					formatUint(bucket.GetCumulativeCount()), timestamp, bucket.GetExemplar())
+			}
+			// The +Inf bucket is required and counts all observations.
+			if !inf {
+				writeOpenMetricsSample(w, name+"_bucket", m.Label, "le", "+Inf",
+					formatUint(m.Histogram.GetSampleCount()), timestamp, nil)
+			}
+			writeOpenMetricsSample(w, name+"_count", m.Label, "", "",
+				formatUint(m.Histogram.GetSampleCount()), timestamp, nil)
+			writeOpenMetricsSample(w, name+"_sum", m.Label, "", "",
+				formatFloat(m.Histogram.GetSampleSum()), timestamp, nil)
+		case dto.MetricType_SUMMARY:
+			for _, quantile := range m.Summary.Quantile {
+				writeOpenMetricsSample(w, name, m.Label, "quantile", formatFloat(quantile.GetQuantile()),
+					formatFloat(quantile.GetValue()), timestamp, nil)
+			}
+			writeOpenMetricsSample(w, name+"_count", m.Label, "", "",
+				formatUint(m.Summary.GetSampleCount()), timestamp, nil)
+			writeOpenMetricsSample(w, name+"_sum", m.Label, "", "",
+				formatFloat(m.Summary.GetSampleSum()), timestamp, nil)
+		default:
+			writeOpenMetricsSample(w, name, m.Label, "", "",
+				formatFloat(m.Untyped.GetValue()), timestamp, nil)
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER, dto.MetricType_HISTOGRAM, dto.MetricType_SUMMARY:
+			if !created.IsZero() {
+				writeOpenMetricsSample(w, name+"_created", m.Label, "", "",
+					formatTimestamp(created), timestamp, nil)
+			}
+		}
+	}
+}
+
+func writeOpenMetricsSample(
+	w *bufio.Writer,
+	name string,
+	labels []*dto.LabelPair,
+	extraName string,
+	extraValue string,
+	value string,
+	timestamp string,
+	exemplar *dto.Exemplar,
+) {
+	w.WriteString(name)
+	writeOpenMetricsLabels(w, labels, extraName, extraValue)
+	w.WriteString(" " + value)
+	if timestamp != "" {
+		w.WriteString(" " + timestamp)
+	}
+
+	if exemplar != nil {
+		w.WriteString(" # ")
+		writeOpenMetricsLabels(w, exemplar.Label, "", "")
+		if len(exemplar.Label) == 0 {
+			w.WriteString("{}")
+		}
+		w.WriteString(" " + formatFloat(exemplar.GetValue()))
+		if exemplar.Timestamp != nil {
+			ts, err := ptypes.Timestamp(exemplar.Timestamp)
+			if err == nil {
+				w.WriteString(" " + formatTimestamp(ts))
+			}
+		}
+	}
+	w.WriteString("\n")
+}
+
+func writeOpenMetricsLabels(w *bufio.Writer, labels []*dto.LabelPair, extraName, extraValue string) {
+	if len(labels) == 0 && extraName == "" {
+		return
+	}
+
+	w.WriteString("{")
+	for i, label := range labels {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString(label.GetName() + `="` + escapeOpenMetrics(label.GetValue()) + `"`)
+	}
+	if extraName != "" {
+		if len(labels) > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString(extraName + `="` + extraValue + `"`)
+	}
+	w.WriteString("}")
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeOpenMetrics(s string) string {
+	return openMetricsEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func formatUint(u uint64) string {
+	return strconv.FormatUint(u, 10)
+}
+
+// formatTimestamp formats the time as seconds since the Unix epoch.
+func formatTimestamp(t time.Time) string {
+	sec, nsec := t.Unix(), t.Nanosecond()
+	if nsec == 0 {
+		return strconv.FormatInt(sec, 10)
+	}
+	if sec < 0 {
+		return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', -1, 64)
+	}
+	return strconv.FormatInt(sec, 10) + strings.TrimRight(fmt.Sprintf(".%09d", nsec), "0")
+}
@@ -0,0 +1,179 @@
+package prometheus
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   FormatConfig
+		metrics  []telegraf.Metric
+		expected string
+	}{
+		{
+			name: "gauge and untyped",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu",
+					map[string]string{"host": "example.org"},
+					map[string]interface{}{"time_idle": 42.0},
+					time.Unix(0, 0),
+					telegraf.Gauge,
+				),
+				testutil.MustMetric(
+					"mem",
+					map[string]string{"host": "ex\"ample\\.org"},
+					map[string]interface{}{"free": 43.0},
+					time.Unix(0, 0),
+				),
+			},
+			expected: `
+# HELP cpu_time_idle Telegraf collected metric
+# TYPE cpu_time_idle gauge
+cpu_time_idle{host="example.org"} 42
+# HELP mem_free Telegraf collected metric
+# TYPE mem_free unknown
+mem_free{host="ex\"ample\\.org"} 43
+# EOF
+`,
+		},
+		{
+			name: "counter with created and exemplar",
+			config: FormatConfig{
+				ExemplarTags:  []string{"trace_id"},
+				ExemplarField: "exemplar",
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"http",
+					map[string]string{"trace_id": "abc"},
+					map[string]interface{}{"requests_total": 10.0, "exemplar": 1.0},
+					time.Unix(1600000000, 500000000),
+					telegraf.Counter,
+				),
+			},
+			expected: `
+# HELP http_requests Telegraf collected metric
+# TYPE http_requests counter
+http_requests_total 10 # {trace_id="abc"} 1 1600000000.5
+http_requests_created 1600000000.5
+# EOF
+`,
+		},
+		{
+			name:   "histogram with timestamp",
+			config: FormatConfig{TimestampExport: ExportTimestamp},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"le": "0.5"},
+					map[string]interface{}{"http_request_duration_seconds_bucket": 1.0},
+					time.Unix(10, 0),
+					telegraf.Histogram,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{},
+					map[string]interface{}{
+						"http_request_duration_seconds_sum":   1.5,
+						"http_request_duration_seconds_count": 2.0,
+					},
+					time.Unix(10, 0),
+					telegraf.Histogram,
+				),
+			},
+			expected: `
+# HELP http_request_duration_seconds Telegraf collected metric
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{le="0.5"} 1 10
+http_request_duration_seconds_bucket{le="+Inf"} 2 10
+http_request_duration_seconds_count 2 10
+http_request_duration_seconds_sum 1.5 10
+http_request_duration_seconds_created 10 10
+# EOF
+`,
+		},
+		{
+			name: "summary",
+			metrics: []telegraf.Metric{
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"quantile": "0.5", "method": "get"},
+					map[string]interface{}{"rpc_duration_seconds": 4773.0},
+					time.Unix(0, 0),
+					telegraf.Summary,
+				),
+				testutil.MustMetric(
+					"prometheus",
+					map[string]string{"method": "get"},
+					map[string]interface{}{
+						"rpc_duration_seconds_sum":   1.7560473e+07,
+						"rpc_duration_seconds_count": 2693,
+					},
+					time.Unix(0, 0),
+					telegraf.Summary,
+				),
+			},
+			expected: `
+# HELP rpc_duration_seconds Telegraf collected metric
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{method="get",quantile="0.5"} 4773
+rpc_duration_seconds_count{method="get"} 2693
+rpc_duration_seconds_sum{method="get"} 1.7560473e+07
+rpc_duration_seconds_created{method="get"} 0
+# EOF
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.MetricSortOrder = SortMetrics
+			c := NewCollection(tt.config)
+			for _, metric := range tt.metrics {
+				c.Add(metric, time.Now())
+			}
+
+			var buf bytes.Buffer
+			err := WriteOpenMetrics(&buf, c.GetFamilies())
+			require.NoError(t, err)
+			require.Equal(t, strings.TrimLeft(tt.expected, "\n"), buf.String())
+		})
+	}
+}
+
+func TestWriteOpenMetricsProto(t *testing.T) {
+	families := []Family{
+		{
+			MetricFamily: &dto.MetricFamily{
+				Name: proto.String("process_cpu_seconds_total"),
+				Help: proto.String("Total user and system CPU time spent in seconds."),
+				Type: dto.MetricType_COUNTER.Enum(),
+				Metric: []*dto.Metric{
+					{
+						Counter:     &dto.Counter{Value: proto.Float64(0.25)},
+						TimestampMs: proto.Int64(1600000000123),
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteOpenMetrics(&buf, families)
+	require.NoError(t, err)
+	require.Equal(t, `# HELP process_cpu_seconds Total user and system CPU time spent in seconds.
+# TYPE process_cpu_seconds counter
+process_cpu_seconds_total 0.25 1600000000.123
+# EOF
+`, buf.String())
+}
@@ -36,6 +36,10 @@ type FormatConfig struct {
 	TimestampExport TimestampExport
 	MetricSortOrder MetricSortOrder
 	StringHandling  StringHandling
+	// ExemplarTags are the tags moved from the labels of a sample into its
+	// exemplar, whose value is taken from the ExemplarField field.
+	ExemplarTags  []string
+	ExemplarField string
 }
 
 type Serializer struct {
@@ -7,10 +7,13 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
+	"github.com/influxdata/telegraf/plugins/serializers/cbor"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
 	"github.com/influxdata/telegraf/plugins/serializers/nowmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/otlp"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
@@ -81,6 +84,9 @@ type Config struct {
 	// Timestamp units to use for JSON formatted output
 	TimestampUnits time.Duration `toml:"timestamp_units"`
 
+	// Go template transforming the batch for JSON formatted output
+	JSONTemplate string `toml:"json_template"`
+
 	// Include HEC routing fields for splunkmetric output
 	HecRouting bool `toml:"hec_routing"`
 
@@ -104,6 +110,12 @@ type Config struct {
 	// Output string fields as metric labels; when false string fields are
 	// discarded.
 	PrometheusStringAsLabel bool `toml:"prometheus_string_as_label"`
+
+	// OTLP encoding, either protobuf or json
+	OTLPFormat string `toml:"otlp_format"`
+
+	// Tags to use as OTLP resource attributes
+	OTLPResourceTags []string `toml:"otlp_resource_tags"`
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -116,7 +128,7 @@ func NewSerializer(config *Config) (Serializer, error) {
 	case "graphite":
 		serializer, err = NewGraphiteSerializer(config.Prefix, config.Template, config.GraphiteTagSupport, config.GraphiteSeparator, config.Templates)
 	case "json":
-		serializer, err = NewJsonSerializer(config.TimestampUnits)
+		serializer, err = NewJsonTemplateSerializer(config.TimestampUnits, config.JSONTemplate)
 	case "splunkmetric":
 		serializer, err = NewSplunkmetricSerializer(config.HecRouting, config.SplunkmetricMultiMetric)
 	case "nowmetric":
@@ -129,6 +141,12 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewPrometheusSerializer(config)
 	case "prometheusremotewrite":
 		serializer, err = NewPrometheusRemoteWriteSerializer(config)
+	case "msgpack":
+		serializer, err = NewMsgpackSerializer()
+	case "cbor":
+		serializer, err = NewCborSerializer()
+	case "otlp":
+		serializer, err = NewOTLPSerializer(config.OTLPFormat, config.OTLPResourceTags)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -183,6 +201,10 @@ func NewJsonSerializer(timestampUnits time.Duration) (Serializer, error) {
 	return json.NewSerializer(timestampUnits)
 }
 
+func NewJsonTemplateSerializer(timestampUnits time.Duration, template string) (Serializer, error) {
+	return json.NewTemplateSerializer(timestampUnits, template)
+}
+
 func NewCarbon2Serializer(carbon2format string) (Serializer, error) {
 	return carbon2.NewSerializer(carbon2format)
 }
@@ -191,6 +213,18 @@ func NewSplunkmetricSerializer(splunkmetric_hec_routing bool, splunkmetric_multi
 	return splunkmetric.NewSerializer(splunkmetric_hec_routing, splunkmetric_multimetric)
 }
 
+func NewMsgpackSerializer() (Serializer, error) {
+	return msgpack.NewSerializer(), nil
+}
+
+func NewCborSerializer() (Serializer, error) {
+	return cbor.NewSerializer(), nil
+}
+
+func NewOTLPSerializer(format string, resourceTags []string) (Serializer, error) {
+	return otlp.NewSerializer(format, resourceTags)
+}
+
 func NewNowSerializer() (Serializer, error) {
 	return nowmetric.NewSerializer()
 }
@@ -0,0 +1,16 @@
+package telegraf
+
+// SecretStore is a plugin providing the secrets referenced in the config as
+// @{id:key}, where id is the id of the store.
+type SecretStore interface {
+	PluginDescriber
+
+	// Get returns the secret stored for key.
+	Get(key string) (string, error)
+
+	// Set stores the secret for key.
+	Set(key, value string) error
+
+	// List returns the keys of all secrets in the store.
+	List() ([]string, error)
+}
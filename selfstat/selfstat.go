@@ -109,6 +109,24 @@ func Metrics() []telegraf.Metric {
 	return metrics
 }
 
+// Lookup returns the current value of each field registered with the given
+// measurement and tags.  Unlike Metrics, calling Lookup does not clear the
+// average of timing stats.
+func Lookup(measurement string, tags map[string]string) map[string]int64 {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	fields := make(map[string]int64)
+	for field, stat := range registry.stats[key("internal_"+measurement, tags)] {
+		if s, ok := stat.(*timingStat); ok {
+			fields[field] = s.peek()
+			continue
+		}
+		fields[field] = stat.Get()
+	}
+	return fields
+}
+
 type Registry struct {
 	stats map[uint64]map[string]Stat
 	mu    sync.Mutex
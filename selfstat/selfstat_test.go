@@ -211,3 +211,24 @@ func TestRegisterCopy(t *testing.T) {
 	tags["new"] = "value"
 	require.NotEqual(t, tags, stat.Tags())
 }
+
+func TestLookup(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	tags := map[string]string{"input": "mem", "alias": "mem1"}
+	Register("gather", "metrics_gathered", tags).Incr(5)
+	timing := RegisterTiming("gather", "gather_time_ns", tags)
+	timing.Incr(10)
+	timing.Incr(20)
+	Register("gather", "metrics_gathered", map[string]string{"input": "cpu"}).Incr(1)
+
+	expected := map[string]int64{"metrics_gathered": 5, "gather_time_ns": 15}
+	require.Equal(t, expected, Lookup("gather", tags))
+
+	// Lookup leaves the timing average in place.
+	require.Equal(t, expected, Lookup("gather", tags))
+	require.Equal(t, int64(15), timing.Get())
+
+	require.Empty(t, Lookup("gather", map[string]string{"input": "disk"}))
+}